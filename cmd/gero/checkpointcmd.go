@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	checkpointFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block number of the check points",
+		Value: zconfig.CheckPointInterval,
+	}
+	checkpointToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block number of the check points (default: the head)",
+		Value: math.MaxUint64,
+	}
+	checkpointKeyFlag = cli.StringFlag{
+		Name:  "key",
+		Usage: "File of the hex private key signing the check points",
+	}
+	checkpointOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File of the check points, the signatures of the file are kept (default: stdout)",
+	}
+
+	checkpointCommand = cli.Command{
+		Name:     "checkpoint",
+		Usage:    "Manage the signed check points",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:   "generate",
+				Usage:  "Generate the check points of the synced chain",
+				Action: utils.MigrateFlags(generateCheckpoints),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					configFileFlag,
+					checkpointFromFlag,
+					checkpointToFlag,
+					checkpointKeyFlag,
+					checkpointOutFlag,
+				},
+				Description: `
+    gero checkpoint generate [--from <num>] [--to <num>] [--key <keyfile>] [--out <file>]
+
+Writes the state roots of the canonical blocks every 10000 blocks in the range
+as check points, signed with the key. Every signer runs the command with the
+same output file to add its signature, a root different from the one of the
+file is an error. Nodes load the file with --checkpoint.file and trust the check
+points signed by --checkpoint.threshold of the --checkpoint.signers. The node
+must not be running.`,
+			},
+		},
+	}
+)
+
+func generateCheckpoints(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	var key *ecdsa.PrivateKey
+	if file := ctx.String(checkpointKeyFlag.Name); file != "" {
+		var err error
+		if key, err = crypto.LoadECDSA(file); err != nil {
+			utils.Fatalf("Could not load the signing key: %v", err)
+		}
+	}
+
+	existing := make(map[uint64]*zconfig.SignedCheckpoint)
+	out := ctx.String(checkpointOutFlag.Name)
+	if out != "" {
+		if _, err := os.Stat(out); err == nil {
+			cps, err := zconfig.ReadCheckpoints(out)
+			if err != nil {
+				utils.Fatalf("Could not read the check points: %v", err)
+			}
+			for i := range cps {
+				existing[cps[i].Num] = &cps[i]
+			}
+		}
+	}
+
+	head := rawdb.ReadHeadBlockHash(db)
+	headNum := rawdb.ReadHeaderNumber(db, head)
+	if headNum == nil {
+		utils.Fatalf("The chain database has no head block")
+	}
+	to := ctx.Uint64(checkpointToFlag.Name)
+	if to > *headNum {
+		to = *headNum
+	}
+	from := ctx.Uint64(checkpointFromFlag.Name)
+	if rem := from % zconfig.CheckPointInterval; rem != 0 || from == 0 {
+		from += zconfig.CheckPointInterval - rem
+	}
+
+	var cps []*zconfig.SignedCheckpoint
+	for num := from; num <= to; num += zconfig.CheckPointInterval {
+		hash := rawdb.ReadCanonicalHash(db, num)
+		header := rawdb.ReadHeader(db, hash, num)
+		if header == nil {
+			utils.Fatalf("The header of block %d is missing", num)
+		}
+		cp, ok := existing[num]
+		if !ok {
+			cp = &zconfig.SignedCheckpoint{Num: num, Root: header.Root}
+		} else if cp.Root != header.Root {
+			utils.Fatalf("The root %x of block %d is not the root %x of the check points", header.Root, num, cp.Root)
+		}
+		if key != nil {
+			if err := cp.Sign(key); err != nil {
+				utils.Fatalf("Could not sign check point %d: %v", cp.Num, err)
+			}
+		}
+		delete(existing, num)
+		cps = append(cps, cp)
+	}
+	for _, cp := range existing {
+		cps = append(cps, cp)
+	}
+	sort.Slice(cps, func(i, j int) bool { return cps[i].Num < cps[j].Num })
+
+	data, err := json.MarshalIndent(cps, "", "  ")
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := ioutil.WriteFile(out, data, 0644); err != nil {
+		utils.Fatalf("Could not write the check points: %v", err)
+	}
+	fmt.Printf("Wrote %d check points to %s, the head is %d %x\n", len(cps), out, *headNum, head)
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:     "db",
+		Usage:    "Manage the databases of the node",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:   "migrate",
+				Usage:  "Convert the databases to the storage engine",
+				Action: utils.MigrateFlags(migrateDatabases),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.DatabaseEngineFlag,
+					configFileFlag,
+				},
+				Description: `
+    gero db migrate --db.engine <engine>
+
+Copies the chain, exchange, light, stake, backup and vote journal databases
+stored by another engine to the storage engine of --db.engine, every database
+replaces the original once it is fully copied. The node must not be running.`,
+			},
+		},
+	}
+)
+
+func migrateDatabases(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	engine := serodb.DefaultEngine
+	dirs := []string{
+		stack.ResolvePath("chaindata"),
+		stack.ResolvePath("lightchaindata"),
+		zconfig.Exchange_dir(),
+		zconfig.Light_dir(),
+		zconfig.Stake_dir(),
+		zconfig.Backup_dir(),
+	}
+	if cfg.Sero.Voter.Journal != "" {
+		dirs = append(dirs, stack.ResolvePath(cfg.Sero.Voter.Journal))
+	}
+	for _, dir := range dirs {
+		from := serodb.DetectEngine(dir)
+		if from == "" {
+			continue
+		}
+		if from == engine {
+			fmt.Printf("%s is already stored by %s\n", dir, engine)
+			continue
+		}
+		start := time.Now()
+		count, err := serodb.Migrate(dir, engine, ctx.GlobalInt(utils.CacheFlag.Name), 256)
+		if err != nil {
+			utils.Fatalf("Could not migrate %s: %v", dir, err)
+		}
+		fmt.Printf("Migrated %s from %s to %s, %d entries in %v\n", dir, from, engine, count, time.Since(start))
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io"
+	"math"
+	"os"
+
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/wallet/exchange"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	exchangePKFlag = cli.StringFlag{
+		Name:  "pk",
+		Usage: "Account PK (base58 or hex) to export",
+	}
+	exchangeBeginFlag = cli.Uint64Flag{
+		Name:  "begin",
+		Usage: "First block number of the export",
+	}
+	exchangeEndFlag = cli.Uint64Flag{
+		Name:  "end",
+		Usage: "Block number the export stops before (default: all)",
+		Value: math.MaxUint64,
+	}
+	exchangeFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format (csv or json)",
+		Value: "csv",
+	}
+	exchangeOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "Output file (default: stdout)",
+	}
+
+	exchangeCommand = cli.Command{
+		Name:     "exchange",
+		Usage:    "Manage the exchange database",
+		Category: "EXCHANGE COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:   "export",
+				Usage:  "Export the history of an account",
+				Action: utils.MigrateFlags(exportExchangeHistory),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					exchangePKFlag,
+					exchangeBeginFlag,
+					exchangeEndFlag,
+					exchangeFormatFlag,
+					exchangeOutFlag,
+				},
+				Description: `
+    gero exchange export --pk <PK> [--begin <num>] [--end <num>] [--format csv|json] [--out <file>]
+
+Writes one row per transaction and currency with the inflow, outflow, fee and
+running balance of the account. The rows are built from the exchange database
+only, so the node does not need to be running.`,
+			},
+		},
+	}
+)
+
+func exportExchangeHistory(ctx *cli.Context) error {
+	if !ctx.IsSet(exchangePKFlag.Name) {
+		utils.Fatalf("--%s is required", exchangePKFlag.Name)
+	}
+	var pk address.PKAddress
+	if err := pk.UnmarshalText([]byte(ctx.String(exchangePKFlag.Name))); err != nil {
+		utils.Fatalf("Invalid pk: %v", err)
+	}
+
+	makeConfigNode(ctx)
+
+	db, err := serodb.NewDatabaseEx(zconfig.Exchange_dir(), 16, 16, true)
+	if err != nil {
+		utils.Fatalf("Could not open exchange database: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := exchange.ExportHistory(db, pk.ToUint512(), ctx.Uint64(exchangeBeginFlag.Name), ctx.Uint64(exchangeEndFlag.Name))
+	if err != nil {
+		utils.Fatalf("Export failed: %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if file := ctx.String(exchangeOutFlag.Name); file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			utils.Fatalf("Could not create %s: %v", file, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	return exchange.WriteHistory(out, rows, ctx.String(exchangeFormatFlag.Name))
+}
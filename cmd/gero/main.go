@@ -79,7 +79,10 @@ var (
 		utils.EthashDatasetsInMemoryFlag,
 		utils.EthashDatasetsOnDiskFlag,
 		utils.TxPoolNoLocalsFlag,
+		utils.TxPoolJournalFlag,
+		utils.TxPoolRejournalFlag,
 		utils.TxPoolPriceLimitFlag,
+		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
@@ -89,6 +92,7 @@ var (
 		utils.GCModeFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
+		utils.DatabaseEngineFlag,
 		utils.CacheGCFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
@@ -115,10 +119,18 @@ var (
 		utils.ExchangeFlag,
 		utils.ExchangeValueStrFlag,
 		utils.StakeFlag,
+		utils.StakeFailoverFlag,
+		utils.StakeFailoverWaitFlag,
+		utils.StakeSignerFlag,
+		utils.StakeSignerTokenFileFlag,
 		utils.AutoMergeFlag,
 		utils.ConfirmedBlockFlag,
 		utils.RecordBlockShareNumber,
 		utils.LightNodeFlag,
+		utils.LightStartFlag,
+		utils.CheckpointFileFlag,
+		utils.CheckpointSignersFlag,
+		utils.CheckpointThresholdFlag,
 		utils.CloseAcceptTx,
 		utils.ResetBlockNumber,
 
@@ -149,6 +161,7 @@ var (
 		utils.ProofoinFeeFlag,
 		utils.ProofoutFeeFlag,
 		utils.ProofFixedFeeFlag,
+		utils.ProofFeeRatesFlag,
 	}
 
 	rpcFlags = []cli.Flag{
@@ -211,6 +224,14 @@ func init() {
 		dumpConfigCommand,
 		// See snapshotcmd.go:
 		snapshotCommand,
+		// See checkpointcmd.go:
+		checkpointCommand,
+		// See dbcmd.go:
+		dbCommand,
+		// See exchangecmd.go:
+		exchangeCommand,
+		// See votejournalcmd.go:
+		voteJournalCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -8,6 +8,19 @@ import (
 )
 
 var (
+	snapshotIncrementalFlag = cli.BoolFlag{
+		Name:  "incremental",
+		Usage: "Create an incremental snapshot on top of the base snapshot",
+	}
+	snapshotBaseFlag = cli.StringFlag{
+		Name:  "base",
+		Usage: "Manifest of the base snapshot of an incremental snapshot",
+	}
+	snapshotUntrustedFlag = cli.BoolFlag{
+		Name:  "untrusted",
+		Usage: "Import a head beyond the highest block verified against the chain checkpoints",
+	}
+
 	snapshotCommand = cli.Command{
 		Action:    utils.MigrateFlags(makeSnapshot),
 		Name:      "snapshot",
@@ -15,10 +28,57 @@ var (
 		ArgsUsage: "<targetChaindataDir>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			snapshotIncrementalFlag,
+			snapshotBaseFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export a snapshot to an archive file",
+				ArgsUsage: "<snapshotDir> <archiveFile>",
+				Action:    utils.MigrateFlags(exportSnapshot),
+				Description: `
+Writes the full snapshot created by "gero snapshot" to a single archive file of
+compressed chunks with a manifest of the head, the state root, the zero state
+roots of the head, the checkpoints and the hash of every chunk.`,
+			},
+			{
+				Name:      "verify",
+				Usage:     "Verify a snapshot archive file",
+				ArgsUsage: "<archiveFile>",
+				Action:    utils.MigrateFlags(verifySnapshot),
+				Description: `
+Checks every chunk of the archive against its manifest and the checkpoints of
+the manifest against the checkpoints of the chain.`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import a snapshot archive file",
+				ArgsUsage: "<archiveFile> [<chaindataDir>]",
+				Action:    utils.MigrateFlags(importSnapshot),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					snapshotUntrustedFlag,
+				},
+				Description: `
+Verifies the archive and writes the snapshot to the chain database, which must be
+empty. The chain database of the data directory is used if none is given.
+
+Only the blocks up to the highest checkpoint of the chain are verified against
+it. A head beyond that block is imported only with --untrusted.`,
+			},
+		},
 		Description: `
-The first argument must be the directory containing the blockchain to download from`,
+The first argument must be the directory containing the blockchain to download from
+
+An interrupted snapshot is resumed by running the command again with the same
+target. The manifest of the snapshot is written to the target when it is done.
+
+    gero snapshot --incremental --base <manifest> <targetChaindataDir>
+
+creates in the target only the blocks after the base head and the state the base
+snapshot next to the manifest does not have, then applies it on top of the base.`,
 	}
 )
 
@@ -35,12 +95,85 @@ func makeSnapshot(ctx *cli.Context) error {
 
 	fmt.Printf("%s ----> %s\n",srcDb,tarDb)
 
+	if ctx.Bool(snapshotIncrementalFlag.Name) {
+		return makeIncrementalSnapshot(ctx, srcDb, tarDb)
+	}
+
 	if sg,err:=snapshot.NewSnapshotGen(srcDb,tarDb);err!=nil {
 		println("Make snapshot error:",err.Error())
 	} else {
-		sg.Run()
+		if err := sg.Run(); err != nil {
+			println("Make snapshot error:", err.Error())
+		}
 		sg.Close()
 	}
 
 	return nil
 }
+
+func makeIncrementalSnapshot(ctx *cli.Context, srcDb string, tarDb string) error {
+	base := ctx.String(snapshotBaseFlag.Name)
+	if base == "" {
+		utils.Fatalf("--%s is required by an incremental snapshot", snapshotBaseFlag.Name)
+	}
+	sg, err := snapshot.NewIncrementalSnapshotGen(srcDb, tarDb, base)
+	if err != nil {
+		utils.Fatalf("Make snapshot error: %v", err)
+	}
+	err = sg.Run()
+	sg.Close()
+	if err != nil {
+		utils.Fatalf("Make snapshot error: %v", err)
+	}
+
+	applied, err := snapshot.Apply(base, tarDb)
+	if err != nil {
+		utils.Fatalf("Apply snapshot error: %v", err)
+	}
+	fmt.Printf("Applied snapshot %d %x on top of %s\n", applied.Number, applied.Hash, base)
+	return nil
+}
+
+func exportSnapshot(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires two arguments.")
+	}
+	am, err := snapshot.Export(ctx.Args().Get(0), ctx.Args().Get(1))
+	if err != nil {
+		utils.Fatalf("Export snapshot error: %v", err)
+	}
+	fmt.Printf("Exported snapshot %d %x in %d chunks\n", am.Number, am.Hash, len(am.Chunks))
+	return nil
+}
+
+func verifySnapshot(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	// The checkpoints of the chain are the ones of the network of the config.
+	makeConfigNode(ctx)
+	am, trusted, err := snapshot.Verify(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Verify snapshot error: %v", err)
+	}
+	fmt.Printf("Verified snapshot %d %x state %x in %d chunks\n", am.Number, am.Hash, am.Root, len(am.Chunks))
+	fmt.Printf("Trusted up to block %d by the chain checkpoints\n", trusted)
+	return nil
+}
+
+func importSnapshot(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 || len(ctx.Args()) > 2 {
+		utils.Fatalf("This command requires one or two arguments.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	dir := ctx.Args().Get(1)
+	if dir == "" {
+		dir = stack.ResolvePath("chaindata")
+	}
+	am, err := snapshot.Import(ctx.Args().First(), dir, ctx.Bool(snapshotUntrustedFlag.Name))
+	if err != nil {
+		utils.Fatalf("Import snapshot error: %v", err)
+	}
+	fmt.Printf("Imported snapshot %d %x to %s\n", am.Number, am.Hash, dir)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/voter"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	voteJournalFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block number to list",
+	}
+	voteJournalToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Block number the listing stops before (default: all)",
+		Value: math.MaxUint64,
+	}
+
+	voteJournalCommand = cli.Command{
+		Name:     "votejournal",
+		Usage:    "Inspect the journal of the signed votes",
+		Category: "STAKE COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "List the journaled votes",
+				Action: utils.MigrateFlags(listVoteJournal),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					configFileFlag,
+					voteJournalFromFlag,
+					voteJournalToFlag,
+				},
+				Description: `
+    gero votejournal list [--from <num>] [--to <num>]
+
+Prints every vote the node signed for the blocks in the range. The voter
+refuses to sign a second vote for a share and PoS hash found in the journal,
+so the listing tells which votes are re-broadcast after a restart. The node
+must not be running.`,
+			},
+		},
+	}
+)
+
+func listVoteJournal(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	if cfg.Sero.Voter.Journal == "" {
+		utils.Fatalf("Vote journal is disabled")
+	}
+
+	journal, err := voter.NewVoteJournal(stack.ResolvePath(cfg.Sero.Voter.Journal), true)
+	if err != nil {
+		utils.Fatalf("Could not open vote journal: %v", err)
+	}
+	defer journal.Close()
+
+	entries := journal.List(ctx.Uint64(voteJournalFromFlag.Name), ctx.Uint64(voteJournalToFlag.Name))
+	for _, entry := range entries {
+		vote := entry.Vote
+		fmt.Printf("block=%d poshash=%s share=%s idx=%d isPool=%v sign=%s time=%s\n",
+			vote.ParentNum+1, vote.PosHash.Hex(), vote.ShareId.Hex(), vote.Idx, vote.IsPool,
+			hexutil.Encode(vote.Sign[:]), time.Unix(int64(entry.Time), 0).Format(time.RFC3339))
+	}
+	fmt.Printf("%d votes\n", len(entries))
+	return nil
+}
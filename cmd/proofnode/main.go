@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
@@ -20,18 +21,71 @@ import (
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 	endpoint := flag.String("addr", ":9765", "listen address")
+	workerEndpoint := flag.String("workerAddr", "127.0.0.1:9766", "listen address of the prover workers")
+	coordinator := flag.String("coordinator", "", "url of the worker endpoint of the proof service to prove the jobs of as a worker")
+	secretFile := flag.String("secretfile", "", "file holding the secret of the prover workers, enables the workers of the service")
+	name := flag.String("name", "", "name of the worker")
+	workerTimeout := flag.Duration("workerTimeout", 30*time.Second, "time without heartbeat after which a worker is lost")
+	proveTimeout := flag.Duration("proveTimeout", 10*time.Minute, "time a worker has to submit the result of a job before it is handed out again")
 	rpc, config, tomeOut := initConfig()
-	if err := startNode(*endpoint, rpc, config, tomeOut); err != nil {
+	secret, err := readSecret(*secretFile)
+	if err != nil {
+		log.Printf("read secret file error: %v", err)
+		os.Exit(1)
+	}
+	if *coordinator != "" {
+		if err := startWorker(*coordinator, secret, *name, config.MaxWorkNumber); err != nil {
+			log.Printf("start worker error: %v", err)
+			os.Exit(1)
+		}
+		select {}
+	}
+	config.WorkerSecret = secret
+	config.WorkerTimeout = *workerTimeout
+	config.ProveTimeout = *proveTimeout
+	if err := startNode(*endpoint, *workerEndpoint, rpc, config, tomeOut); err != nil {
 		os.Exit(0)
 	}
 	select {}
 }
 
-func startNode(endpoint, rpcAddr string, config *proofservice.Config, timeout rpc.HTTPTimeouts) error {
+// readSecret reads the secret of the prover workers from the file, empty if no
+// file is given.
+func readSecret(file string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func startWorker(coordinator, secret, name string, threads int) error {
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	worker, err := proofservice.NewWorker(coordinator, secret, name, threads)
+	if err != nil {
+		return err
+	}
+	if err := worker.Start(); err != nil {
+		return err
+	}
+	log.Printf("Prover worker started, coordinator %s", coordinator)
+	return nil
+}
+
+func startNode(endpoint, workerEndpoint, rpcAddr string, config *proofservice.Config, timeout rpc.HTTPTimeouts) error {
 	if endpoint == "" {
 		return nil
 	}
-	proofservice.NewProofService(rpcAddr, nil, config)
+	service, err := proofservice.NewProofService(rpcAddr, nil, config)
+	if err != nil {
+		log.Printf("start proof service error: %v", err)
+		return err
+	}
 
 	apis := []rpc.API{
 		{
@@ -41,11 +95,18 @@ func startNode(endpoint, rpcAddr string, config *proofservice.Config, timeout rp
 			Public:    true,
 		}}
 
-	_, _, err := rpc.StartHTTPEndpoint(endpoint, apis, []string{"proof"}, []string{}, []string{}, timeout)
+	_, _, err = rpc.StartHTTPEndpoint(endpoint, apis, []string{"proof"}, []string{}, []string{}, timeout)
 	if err != nil {
 		return err
 	}
 	log.Printf("HTTP endpoint opened, url %s", fmt.Sprintf("http://%s", endpoint))
+
+	if config.WorkerSecret != "" {
+		if _, _, err := proofservice.StartWorkerEndpoint(workerEndpoint, service, config.WorkerSecret, timeout); err != nil {
+			return err
+		}
+		log.Printf("Worker endpoint opened, url %s", fmt.Sprintf("http://%s", workerEndpoint))
+	}
 	return nil
 }
 
@@ -64,14 +125,17 @@ func initConfig() (string, *proofservice.Config, rpc.HTTPTimeouts) {
 		oinFee   = flag.String("oinFee", "0sero", "oinFee")
 		outFee   = flag.String("outFee", "0sero", "outFee")
 		fixedFee = flag.String("fee", "0sero", "outFee")
+		feeRates = flag.String("feeRates", "", "currencies accepted for the fee besides sero, CURRENCY=rate,...")
 
 		pkrString = flag.String("pkr", "0sero", "outFee")
+		dataDir   = flag.String("datadir", "proofdata", "job database directory, empty keeps the jobs in memory")
 
 		// endpoint = flag.String("redis", "127.0.0.1:6379", "redis endpoint")
 		// password = flag.String("password", "", "redis password")
 		// database = flag.Int64("database", 0, "redis database")
 		// poolSize = flag.Int("poolSize", 10, "redis poolSize")
 	)
+	flag.Parse()
 
 	if strings.TrimSpace(*pkrString) == "" {
 		panic("pkr is empty")
@@ -94,8 +158,20 @@ func initConfig() (string, *proofservice.Config, rpc.HTTPTimeouts) {
 		panic(err)
 	}
 
+	rates, err := proofservice.ParseRates(*feeRates)
+	if err != nil {
+		panic(err)
+	}
+
 	pkr := c_type.NewPKrByBytes(base58.Decode(*pkrString))
 	timeout := rpc.HTTPTimeouts{*readTimeout, *writeTimeout, *idleTimeout}
 	fee := proofservice.ServiceFee{zinFeeAmount, oinFeeAmount, outFeeAmount, fixedFeeAmount}
-	return *rpcAddr, &proofservice.Config{pkr, *maxWorkNumber, *maxQueueNumber, fee}, timeout
+	return *rpcAddr, &proofservice.Config{
+		PKr:            pkr,
+		MaxWorkNumber:  *maxWorkNumber,
+		MaxQueueNumber: *maxQueueNumber,
+		Fee:            fee,
+		Rates:          rates,
+		DataDir:        *dataDir,
+	}, timeout
 }
@@ -68,6 +68,7 @@ import (
 	"github.com/sero-cash/go-sero/sero/downloader"
 	"github.com/sero-cash/go-sero/sero/gasprice"
 	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/voter"
 )
 
 var (
@@ -252,11 +253,26 @@ var (
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exemptions for locally submitted transactions",
 	}
+	TxPoolJournalFlag = cli.StringFlag{
+		Name:  "txpool.journal",
+		Usage: "Disk journal for pooled transactions to survive node restarts",
+		Value: sero.DefaultConfig.TxPool.Journal,
+	}
+	TxPoolRejournalFlag = cli.DurationFlag{
+		Name:  "txpool.rejournal",
+		Usage: "Time interval to regenerate the transaction journal",
+		Value: sero.DefaultConfig.TxPool.Rejournal,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
 		Value: sero.DefaultConfig.TxPool.PriceLimit,
 	}
+	TxPoolPriceBumpFlag = cli.Uint64Flag{
+		Name:  "txpool.pricebump",
+		Usage: "Price bump percentage to replace an already pooled transaction spending the same nils",
+		Value: sero.DefaultConfig.TxPool.PriceBump,
+	}
 	TxPoolAccountSlotsFlag = cli.Uint64Flag{
 		Name:  "txpool.accountslots",
 		Usage: "Minimum number of executable transaction slots guaranteed per account",
@@ -293,6 +309,11 @@ var (
 		Usage: "Percentage of cache memory allowance to use for database io",
 		Value: 75,
 	}
+	DatabaseEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Storage engine of the new databases (" + strings.Join(serodb.Engines(), ", ") + ")",
+		Value: serodb.DefaultEngine,
+	}
 	CacheGCFlag = cli.IntFlag{
 		Name:  "cache.gc",
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
@@ -330,6 +351,27 @@ var (
 		Usage: "start stake",
 	}
 
+	StakeFailoverFlag = cli.BoolFlag{
+		Name:  "stake.failover",
+		Usage: "Vote for own shares when their stake pool misses the vote",
+	}
+
+	StakeFailoverWaitFlag = cli.Float64Flag{
+		Name:  "stake.failoverwait",
+		Usage: "Fraction of the lottery wait window to wait for the pool vote before voting",
+		Value: voter.DefaultConfig.FailoverWait,
+	}
+
+	StakeSignerFlag = cli.StringFlag{
+		Name:  "stake.signer",
+		Usage: "Endpoint of the remote vote signer (default: sign with the node keystore)",
+	}
+
+	StakeSignerTokenFileFlag = cli.StringFlag{
+		Name:  "stake.signertokenfile",
+		Usage: "File holding the token authenticating the node to the remote vote signer",
+	}
+
 	AutoMergeFlag = cli.BoolFlag{
 		Name:  "autoMerge",
 		Usage: "autoMerge outs",
@@ -340,6 +382,27 @@ var (
 		Usage: "start light node",
 	}
 
+	LightStartFlag = cli.Uint64Flag{
+		Name:  "lightNode.start",
+		Usage: "Block number the light node indexes the blocks after (default: 1280000)",
+	}
+
+	CheckpointFileFlag = cli.StringFlag{
+		Name:  "checkpoint.file",
+		Usage: "File of the signed check points to trust and to save the added ones to (default: checkpoints.json in the data directory)",
+	}
+
+	CheckpointSignersFlag = cli.StringFlag{
+		Name:  "checkpoint.signers",
+		Usage: "Comma separated compressed public keys trusted to sign check points",
+	}
+
+	CheckpointThresholdFlag = cli.IntFlag{
+		Name:  "checkpoint.threshold",
+		Usage: "Number of the check point signers a check point needs",
+		Value: 1,
+	}
+
 	CloseAcceptTx = cli.BoolFlag{
 		Name:  "closeAcceptTx",
 		Usage: "Close Accept from remote Tx",
@@ -669,6 +732,10 @@ var (
 		Usage: "proof for tx out fee",
 		Value: "0sero",
 	}
+	ProofFeeRatesFlag = cli.StringFlag{
+		Name:  "feeRates",
+		Usage: "currencies accepted for the proof fee besides sero, as CURRENCY=rate,... with the units of the currency paying a unit of sero",
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -1073,6 +1140,13 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
+	if ctx.GlobalIsSet(DatabaseEngineFlag.Name) {
+		engine := ctx.GlobalString(DatabaseEngineFlag.Name)
+		if !serodb.ValidEngine(engine) {
+			Fatalf("--%s must be one of %s", DatabaseEngineFlag.Name, strings.Join(serodb.Engines(), ", "))
+		}
+		serodb.DefaultEngine = engine
+	}
 	if ctx.GlobalIsSet(TestForkFlag.Name) {
 		zconfig.Init_TestFork()
 		if ctx.GlobalIsSet(TestStartBlockFlag.Name) {
@@ -1101,9 +1175,18 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
+		cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
+		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.GlobalUint64(TxPoolPriceBumpFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolAccountSlotsFlag.Name) {
 		cfg.AccountSlots = ctx.GlobalUint64(TxPoolAccountSlotsFlag.Name)
 	}
@@ -1129,6 +1212,7 @@ func initProof(ctx *cli.Context) (cfg *proofservice.Config) {
 	cfg = &proofservice.Config{}
 	cfg.MaxWorkNumber = ctx.GlobalInt(ProofMaxThreadFlag.Name)
 	cfg.MaxQueueNumber = ctx.GlobalInt(ProofMaxQueueFlag.Name)
+	cfg.DataDir = zconfig.Proof_dir()
 	cfg.Fee = proofservice.ServiceFee{}
 
 	if ctx.GlobalIsSet(ProofzinFeeFlag.Name) {
@@ -1162,6 +1246,14 @@ func initProof(ctx *cli.Context) (cfg *proofservice.Config) {
 		}
 		cfg.Fee.FixedFee = fixedFee
 	}
+
+	if ctx.GlobalIsSet(ProofFeeRatesFlag.Name) {
+		rates, err := proofservice.ParseRates(ctx.GlobalString(ProofFeeRatesFlag.Name))
+		if err != nil {
+			Fatalf("Invalid --%s: %v", ProofFeeRatesFlag.Name, err)
+		}
+		cfg.Rates = rates
+	}
 	return
 }
 
@@ -1290,9 +1382,30 @@ func SetSeroConfig(ctx *cli.Context, stack *node.Node, cfg *sero.Config) {
 		cfg.StartStake = true
 	}
 
+	if ctx.GlobalIsSet(StakeFailoverFlag.Name) {
+		cfg.Voter.Failover = true
+	}
+	if ctx.GlobalIsSet(StakeFailoverWaitFlag.Name) {
+		cfg.Voter.FailoverWait = ctx.GlobalFloat64(StakeFailoverWaitFlag.Name)
+	}
+	if ctx.GlobalIsSet(StakeSignerFlag.Name) {
+		cfg.Voter.Signer = ctx.GlobalString(StakeSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(StakeSignerTokenFileFlag.Name) {
+		token, err := ioutil.ReadFile(ctx.GlobalString(StakeSignerTokenFileFlag.Name))
+		if err != nil {
+			Fatalf("Failed to read signer token file: %v", err)
+		}
+		cfg.Voter.SignerToken = strings.TrimRight(string(token), "\r\n")
+	}
+
 	if ctx.GlobalIsSet(LightNodeFlag.Name) {
 		cfg.StartLight = true
 	}
+	if ctx.GlobalIsSet(LightStartFlag.Name) {
+		zconfig.Init_LightStartNum(ctx.GlobalUint64(LightStartFlag.Name))
+	}
+	setCheckpoints(ctx, stack)
 
 	if ctx.GlobalIsSet(CloseAcceptTx.Name) {
 		cfg.CloseAcceptTx = true
@@ -1517,3 +1630,33 @@ func MigrateFlags(action func(ctx *cli.Context) error) func(*cli.Context) error
 		return action(ctx)
 	}
 }
+
+// setCheckpoints trusts the check point signers and loads the signed check points.
+func setCheckpoints(ctx *cli.Context, stack *node.Node) {
+	if !ctx.GlobalIsSet(CheckpointSignersFlag.Name) {
+		if ctx.GlobalIsSet(CheckpointFileFlag.Name) {
+			Fatalf("--%s requires --%s", CheckpointFileFlag.Name, CheckpointSignersFlag.Name)
+		}
+		return
+	}
+	signers := strings.Split(ctx.GlobalString(CheckpointSignersFlag.Name), ",")
+	if err := zconfig.Init_CheckpointSigners(signers, ctx.GlobalInt(CheckpointThresholdFlag.Name)); err != nil {
+		Fatalf("Invalid check point signers: %v", err)
+	}
+	// The check points added at runtime are saved to the file, the one of the
+	// data directory if none is given
+	file := ctx.GlobalString(CheckpointFileFlag.Name)
+	if file == "" {
+		file = stack.ResolvePath("checkpoints.json")
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			zconfig.Init_CheckpointFile(file)
+			return
+		}
+	}
+	count, err := zconfig.LoadCheckpoints(file)
+	if err != nil {
+		Fatalf("Failed to load check points: %v", err)
+	}
+	log.Info("Loaded signed check points", "file", file, "count", count, "max", zconfig.CheckPoints.MaxNum())
+	zconfig.Init_CheckpointFile(file)
+}
@@ -0,0 +1,123 @@
+// votesigner holds the voting keys of a staking node and signs its votes over
+// IPC, so the keys never have to be unlocked on the node itself. It only signs
+// for the vote PKrs listed in -pkrfile and journals every signature, so it
+// never signs the same vote twice.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/superzk"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/accounts/keystore"
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/voter"
+)
+
+func readFile(name, file string) string {
+	if file == "" {
+		utils.Fatalf("-%s is required", name)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		utils.Fatalf("-%s: %v", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n")
+}
+
+// readPKrs reads the base58 vote PKrs of file, one per line.
+func readPKrs(file string) (pkrs []c_type.PKr) {
+	for _, line := range strings.Split(readFile("pkrfile", file), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pkr := common.Base58ToAddress(line).ToPKr()
+		if !superzk.IsPKrValid(pkr) {
+			utils.Fatalf("-pkrfile: invalid vote pkr %s", line)
+		}
+		pkrs = append(pkrs, *pkr)
+	}
+	if len(pkrs) == 0 {
+		utils.Fatalf("-pkrfile: no vote pkr")
+	}
+	return
+}
+
+func main() {
+	var (
+		keydir    = flag.String("keystore", "", "directory of the voting keys")
+		socket    = flag.String("socket", "votesigner.ipc", "IPC socket the node connects to")
+		tokenFile = flag.String("tokenfile", "", "file holding the token shared with the node")
+		password  = flag.String("password", "", "file holding the password of the voting keys")
+		pkrFile   = flag.String("pkrfile", "", "file holding the vote PKrs to sign for, one per line")
+		journal   = flag.String("journal", "votesigner.journal", "database journaling the signatures")
+		net       = flag.String("net", "beta", "network of the keys (beta|alpha|dev)")
+		verbosity = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-9)")
+	)
+	flag.Parse()
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(*verbosity))
+	log.Root().SetHandler(glogger)
+
+	if *keydir == "" {
+		utils.Fatalf("-keystore is required")
+	}
+	token := readFile("tokenfile", *tokenFile)
+	passphrase := readFile("password", *password)
+
+	netType := c_type.NET_Beta
+	switch *net {
+	case "alpha":
+		netType = c_type.NET_Alpha
+	case "dev":
+		netType = c_type.NET_Dev
+	}
+	superzk.ZeroInit(*keydir, netType)
+	pkrs := readPKrs(*pkrFile)
+
+	ks := keystore.NewKeyStore(*keydir, keystore.StandardScryptN, keystore.StandardScryptP)
+	for _, account := range ks.Accounts() {
+		if err := ks.Unlock(account, passphrase); err != nil {
+			utils.Fatalf("Failed to unlock %s: %v", account.Address.String(), err)
+		}
+		log.Info("Unlocked voting key", "address", account.Address.String())
+	}
+
+	signatures, err := voter.NewVoteJournal(*journal, false)
+	if err != nil {
+		utils.Fatalf("Failed to open the signature journal %s: %v", *journal, err)
+	}
+	defer signatures.Close()
+
+	service := voter.NewSignerService(voter.NewKeystoreSigner(accounts.NewManager(ks)), token, pkrs, signatures)
+	listener, server, err := rpc.StartIPCEndpoint(*socket, []rpc.API{
+		{
+			Namespace: "signer",
+			Version:   "1.0",
+			Service:   service,
+		},
+	})
+	if err != nil {
+		utils.Fatalf("Failed to listen on %s: %v", *socket, err)
+	}
+	log.Info("Vote signer started", "socket", *socket)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	<-sigc
+	log.Info("Vote signer stopping")
+	listener.Close()
+	server.Stop()
+}
@@ -0,0 +1,201 @@
+// Package pkrfilter implements the compact block filters over the PKrs of the
+// outputs of a block, Golomb-coded sets in the style of BIP-158. A light wallet
+// downloads the filters, tests its PKrs locally and only fetches the blocks
+// that match, so the server never learns the PKrs of the wallet.
+package pkrfilter
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sort"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+)
+
+const (
+	// P is the bit length of the remainders of the Golomb-Rice coding.
+	P = 19
+	// M is the inverse false positive rate of a single item.
+	M = 784931
+)
+
+var errFilterData = errors.New("invalid filter data")
+
+// BlockFilter is the filter of a block.
+type BlockFilter struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+	Filter hexutil.Bytes  `json:"filter"`
+}
+
+// Key is the SipHash key of the filter of the block hash.
+func Key(hash common.Hash) (key [16]byte) {
+	copy(key[:], hash[:16])
+	return
+}
+
+// Filter is a Golomb-coded set of items.
+type Filter struct {
+	n    uint32
+	data []byte
+}
+
+// New builds the filter of items under key.
+func New(key [16]byte, items [][]byte) *Filter {
+	distinct := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		distinct[string(item)] = struct{}{}
+	}
+	f := &Filter{n: uint32(len(distinct))}
+	if f.n == 0 {
+		return f
+	}
+	values := make([]uint64, 0, f.n)
+	for item := range distinct {
+		values = append(values, hashToRange(key, []byte(item), f.modulus()))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	var w bitWriter
+	last := uint64(0)
+	for _, v := range values {
+		delta := v - last
+		last = v
+		for q := delta >> P; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, P)
+	}
+	f.data = w.bytes
+	return f
+}
+
+// FromBytes decodes a filter encoded by Bytes.
+func FromBytes(data []byte) (*Filter, error) {
+	n, size := binary.Uvarint(data)
+	if size <= 0 || n > uint64(^uint32(0)) {
+		return nil, errFilterData
+	}
+	return &Filter{n: uint32(n), data: common.CopyBytes(data[size:])}, nil
+}
+
+// Bytes encodes the filter as the item count followed by the coded set.
+func (f *Filter) Bytes() []byte {
+	buf := make([]byte, binary.MaxVarintLen32, binary.MaxVarintLen32+len(f.data))
+	return append(buf[:binary.PutUvarint(buf, uint64(f.n))], f.data...)
+}
+
+// N is the number of distinct items of the filter.
+func (f *Filter) N() uint32 {
+	return f.n
+}
+
+func (f *Filter) modulus() uint64 {
+	return uint64(f.n) * M
+}
+
+// Match reports whether item may be in the filter.
+func (f *Filter) Match(key [16]byte, item []byte) bool {
+	return f.MatchAny(key, [][]byte{item})
+}
+
+// MatchAny reports whether any of items may be in the filter.
+func (f *Filter) MatchAny(key [16]byte, items [][]byte) bool {
+	if f.n == 0 || len(items) == 0 {
+		return false
+	}
+	targets := make([]uint64, len(items))
+	for i, item := range items {
+		targets[i] = hashToRange(key, item, f.modulus())
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+
+	r := bitReader{data: f.data}
+	value := uint64(0)
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := r.readDelta()
+		if err != nil {
+			return false
+		}
+		value += delta
+		for targets[0] < value {
+			if targets = targets[1:]; len(targets) == 0 {
+				return false
+			}
+		}
+		if targets[0] == value {
+			return true
+		}
+	}
+	return false
+}
+
+func hashToRange(key [16]byte, item []byte, modulus uint64) uint64 {
+	hi, _ := bits.Mul64(sipHash(key, item), modulus)
+	return hi
+}
+
+type bitWriter struct {
+	bytes []byte
+	used  uint8
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.used == 0 {
+		w.bytes = append(w.bytes, 0)
+		w.used = 8
+	}
+	if bit {
+		w.bytes[len(w.bytes)-1] |= 1 << (w.used - 1)
+	}
+	w.used--
+}
+
+func (w *bitWriter) writeBits(value uint64, n uint) {
+	for i := n; i > 0; i-- {
+		w.writeBit(value&(1<<(i-1)) != 0)
+	}
+}
+
+type bitReader struct {
+	data []byte
+	pos  uint
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos >= uint(len(r.data))*8 {
+		return false, errFilterData
+	}
+	bit := r.data[r.pos/8]&(0x80>>(r.pos%8)) != 0
+	r.pos++
+	return bit, nil
+}
+
+func (r *bitReader) readDelta() (uint64, error) {
+	q := uint64(0)
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		q++
+	}
+	rem := uint64(0)
+	for i := 0; i < P; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		rem <<= 1
+		if bit {
+			rem |= 1
+		}
+	}
+	return q<<P | rem, nil
+}
@@ -0,0 +1,67 @@
+package pkrfilter
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+)
+
+func TestSipHash(t *testing.T) {
+	var key [16]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	// reference vectors of the SipHash-2-4 paper
+	if h := sipHash(key, nil); h != 0x726fdb47dd0e0e31 {
+		t.Errorf("empty message: have %x", h)
+	}
+	msg := make([]byte, 15)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	if h := sipHash(key, msg); h != 0xa129ca6149be45e5 {
+		t.Errorf("15 bytes message: have %x", h)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	key := Key(common.Hash{1, 2, 3})
+	var items [][]byte
+	for i := 0; i < 200; i++ {
+		item := make([]byte, 96)
+		rand.Read(item)
+		items = append(items, item)
+	}
+	filter, err := FromBytes(New(key, append(items, items[0])).Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.N() != 200 {
+		t.Errorf("items mismatch: have %d, want 200", filter.N())
+	}
+	for i, item := range items {
+		if !filter.Match(key, item) {
+			t.Fatalf("item %d not matched", i)
+		}
+	}
+
+	misses := 0
+	for i := 0; i < 1000; i++ {
+		item := make([]byte, 96)
+		rand.Read(item)
+		if !filter.Match(key, item) {
+			misses++
+		}
+		if filter.MatchAny(key, [][]byte{item, items[i%len(items)]}) == false {
+			t.Fatalf("item %d not matched among others", i%len(items))
+		}
+	}
+	if misses < 999 {
+		t.Errorf("too many false positives: %d", 1000-misses)
+	}
+
+	if empty := New(key, nil); empty.Match(key, items[0]) {
+		t.Errorf("empty filter matched")
+	}
+}
@@ -105,3 +105,15 @@ func WriteBloomBits(db DatabaseWriter, bit uint, section uint64, head common.Has
 		log.Crit("Failed to store bloom bits", "err", err)
 	}
 }
+
+// ReadPKrFilter retrieves the pkr filter of the block.
+func ReadPKrFilter(db DatabaseReader, number uint64, hash common.Hash) ([]byte, error) {
+	return db.Get(pkrFilterKey(number, hash))
+}
+
+// WritePKrFilter stores the pkr filter of the block.
+func WritePKrFilter(db DatabaseWriter, number uint64, hash common.Hash, filter []byte) {
+	if err := db.Put(pkrFilterKey(number, hash), filter); err != nil {
+		log.Crit("Failed to store pkr filter", "err", err)
+	}
+}
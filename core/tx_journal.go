@@ -20,6 +20,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"time"
 
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/log"
@@ -39,11 +40,36 @@ type devNull struct{}
 func (*devNull) Write(p []byte) (n int, err error) { return len(p), nil }
 func (*devNull) Close() error                      { return nil }
 
-// txJournal is a rotating log of transactions with the aim of storing locally
-// created transactions to allow non-executed ones to survive node restarts.
+// journalEntry is a journaled transaction with its arrival time and whether it
+// was submitted locally.
+type journalEntry struct {
+	Tx    *types.Transaction
+	Time  uint64 // Arrival time in unix nanoseconds
+	Local bool
+}
+
+// JournalStats reports the transaction journal of the pool.
+type JournalStats struct {
+	Path       string    `json:"path"`
+	Entries    int       `json:"entries"` // Transactions written since the last rotation
+	Size       int64     `json:"size"`
+	Loaded     int       `json:"loaded"`  // Transactions reloaded on startup
+	Pruned     int       `json:"pruned"`  // Transactions with spent nils skipped on startup
+	Dropped    int       `json:"dropped"` // Transactions failing revalidation on startup
+	LastRotate time.Time `json:"lastRotate"`
+}
+
+// txJournal is a rotating log of transactions with the aim of storing the
+// pooled transactions to allow non-executed ones to survive node restarts.
 type txJournal struct {
 	path   string         // Filesystem path to store the transactions at
 	writer io.WriteCloser // Output stream to write new transactions into
+
+	entries int
+	loaded  int
+	pruned  int
+	dropped int
+	rotated time.Time
 }
 
 // newTxJournal creates a new transaction journal to
@@ -54,8 +80,9 @@ func newTxJournal(path string) *txJournal {
 }
 
 // load parses a transaction journal dump from disk, loading its contents into
-// the specified pool.
-func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
+// the specified pool. The transactions whose nils are already spent are pruned
+// without being added.
+func (journal *txJournal) load(add func([]*journalEntry) []error, spent func(*types.Transaction) bool) error {
 	// Skip the parsing if the journal file doens't exist at all
 	if _, err := os.Stat(journal.path); os.IsNotExist(err) {
 		return nil
@@ -73,13 +100,13 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 
 	// Inject all transactions from the journal into the pool
 	stream := rlp.NewStream(input, 0)
-	total, dropped := 0, 0
+	total, pruned, dropped := 0, 0, 0
 
 	// Create a method to load a limited batch of transactions and bump the
 	// appropriate progress counters. Then use this method to load all the
 	// journalled transactions in small-ish batches.
-	loadBatch := func(txs types.Transactions) {
-		for _, err := range add(txs) {
+	loadBatch := func(entries []*journalEntry) {
+		for _, err := range add(entries) {
 			if err != nil {
 				log.Debug("Failed to add journaled transaction", "err", err)
 				dropped++
@@ -88,16 +115,16 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 	}
 	var (
 		failure error
-		batch   types.Transactions
+		batch   []*journalEntry
 	)
 	for {
 		// Parse the next transaction and terminate on error
-		tx := new(types.Transaction)
-		if err = stream.Decode(tx); err != nil {
+		entry := new(journalEntry)
+		if err = stream.Decode(entry); err != nil {
 			if err != io.EOF {
 				failure = err
 			}
-			if batch.Len() > 0 {
+			if len(batch) > 0 {
 				loadBatch(batch)
 			}
 			break
@@ -105,30 +132,36 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 		// New transaction parsed, queue up for later, import if threnshold is reached
 		total++
 
-		if batch = append(batch, tx); batch.Len() > 1024 {
+		if spent(entry.Tx) {
+			pruned++
+			continue
+		}
+		if batch = append(batch, entry); len(batch) > 1024 {
 			loadBatch(batch)
 			batch = batch[:0]
 		}
 	}
-	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
+	journal.loaded, journal.pruned, journal.dropped = total-pruned-dropped, pruned, dropped
+	log.Info("Loaded transaction journal", "transactions", total, "pruned", pruned, "dropped", dropped)
 
 	return failure
 }
 
 // insert adds the specified transaction to the local disk journal.
-func (journal *txJournal) insert(tx *types.Transaction) error {
+func (journal *txJournal) insert(entry *journalEntry) error {
 	if journal.writer == nil {
 		return errNoActiveJournal
 	}
-	if err := rlp.Encode(journal.writer, tx); err != nil {
+	if err := rlp.Encode(journal.writer, entry); err != nil {
 		return err
 	}
+	journal.entries++
 	return nil
 }
 
 // rotate regenerates the transaction journal based on the current contents of
 // the transaction pool.
-func (journal *txJournal) rotate() error {
+func (journal *txJournal) rotate(all []*journalEntry) error {
 	// Close the current journal (if any is open)
 	if journal.writer != nil {
 		if err := journal.writer.Close(); err != nil {
@@ -141,7 +174,12 @@ func (journal *txJournal) rotate() error {
 	if err != nil {
 		return err
 	}
-
+	for _, entry := range all {
+		if err = rlp.Encode(replacement, entry); err != nil {
+			replacement.Close()
+			return err
+		}
+	}
 	replacement.Close()
 
 	// Replace the live journal with the newly generated one
@@ -153,11 +191,29 @@ func (journal *txJournal) rotate() error {
 		return err
 	}
 	journal.writer = sink
-	log.Info("Regenerated local transaction journal", "transactions", "accounts")
+	journal.entries = len(all)
+	journal.rotated = time.Now()
+	log.Info("Regenerated transaction journal", "transactions", len(all))
 
 	return nil
 }
 
+// stats returns the statistics of the journal.
+func (journal *txJournal) stats() JournalStats {
+	stats := JournalStats{
+		Path:       journal.path,
+		Entries:    journal.entries,
+		Loaded:     journal.loaded,
+		Pruned:     journal.pruned,
+		Dropped:    journal.dropped,
+		LastRotate: journal.rotated,
+	}
+	if info, err := os.Stat(journal.path); err == nil {
+		stats.Size = info.Size()
+	}
+	return stats
+}
+
 // close flushes the transaction journal contents to disk and closes the file.
 func (journal *txJournal) close() error {
 	var err error
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate/data"
+)
+
+// journalChain serves the head block of the journal test pool.
+type journalChain struct {
+	blockChain
+	head *types.Block
+}
+
+func (self *journalChain) CurrentBlock() *types.Block {
+	return self.head
+}
+
+func TestTxJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txjournal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	journal := newTxJournal(filepath.Join(dir, "transactions.rlp"))
+	if err := journal.rotate(nil); err != nil {
+		t.Fatal(err)
+	}
+	entries := []*journalEntry{
+		{Tx: nilsTx(1, 100, 1), Time: 10, Local: true},
+		{Tx: nilsTx(2, 100, 2), Time: 20},
+		{Tx: nilsTx(3, 100, 3), Time: 30},
+	}
+	for _, entry := range entries {
+		if err := journal.insert(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	journal.close()
+
+	// Reload, pruning the spent transaction and dropping a failing one
+	var loaded []*journalEntry
+	add := func(batch []*journalEntry) []error {
+		errs := make([]error, len(batch))
+		for i, entry := range batch {
+			if entry.Tx.Hash() == entries[2].Tx.Hash() {
+				errs[i] = ErrUnderpriced
+				continue
+			}
+			loaded = append(loaded, entry)
+		}
+		return errs
+	}
+	spent := func(tx *types.Transaction) bool { return tx.Hash() == entries[1].Tx.Hash() }
+	journal = newTxJournal(journal.path)
+	if err := journal.load(add, spent); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0].Tx.Hash() != entries[0].Tx.Hash() || loaded[0].Time != 10 || !loaded[0].Local {
+		t.Fatalf("loaded entries mismatch: %v", loaded)
+	}
+	if stats := journal.stats(); stats.Loaded != 1 || stats.Pruned != 1 || stats.Dropped != 1 || stats.Size == 0 {
+		t.Fatalf("journal stats mismatch: %+v", stats)
+	}
+
+	// Rotation keeps only the given transactions
+	if err := journal.rotate(loaded); err != nil {
+		t.Fatal(err)
+	}
+	journal.close()
+	loaded = nil
+	if err := newTxJournal(journal.path).load(add, spent); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0].Tx.Hash() != entries[0].Tx.Hash() {
+		t.Fatalf("rotated entries mismatch: %v", loaded)
+	}
+}
+
+func TestTxPoolJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txjournal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	header := &types.Header{Number: big.NewInt(0), GasLimit: 8000000}
+	statedb, err := state.New(state.NewDatabase(serodb.NewMemDatabase()), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := newReplaceTestPool()
+	pool.chain = &journalChain{head: types.NewBlockWithHeader(header)}
+	pool.currentState = statedb
+	pool.currentMaxGas = header.GasLimit
+	pool.faileds = make(map[common.Hash]time.Time)
+	pool.arrivals = make(map[common.Hash]*journalEntry)
+	pool.journal = newTxJournal(filepath.Join(dir, "transactions.rlp"))
+	if err := pool.journal.rotate(nil); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.journal.close()
+
+	// The nils spent in the chain prune the journaled transactions
+	spent := c_type.Uint256{1}
+	if err := statedb.CurrentZState().Tri.TryUpdate(data.InName(&spent), []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if !pool.nilsSpent(nilsTx(1, 100, 1, 2)) {
+		t.Fatalf("transaction spending a chain nil not detected")
+	}
+	if pool.nilsSpent(nilsTx(2, 100, 3)) {
+		t.Fatalf("transaction with unspent nils detected as spent")
+	}
+
+	// A journaled transaction failing the validation is not pooled again
+	invalid := &journalEntry{Tx: nilsTx(3, 100, 4), Time: 10, Local: true}
+	if errs := pool.addJournaled([]*journalEntry{invalid}); len(errs) != 1 || errs[0] == nil {
+		t.Fatalf("invalid journaled transaction added: %v", errs)
+	}
+	if pool.all.Get(invalid.Tx.Hash()) != nil || pool.arrivals[invalid.Tx.Hash()] != nil {
+		t.Fatalf("invalid journaled transaction pooled")
+	}
+
+	// The arrivals leave with the transactions
+	tx := nilsTx(4, 100, 5)
+	if _, err := pool.enqueueTx(tx.Hash(), tx); err != nil {
+		t.Fatal(err)
+	}
+	pool.journalTx(tx, true)
+	if entries := pool.journalEntries(); len(entries) != 1 || entries[0].Tx.Hash() != tx.Hash() || !entries[0].Local {
+		t.Fatalf("journal entries mismatch: %v", entries)
+	}
+	pool.removeAllTx(tx.Hash())
+	if _, ok := pool.arrivals[tx.Hash()]; ok {
+		t.Fatalf("arrival of a removed transaction kept")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"math"
 	"math/big"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -113,9 +114,12 @@ type blockChain interface {
 
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
-	NoLocals bool // Whether local transaction handling should be disabled
+	NoLocals  bool          // Whether local transaction handling should be disabled
+	Journal   string        // Journal of pooled transactions to survive node restarts
+	Rejournal time.Duration // Time interval to regenerate the transaction journal
 
 	PriceLimit uint64 // Minimum gas priced to enforce for acceptance into the pool
+	PriceBump  uint64 // Minimum price bump percentage to replace a transaction spending the same nils
 
 	AccountSlots uint64 // Number of executable transaction slots guaranteed per account
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
@@ -130,8 +134,11 @@ type TxPoolConfig struct {
 // DefaultTxPoolConfig contains the default configurations for the transaction
 // pool.
 var DefaultTxPoolConfig = TxPoolConfig{
+	Journal:   "transactions.rlp",
+	Rejournal: time.Hour,
 
 	PriceLimit:   params.Gta,
+	PriceBump:    10,
 	AccountSlots: 16,
 	GlobalSlots:  2048,
 	AccountQueue: 64,
@@ -149,6 +156,14 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool priced limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
 	}
+	if conf.Rejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
+		conf.Rejournal = time.Second
+	}
+	if conf.PriceBump < 1 {
+		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
+		conf.PriceBump = DefaultTxPoolConfig.PriceBump
+	}
 	return conf
 }
 
@@ -339,7 +354,8 @@ type TxPool struct {
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
 	//locals *accountSet // Set of local transaction to exempt from eviction rules
-	//journal *txJournal  // Journal of local transaction to back up to disk
+	journal  *txJournal                    // Journal of pooled transactions to back up to disk
+	arrivals map[common.Hash]*journalEntry // Arrival of the journaled transactions
 
 	all        *txLookup     // All transactions to allow lookups
 	priced     *txPricedList // All transactions sorted by priced
@@ -347,6 +363,7 @@ type TxPool struct {
 	newPending *txPricedList
 	beats      hashTime
 	faileds    hashTime
+	nils       map[c_type.Uint256]common.Hash // Pooled transaction spending each nil
 
 	wg sync.WaitGroup // for shutdown sync
 
@@ -369,6 +386,8 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		chain:       chain,
 		beats:       make(map[common.Hash]time.Time),
 		faileds:     make(map[common.Hash]time.Time),
+		nils:        make(map[c_type.Uint256]common.Hash),
+		arrivals:    make(map[common.Hash]*journalEntry),
 		all:         newTxLookup(),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
@@ -380,6 +399,17 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	pool.newPending = newTxPricedList(newTxLookup())
 	pool.reset(nil, chain.CurrentBlock().Header())
 
+	// If journaling is enabled, load the transactions from disk
+	if config.Journal != "" {
+		pool.journal = newTxJournal(config.Journal)
+
+		if err := pool.journal.load(pool.addJournaled, pool.nilsSpent); err != nil {
+			log.Warn("Failed to load transaction journal", "err", err)
+		}
+		if err := pool.journal.rotate(pool.journalEntries()); err != nil {
+			log.Warn("Failed to rotate transaction journal", "err", err)
+		}
+	}
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
@@ -413,6 +443,9 @@ func (pool *TxPool) loop() {
 	evict := time.NewTicker(evictionInterval)
 	defer evict.Stop()
 
+	journal := time.NewTicker(pool.config.Rejournal)
+	defer journal.Stop()
+
 	// Track the previous head headers for transaction reorgs
 	head := pool.chain.CurrentBlock()
 
@@ -481,8 +514,18 @@ func (pool *TxPool) loop() {
 			for _, h := range dropFaileds {
 				delete(pool.faileds, h)
 			}
+			pool.pruneNils()
 			pool.mu.Unlock()
 
+			// Handle journal rotation
+		case <-journal.C:
+			if pool.journal != nil {
+				pool.mu.Lock()
+				if err := pool.journal.rotate(pool.journalEntries()); err != nil {
+					log.Warn("Failed to rotate transaction journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -507,11 +550,11 @@ func (pool *TxPool) lockedReset(oldHead, newHead *types.Header) {
 }
 
 func (pool *TxPool) canAddPkrTx() bool {
-	difference := time.Now().Unix() - pool.chain.CurrentBlock().Time().Int64()
-	if difference > 10*60 {
+	if !pool.config.StartLight {
 		return false
 	}
-	return pool.config.StartLight
+	difference := time.Now().Unix() - pool.chain.CurrentBlock().Time().Int64()
+	return difference <= 10*60
 }
 
 // reset retrieves the current state of the blockchain and ensures the content
@@ -624,6 +667,9 @@ func (pool *TxPool) Stop() {
 	pool.chainHeadSub.Unsubscribe()
 	pool.wg.Wait()
 
+	if pool.journal != nil {
+		pool.journal.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -642,10 +688,20 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	pool.priced.RemoveWithPrice(pool.gasPrice)
 	pool.newQueue.RemoveWithPrice(pool.gasPrice)
 	pool.newPending.RemoveWithPrice(pool.gasPrice)
+	for hash := range pool.arrivals {
+		if pool.all.Get(hash) == nil {
+			delete(pool.arrivals, hash)
+		}
+	}
 
 	log.Info("Transaction pool priced threshold updated", "priced", pool.gasPrice)
 }
 
+// Config returns the sanitized configuration the pool runs with.
+func (pool *TxPool) Config() TxPoolConfig {
+	return pool.config
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -844,9 +900,9 @@ func (pool *TxPool) checkDescCmd(tx *stx.T, state *state.StateDB) (err error) {
 }
 
 // add validates a transaction and inserts it into the non-executable queue for
-// later pending promotion and execution. If the transaction is a replacement for
-// an already pending or queued one, it overwrites the previous and returns this
-// so outer code doesn't uselessly call promote.
+// later pending promotion and execution. If the transaction spends all the nils
+// of already pending or queued ones at a gas price bumped by PriceBump percent,
+// it replaces them.
 //
 // If a newly added transaction is marked as local, its sending account will be
 // whitelisted, preventing any associated transaction from being dropped out of
@@ -879,6 +935,12 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
+	// A transaction spending the nils of pooled ones must replace them all
+	olds, err := pool.replaced(tx)
+	if err != nil {
+		log.Info("Discarding conflicting transaction", "hash", hash.Hex(), "priced", tx.GasPrice(), "err", err)
+		return false, err
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -902,6 +964,8 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	pool.replace(tx, olds)
+	pool.journalTx(tx, local)
 	if pool.canAddPkrTx() {
 		pool.pkrTxOuts.AddPendingTxOut(*tx)
 	}
@@ -932,6 +996,94 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	return true, nil
 }
 
+// journalTx adds the specified transaction to the journal with its arrival
+// time and origin.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) journalTx(tx *types.Transaction, local bool) {
+	if pool.journal == nil {
+		return
+	}
+	hash := tx.Hash()
+	if _, ok := pool.arrivals[hash]; ok {
+		return
+	}
+	entry := &journalEntry{Tx: tx, Time: uint64(time.Now().UnixNano()), Local: local}
+	pool.arrivals[hash] = entry
+	if err := pool.journal.insert(entry); err != nil {
+		log.Warn("Failed to journal transaction", "hash", hash.Hex(), "err", err)
+	}
+}
+
+// journalEntries returns the journaled transactions still in the pool by
+// arrival time.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) journalEntries() []*journalEntry {
+	entries := make([]*journalEntry, 0, len(pool.arrivals))
+	for hash, entry := range pool.arrivals {
+		if pool.all.Get(hash) == nil {
+			delete(pool.arrivals, hash)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Time < entries[j].Time })
+	return entries
+}
+
+// addJournaled revalidates and adds the transactions loaded from the journal,
+// keeping their arrival time and origin. The local ones are broadcast again.
+func (pool *TxPool) addJournaled(entries []*journalEntry) []error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	errs := make([]error, len(entries))
+	added, broadcast := 0, false
+	for i, entry := range entries {
+		local := entry.Local && !pool.config.NoLocals
+		if _, errs[i] = pool.add(entry.Tx, local); errs[i] == nil {
+			if arrival, ok := pool.arrivals[entry.Tx.Hash()]; ok {
+				arrival.Time = entry.Time
+				arrival.Local = entry.Local
+			}
+			added++
+			broadcast = broadcast || local
+		}
+	}
+	if added > 0 {
+		pool.promoteExecutables(broadcast)
+	}
+	return errs
+}
+
+// nilsSpent reports whether some nils of the transaction are already spent in
+// the chain.
+func (pool *TxPool) nilsSpent(tx *types.Transaction) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	state := pool.currentState.CurrentZState()
+	for _, Nil := range txNils(tx) {
+		if state.State.HasIn(&Nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// JournalStats returns the statistics of the transaction journal, the zero
+// value when journaling is disabled.
+func (pool *TxPool) JournalStats() JournalStats {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	if pool.journal == nil {
+		return JournalStats{}
+	}
+	return pool.journal.stats()
+}
+
 // AddLocal enqueues a single transaction into the pool if it is valid, marking
 // the sender as a local one in the mean time, ensuring it goes around the local
 // pricing constraints.
@@ -1066,6 +1218,7 @@ func (pool *TxPool) removeAllTx(hash common.Hash) {
 
 	pool.priced.Remove(tx)
 	delete(pool.beats, hash)
+	delete(pool.arrivals, hash)
 	//Remove it from the list of known transactions
 	if pool.newQueue.Remove(tx) {
 		return
@@ -1078,6 +1231,7 @@ func (pool *TxPool) removeAllTx(hash common.Hash) {
 func (pool *TxPool) removeWorkQueue(tx *types.Transaction) {
 
 	delete(pool.beats, tx.Hash())
+	delete(pool.arrivals, tx.Hash())
 	//Remove it from the list of known transactions
 	if pool.newQueue.Remove(tx) {
 		return
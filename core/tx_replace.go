@@ -0,0 +1,117 @@
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+)
+
+var (
+	// ErrReplaceUnderpriced is returned if a transaction spending the nils of a
+	// pooled one does not bump its gas price enough to replace it.
+	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
+
+	// ErrNilsConflict is returned if a transaction spends some of the nils of a
+	// pooled one but not all of them, so it can not replace it.
+	ErrNilsConflict = errors.New("transaction spends the nils of a pooled transaction")
+)
+
+// txNils returns the nils, roots and traces spent by the inputs of the
+// transaction, the same input always gives the same keys.
+func txNils(tx *types.Transaction) (nils []c_type.Uint256) {
+	stxt := tx.Stxt()
+	if tx0 := stxt.Tx0(); tx0 != nil {
+		for _, in := range tx0.Desc_O.Ins {
+			nils = append(nils, in.Nil, in.Root)
+		}
+		for _, in := range tx0.Desc_Z.Ins {
+			nils = append(nils, in.Nil, in.Trace)
+		}
+	}
+	for _, in := range stxt.Tx1.Ins_C {
+		nils = append(nils, in.Nil)
+	}
+	for _, in := range stxt.Tx1.Ins_P {
+		nils = append(nils, in.Nil, in.Root)
+	}
+	for _, in := range stxt.Tx1.Ins_P0 {
+		nils = append(nils, in.Nil, in.Root, in.Trace)
+	}
+	return
+}
+
+// replaced returns the pooled transactions the transaction replaces. It spends
+// all their nils and pays at least PriceBump percent more gas price than each
+// of them.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) replaced(tx *types.Transaction) (types.Transactions, error) {
+	hash := tx.Hash()
+	spends := make(map[c_type.Uint256]bool)
+	for _, Nil := range txNils(tx) {
+		spends[Nil] = true
+	}
+
+	olds := types.Transactions{}
+	seen := make(map[common.Hash]bool)
+	for Nil := range spends {
+		h, ok := pool.nils[Nil]
+		if !ok || h == hash || seen[h] {
+			continue
+		}
+		seen[h] = true
+		old := pool.all.Get(h)
+		if old == nil {
+			// The spending transaction left the pool.
+			delete(pool.nils, Nil)
+			continue
+		}
+		olds = append(olds, old)
+	}
+
+	for _, old := range olds {
+		for _, Nil := range txNils(old) {
+			if !spends[Nil] {
+				return nil, ErrNilsConflict
+			}
+		}
+		threshold := new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(pool.config.PriceBump)))
+		threshold.Div(threshold, big.NewInt(100))
+		if tx.GasPrice().Cmp(threshold) < 0 {
+			return nil, ErrReplaceUnderpriced
+		}
+	}
+	return olds, nil
+}
+
+// replace drops the transactions replaced by tx and indexes its nils.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) replace(tx *types.Transaction, olds types.Transactions) {
+	for _, old := range olds {
+		pool.removeAllTx(old.Hash())
+		if pool.canAddPkrTx() {
+			pool.pkrTxOuts.delPendintTxOut(*old)
+		}
+		log.Info("Replaced pooled transaction", "hash", old.Hash().Hex(), "by", tx.Hash().Hex(), "priced", old.GasPrice(), "newpriced", tx.GasPrice())
+	}
+	hash := tx.Hash()
+	for _, Nil := range txNils(tx) {
+		pool.nils[Nil] = hash
+	}
+}
+
+// pruneNils drops the nils of the transactions that left the pool.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) pruneNils() {
+	for Nil, hash := range pool.nils {
+		if pool.all.Get(hash) == nil {
+			delete(pool.nils, Nil)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txs/stx/stx_v1"
+)
+
+func newReplaceTestPool() *TxPool {
+	pool := &TxPool{
+		config:    DefaultTxPoolConfig,
+		all:       newTxLookup(),
+		beats:     make(map[common.Hash]time.Time),
+		nils:      make(map[c_type.Uint256]common.Hash),
+		pkrTxOuts: make(map[c_type.PKr]map[c_type.Uint256]*TxOutInfo),
+		gasPrice:  big.NewInt(1),
+	}
+	pool.priced = newTxPricedList(pool.all)
+	pool.newQueue = newTxPricedList(newTxLookup())
+	pool.newPending = newTxPricedList(newTxLookup())
+	return pool
+}
+
+func nilsTx(ehash byte, gasPrice int64, nils ...byte) *types.Transaction {
+	t := &stx.T{Ehash: c_type.Uint256{ehash}}
+	for _, n := range nils {
+		t.Tx1.Ins_C = append(t.Tx1.Ins_C, stx_v1.In_C{Nil: c_type.Uint256{n}})
+	}
+	return types.NewTxWithGTx(25000, big.NewInt(gasPrice), t)
+}
+
+func TestTxPoolReplace(t *testing.T) {
+	pool := newReplaceTestPool()
+	old := nilsTx(1, 100, 1, 2)
+	if _, err := pool.enqueueTx(old.Hash(), old); err != nil {
+		t.Fatal(err)
+	}
+	pool.replace(old, nil)
+
+	if olds, err := pool.replaced(old); err != nil || len(olds) != 0 {
+		t.Fatalf("resent transaction replaces %d: %v", len(olds), err)
+	}
+	if _, err := pool.replaced(nilsTx(2, 1000, 1)); err != ErrNilsConflict {
+		t.Fatalf("partial spender: have %v, want %v", err, ErrNilsConflict)
+	}
+	if _, err := pool.replaced(nilsTx(3, 109, 1, 2)); err != ErrReplaceUnderpriced {
+		t.Fatalf("underpriced replacement: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if olds, err := pool.replaced(nilsTx(4, 500, 3)); err != nil || len(olds) != 0 {
+		t.Fatalf("unrelated transaction replaces %d: %v", len(olds), err)
+	}
+
+	tx := nilsTx(5, 110, 1, 2, 3)
+	olds, err := pool.replaced(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(olds) != 1 || olds[0].Hash() != old.Hash() {
+		t.Fatalf("replaced transactions mismatch: %v", olds)
+	}
+	if _, err := pool.enqueueTx(tx.Hash(), tx); err != nil {
+		t.Fatal(err)
+	}
+	pool.replace(tx, olds)
+	if pool.all.Get(old.Hash()) != nil {
+		t.Fatalf("replaced transaction still pooled")
+	}
+	for _, n := range []byte{1, 2, 3} {
+		if pool.nils[c_type.Uint256{n}] != tx.Hash() {
+			t.Errorf("nil %d not indexed to the replacement", n)
+		}
+	}
+
+	pool.removeAllTx(tx.Hash())
+	if _, err := pool.replaced(nilsTx(6, 1, 1)); err != nil {
+		t.Fatalf("nils of a dropped transaction conflict: %v", err)
+	}
+	pool.pruneNils()
+	if len(pool.nils) != 0 {
+		t.Fatalf("stale nils left: %d", len(pool.nils))
+	}
+}
@@ -70,10 +70,9 @@ import (
 	"github.com/sero-cash/go-sero/params"
 	"github.com/sero-cash/go-sero/rlp"
 	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/serodb"
 	"github.com/sero-cash/go-sero/zero/txs/stx"
 	ztx "github.com/sero-cash/go-sero/zero/txs/tx"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const (
@@ -175,17 +174,28 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]*RPCTransaction {
 	return content
 }
 
-// Status returns the number of pending and queued transaction in the pool.
+// Status returns the number of pending and queued transaction in the pool and
+// the counters of its journal.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue, all, total := s.b.Stats()
+	journal := s.b.JournalStats()
 	return map[string]hexutil.Uint{
-		"pending": hexutil.Uint(pending),
-		"queued":  hexutil.Uint(queue),
-		"all":     hexutil.Uint(all),
-		"total":   hexutil.Uint(total),
+		"pending":        hexutil.Uint(pending),
+		"queued":         hexutil.Uint(queue),
+		"all":            hexutil.Uint(all),
+		"total":          hexutil.Uint(total),
+		"journalEntries": hexutil.Uint(journal.Entries),
+		"journalLoaded":  hexutil.Uint(journal.Loaded),
+		"journalPruned":  hexutil.Uint(journal.Pruned),
+		"journalDropped": hexutil.Uint(journal.Dropped),
 	}
 }
 
+// Journal returns the statistics of the transaction journal of the pool.
+func (s *PublicTxPoolAPI) Journal() core.JournalStats {
+	return s.b.JournalStats()
+}
+
 // Inspect retrieves the content of the transaction pool and flattens it into an
 // easily inspectable list.
 
@@ -2103,6 +2113,97 @@ func (s *PublicTransactionPoolAPI) ReSendTransaction(ctx context.Context, txhash
 	return submitTransaction(ctx, s.b, tx, nil)
 }
 
+// CancelTransaction replaces a pooled transaction of from by one paying all its
+// inputs back to from at a higher gas price. The inputs must hold the SERO of
+// the new fee, gasPrice defaults to the price bump of the pool.
+func (s *PublicTransactionPoolAPI) CancelTransaction(ctx context.Context, from address.MixBase58Adrress, txhash common.Hash, gasPrice *hexutil.Big) (common.Hash, error) {
+	if !seroparam.IsExchange() {
+		return common.Hash{}, errors.New("not support")
+	}
+	tx := s.b.GetPoolTransaction(txhash)
+	if tx == nil {
+		return common.Hash{}, errors.New("can not find tx " + txhash.Hex() + " in local txpool!")
+	}
+	fromAccount, err := s.b.AccountManager().FindAccountByPkr(from.ToPkr())
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	roots, err := spentRoots(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	price := new(big.Int).Mul(tx.GasPrice(), big.NewInt(100+int64(s.b.TxPoolConfig().PriceBump)))
+	price.Div(price, big.NewInt(100))
+	if gasPrice != nil {
+		if gasPrice.ToInt().Cmp(price) < 0 {
+			return common.Hash{}, fmt.Errorf("gasPrice must >= %v", price)
+		}
+		price = gasPrice.ToInt()
+	}
+	fee := new(big.Int).Mul(price, new(big.Int).SetUint64(tx.Gas()))
+
+	refundPkr := from.ToPkr()
+	txParam := prepare.PreTxParam{
+		From:     fromAccount.Address.ToUint512(),
+		RefundTo: &refundPkr,
+		Fee: assets.Token{
+			utils.CurrencyToUint256("SERO"),
+			utils.U256(*fee),
+		},
+		GasPrice: price,
+		Roots:    roots,
+	}
+	_, gtx, err := exchange.CurrentExchange().GenTxWithSign(txParam)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err = s.b.CommitTx(gtx); err != nil {
+		// The inputs stay reserved for the pooled transaction.
+		var old c_type.Uint256
+		copy(old[:], txhash[:])
+		exchange.CurrentExchange().Lease(roots, "", 0, &old)
+		return common.Hash{}, err
+	}
+	hash := common.BytesToHash(gtx.Hash[:])
+	log.Info("Submitted cancel transaction", "fullhash", hash.Hex(), "cancelled", txhash.Hex())
+	return hash, nil
+}
+
+// spentRoots returns the roots of the utxos spent by the transaction.
+func spentRoots(tx *types.Transaction) (roots []c_type.Uint256, err error) {
+	byNil := func(Nil c_type.Uint256) {
+		if err != nil {
+			return
+		}
+		if root := exchange.CurrentExchange().GetRootByNil(Nil); root != nil {
+			roots = append(roots, *root)
+		} else {
+			err = fmt.Errorf("can not find the utxo of nil %v", hexutil.Encode(Nil[:]))
+		}
+	}
+	stxt := tx.Stxt()
+	if tx0 := stxt.Tx0(); tx0 != nil {
+		for _, in := range tx0.Desc_O.Ins {
+			roots = append(roots, in.Root)
+		}
+		for _, in := range tx0.Desc_Z.Ins {
+			byNil(in.Nil)
+		}
+	}
+	for _, in := range stxt.Tx1.Ins_C {
+		byNil(in.Nil)
+	}
+	for _, in := range stxt.Tx1.Ins_P {
+		roots = append(roots, in.Root)
+	}
+	for _, in := range stxt.Tx1.Ins_P0 {
+		roots = append(roots, in.Root)
+	}
+	return
+}
+
 /**
 func (s *PublicTransactionPoolAPI) CreatePkg(ctx context.Context, args SendTxArgs) (common.Hash, error) {
 	s.nonceLock.mu.Lock()
@@ -2344,30 +2445,24 @@ func NewPrivateDebugAPI(b Backend) *PrivateDebugAPI {
 
 // ChaindbProperty returns leveldb properties of the chain database.
 func (api *PrivateDebugAPI) ChaindbProperty(property string) (string, error) {
-	ldb, ok := api.b.ChainDb().(interface {
-		LDB() *leveldb.DB
-	})
+	db, ok := api.b.ChainDb().(serodb.KeyValueStore)
 	if !ok {
 		return "", fmt.Errorf("chaindbProperty does not work for memory databases")
 	}
 	if property == "" {
-		property = "leveldb.stats"
-	} else if !strings.HasPrefix(property, "leveldb.") {
-		property = "leveldb." + property
+		property = "stats"
 	}
-	return ldb.LDB().GetProperty(property)
+	return db.Stat(property)
 }
 
 func (api *PrivateDebugAPI) ChaindbCompact() error {
-	ldb, ok := api.b.ChainDb().(interface {
-		LDB() *leveldb.DB
-	})
+	db, ok := api.b.ChainDb().(serodb.KeyValueStore)
 	if !ok {
 		return fmt.Errorf("chaindbCompact does not work for memory databases")
 	}
 	for b := byte(0); b < 255; b++ {
 		log.Info("Compacting chain database", "range", fmt.Sprintf("0x%0.2X-0x%0.2X", b, b+1))
-		err := ldb.LDB().CompactRange(util.Range{Start: []byte{b}, Limit: []byte{b + 1}})
+		err := db.Compact([]byte{b}, []byte{b + 1})
 		if err != nil {
 			log.Error("Database compaction failed", "err", err)
 			return err
@@ -1,6 +1,7 @@
 package ethapi
 
 import (
+	"bytes"
 	"context"
 
 	"github.com/sero-cash/go-sero/zero/txtool/prepare"
@@ -12,6 +13,7 @@ import (
 	"github.com/sero-cash/go-sero/zero/txtool"
 	"github.com/sero-cash/go-sero/zero/utils"
 
+	"github.com/sero-cash/go-sero/core"
 	"github.com/sero-cash/go-sero/core/types"
 
 	"github.com/sero-cash/go-sero/core/rawdb"
@@ -19,6 +21,7 @@ import (
 	"github.com/sero-cash/go-sero/rpc"
 
 	"math/big"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sero-cash/go-sero/log"
@@ -58,13 +61,21 @@ func (s *PublicExchangeAPI) GetPkSynced(ctx context.Context, pk *address.PKAddre
 	numbers := exchangeInstance.GetUtxoNum(pk.ToUint512())
 
 	// Otherwise gather the block sync stats
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"currentPKBlock": currentPKBlock,
 		"confirmedBlock": seroparam.DefaultConfirmedBlock(),
 		"currentBlock":   progress.CurrentBlock,
 		"highestBlock":   progress.HighestBlock,
 		"utxoCount":      numbers,
-	}, nil
+		"backfilling":    false,
+	}
+	if backfill := exchangeInstance.GetBackfill(pk.ToUint512()); backfill != nil {
+		result["backfilling"] = true
+		result["backfillRunning"] = backfill.Running
+		result["backfillStart"] = backfill.Start
+		result["backfillTarget"] = backfill.Target
+	}
+	return result, nil
 
 }
 
@@ -431,6 +442,47 @@ func (s *PublicExchangeAPI) ClearUsedFlagForRoot(ctx context.Context, roots []c_
 	return
 }
 
+// ListReservations returns the utxos reserved by generated transactions, of pk
+// when given, with the owner that reserved them and when the lease expires.
+func (s *PublicExchangeAPI) ListReservations(ctx context.Context, pk *address.PKAddress) ([]map[string]interface{}, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	var key *c_type.Uint512
+	if pk != nil {
+		key = pk.ToUint512().NewRef()
+	}
+	return reservationFields(exchangeInstance.ListReservations(key)), nil
+}
+
+func reservationFields(list []exchange.Reservation) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, r := range list {
+		fields := map[string]interface{}{
+			"root":    r.Root,
+			"owner":   r.Owner,
+			"created": r.Created.Unix(),
+			"expiry":  r.Expiry.Unix(),
+		}
+		if r.TxHash != nil {
+			fields["txHash"] = *r.TxHash
+		}
+		result = append(result, fields)
+	}
+	return result
+}
+
+// Lease relabels the reservations of roots with owner and extends them by ttl
+// seconds, returning how many roots were reserved.
+func (s *PublicExchangeAPI) Lease(ctx context.Context, roots []c_type.Uint256, owner string, ttl uint64) (int, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return 0, errors.New("exchange mode no start")
+	}
+	return exchangeInstance.Lease(roots, owner, time.Duration(ttl)*time.Second, nil), nil
+}
+
 type Block struct {
 	BlockNumber uint64
 	BlockHash   c_type.Uint256
@@ -578,11 +630,19 @@ func (s *PublicExchangeAPI) Pk2Pkr(ctx context.Context, pk address.PKAddress, in
 	return pkrAddress, nil
 }
 
-func (s *PublicExchangeAPI) FindRoots(pk address.PKAddress, cy Smbol, amount Big) (map[string]interface{}, error) {
-	utxos, remaining := exchange.CurrentExchange().FindRoots(pk.ToUint512().NewRef(), string(cy), amount.ToInt())
+func (s *PublicExchangeAPI) FindRoots(pk address.PKAddress, cy Smbol, amount Big, selector *string) (map[string]interface{}, error) {
+	name := exchange.DefaultSelector
+	if selector != nil && *selector != "" {
+		if _, err := exchange.GetCoinSelector(*selector); err != nil {
+			return nil, err
+		}
+		name = *selector
+	}
+	utxos, remaining, used := exchange.CurrentExchange().FindRoots(pk.ToUint512().NewRef(), string(cy), amount.ToInt(), name)
 	result := map[string]interface{}{}
 	result["utxos"] = utxos
 	result["remaining"] = Big(remaining)
+	result["strategy"] = used
 	return result, nil
 }
 
@@ -601,3 +661,288 @@ func (s *PublicExchangeAPI) SetBalancePkr(ctx context.Context, pkr PKrAddress) e
 func (s *PublicExchangeAPI) IgnorePkrUtxos(ctx context.Context, pkr PKrAddress, ignore bool) (utxos []exchange.Utxo, e error) {
 	return exchange.CurrentExchange().IgnorePkrUtxos(*pkr.ToPKr(), ignore)
 }
+
+// defaultUtxoNotifyDepth is the number of confirmations after which a UTXO
+// notification stops being updated.
+const defaultUtxoNotifyDepth = uint64(12)
+
+type UtxoNotification struct {
+	Kind          string
+	PK            address.PKAddress
+	PKr           PKrAddress
+	Currency      string
+	Amount        *Big
+	Root          c_type.Uint256
+	BlockNumber   uint64
+	Confirmations uint64
+	Removed       bool
+}
+
+func confirmations(head uint64, num uint64) uint64 {
+	if head < num {
+		return 0
+	}
+	return head - num + 1
+}
+
+// utxoWatch turns the UTXO events of the watched accounts into notifications
+// and keeps the ones still below depth to update their confirmations.
+type utxoWatch struct {
+	watched map[c_type.Uint512]bool
+	depth   uint64
+	pending []*UtxoNotification
+}
+
+func newUtxoWatch(pks []address.PKAddress, depth uint64) *utxoWatch {
+	watched := map[c_type.Uint512]bool{}
+	for _, pk := range pks {
+		watched[pk.ToUint512()] = true
+	}
+	return &utxoWatch{watched: watched, depth: depth}
+}
+
+// events returns the notifications of the events posted at chain head.
+func (self *utxoWatch) events(events []exchange.UtxoEvent, head uint64) (ns []UtxoNotification) {
+	for _, ev := range events {
+		if len(self.watched) > 0 && !self.watched[ev.Pk] {
+			continue
+		}
+		n := &UtxoNotification{
+			Kind:          ev.Kind,
+			PKr:           pkrToPKrAddress(ev.Pkr),
+			Currency:      ev.Currency,
+			Amount:        (*Big)(ev.Amount),
+			Root:          ev.Root,
+			BlockNumber:   ev.Num,
+			Confirmations: confirmations(head, ev.Num),
+			Removed:       ev.Removed,
+		}
+		copy(n.PK[:], ev.Pk[:])
+		ns = append(ns, *n)
+		if n.Removed {
+			remain := self.pending[:0]
+			for _, p := range self.pending {
+				if p.Root != n.Root {
+					remain = append(remain, p)
+				}
+			}
+			self.pending = remain
+			continue
+		}
+		if n.Confirmations < self.depth {
+			self.pending = append(self.pending, n)
+		}
+	}
+	return
+}
+
+// head returns the pending notifications updated to the new chain head.
+func (self *utxoWatch) head(head uint64) (ns []UtxoNotification) {
+	remain := self.pending[:0]
+	for _, n := range self.pending {
+		n.Confirmations = confirmations(head, n.BlockNumber)
+		ns = append(ns, *n)
+		if n.Confirmations < self.depth {
+			remain = append(remain, n)
+		}
+	}
+	self.pending = remain
+	return
+}
+
+// Utxos creates a subscription that fires when a watched account receives or
+// spends a UTXO. Each notification is sent again with an updated confirmation
+// count on every new block until it reaches depth, or with Removed set when a
+// reorg drops a received UTXO. An empty pks watches every account of the
+// exchange.
+func (s *PublicExchangeAPI) Utxos(ctx context.Context, pks []address.PKAddress, depth *uint64) (*rpc.Subscription, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	maxDepth := defaultUtxoNotifyDepth
+	if depth != nil {
+		maxDepth = *depth
+	}
+	watch := newUtxoWatch(pks, maxDepth)
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		utxoEvents := make(chan []exchange.UtxoEvent, 16)
+		heads := make(chan core.ChainHeadEvent, 16)
+		utxoSub := exchangeInstance.SubscribeUtxoEvent(utxoEvents)
+		headSub := s.b.SubscribeChainHeadEvent(heads)
+		defer utxoSub.Unsubscribe()
+		defer headSub.Unsubscribe()
+
+		for {
+			var ns []UtxoNotification
+			select {
+			case events := <-utxoEvents:
+				ns = watch.events(events, s.b.CurrentBlock().NumberU64())
+			case h := <-heads:
+				ns = watch.head(h.Block.NumberU64())
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+			for _, n := range ns {
+				notifier.Notify(rpcSub.ID, n)
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+type BatchReceptionArgs struct {
+	Addr     MixAdrress
+	Currency Smbol
+	Value    *Big
+	Memo     string
+}
+
+type GenBatchTxArgs struct {
+	From       address.PKAddress
+	RefundTo   *PKrAddress
+	Receptions []BatchReceptionArgs
+	Gas        uint64
+	GasPrice   *Big
+	Selector   string
+}
+
+func (args GenBatchTxArgs) check() error {
+	if len(args.Receptions) == 0 {
+		return errors.New("have no receptions")
+	}
+	if args.RefundTo != nil {
+		if !superzk.IsPKrValid(args.RefundTo.ToPKr()) {
+			return errors.New("RefundTo is not a valid pkr")
+		}
+	}
+	if args.Selector != "" {
+		if _, err := exchange.GetCoinSelector(args.Selector); err != nil {
+			return err
+		}
+	}
+	for _, rec := range args.Receptions {
+		if _, err := validAddress(rec.Addr); err != nil {
+			return err
+		}
+		if rec.Currency.IsEmpty() {
+			return errors.Errorf("%v reception currency is nil", hexutil.Encode(rec.Addr[:]))
+		}
+		if rec.Value == nil || rec.Value.ToInt().Sign() <= 0 {
+			return errors.Errorf("%v reception value must > 0", hexutil.Encode(rec.Addr[:]))
+		}
+	}
+	return nil
+}
+
+func (args GenBatchTxArgs) toBatchParam() *exchange.BatchTxParam {
+	param := exchange.BatchTxParam{
+		From:     args.From.ToUint512(),
+		Gas:      args.Gas,
+		Selector: args.Selector,
+	}
+	if args.RefundTo != nil {
+		param.RefundTo = args.RefundTo.ToPKr()
+	}
+	if args.GasPrice != nil {
+		param.GasPrice = args.GasPrice.ToInt()
+	}
+	for _, rec := range args.Receptions {
+		param.Receptions = append(param.Receptions, exchange.BatchReception{
+			Addr:     MixAdrressToPkr(rec.Addr),
+			Currency: string(rec.Currency),
+			Value:    rec.Value.ToInt(),
+			Memo:     stringToUint512(rec.Memo),
+		})
+	}
+	return &param
+}
+
+type BatchTxFee struct {
+	Currency string
+	Gas      hexutil.Uint64
+	GasPrice *Big
+	Value    *Big
+}
+
+type BatchTxResult struct {
+	Tx         *txtool.GTx
+	Receptions []int
+	Ins        int
+	Outs       int
+	Fee        BatchTxFee
+}
+
+// GenBatchTx signs the transactions paying all receptions. The utxos they
+// spend stay reserved until the transactions are committed or cleared.
+func (s *PublicExchangeAPI) GenBatchTx(ctx context.Context, args GenBatchTxArgs) (map[string]interface{}, error) {
+	if err := args.check(); err != nil {
+		return nil, err
+	}
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	txs, err := exchangeInstance.GenBatchTx(args.toBatchParam())
+	if err != nil {
+		return nil, err
+	}
+
+	results := []BatchTxResult{}
+	totalFee := new(big.Int)
+	for _, tx := range txs {
+		results = append(results, BatchTxResult{
+			Tx:         tx.Tx,
+			Receptions: tx.Receptions,
+			Ins:        tx.Ins,
+			Outs:       tx.Outs,
+			Fee: BatchTxFee{
+				Currency: tx.Fee.Currency,
+				Gas:      hexutil.Uint64(tx.Fee.Gas),
+				GasPrice: (*Big)(tx.Fee.GasPrice),
+				Value:    (*Big)(tx.Fee.Value),
+			},
+		})
+		totalFee.Add(totalFee, tx.Fee.Value)
+	}
+	return map[string]interface{}{
+		"txs":      results,
+		"totalFee": (*Big)(totalFee),
+	}, nil
+}
+
+// ExportHistory returns the statement of pk for the blocks [begin, end) with a
+// running balance per currency. The "csv" format returns the file content,
+// otherwise the rows are returned as JSON.
+func (s *PublicExchangeAPI) ExportHistory(ctx context.Context, pk address.PKAddress, begin, end uint64, format *string) (interface{}, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	rows, err := exchangeInstance.ExportHistory(pk.ToUint512(), begin, end)
+	if err != nil {
+		return nil, err
+	}
+	if format != nil && *format == "csv" {
+		var buf bytes.Buffer
+		if err := exchange.WriteHistory(&buf, rows, "csv"); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	}
+	if rows == nil {
+		rows = []exchange.HistoryRow{}
+	}
+	return rows, nil
+}
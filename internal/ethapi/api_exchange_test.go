@@ -0,0 +1,104 @@
+package ethapi
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/zero/wallet/exchange"
+)
+
+func checkUtxoNotifications(t *testing.T, have []UtxoNotification, want ...UtxoNotification) {
+	if len(have) != len(want) {
+		t.Fatalf("notifications mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i, n := range have {
+		w := want[i]
+		if n.Kind != w.Kind || n.Root != w.Root || n.BlockNumber != w.BlockNumber || n.Confirmations != w.Confirmations || n.Removed != w.Removed {
+			t.Errorf("notification %d mismatch: have %s/%d/%d/%v, want %s/%d/%d/%v", i, n.Kind, n.BlockNumber, n.Confirmations, n.Removed, w.Kind, w.BlockNumber, w.Confirmations, w.Removed)
+		}
+	}
+}
+
+func TestUtxoWatch(t *testing.T) {
+	var pk address.PKAddress
+	pk[0] = 1
+	watch := newUtxoWatch([]address.PKAddress{pk}, 3)
+	event := func(kind string, pk c_type.Uint512, root byte, num uint64, removed bool) exchange.UtxoEvent {
+		return exchange.UtxoEvent{Kind: kind, Pk: pk, Currency: "SERO", Amount: big.NewInt(100), Root: c_type.Uint256{root}, Num: num, Removed: removed}
+	}
+	note := func(kind string, root byte, num uint64, confirmations uint64, removed bool) UtxoNotification {
+		return UtxoNotification{Kind: kind, Root: c_type.Uint256{root}, BlockNumber: num, Confirmations: confirmations, Removed: removed}
+	}
+
+	// Only the watched accounts are notified
+	ns := watch.events([]exchange.UtxoEvent{
+		event(exchange.UtxoReceived, pk.ToUint512(), 1, 10, false),
+		event(exchange.UtxoReceived, c_type.Uint512{2}, 2, 10, false),
+		event(exchange.UtxoReceived, pk.ToUint512(), 3, 10, false),
+	}, 10)
+	checkUtxoNotifications(t, ns, note(exchange.UtxoReceived, 1, 10, 1, false), note(exchange.UtxoReceived, 3, 10, 1, false))
+	if ns[0].PK != pk || ns[0].Currency != "SERO" || (*big.Int)(ns[0].Amount).Int64() != 100 {
+		t.Fatalf("notification fields mismatch: %v", ns[0])
+	}
+
+	// Each new head updates the confirmations
+	checkUtxoNotifications(t, watch.head(11), note(exchange.UtxoReceived, 1, 10, 2, false), note(exchange.UtxoReceived, 3, 10, 2, false))
+
+	// A spend is notified and followed as well
+	ns = watch.events([]exchange.UtxoEvent{event(exchange.UtxoSpent, pk.ToUint512(), 1, 11, false)}, 11)
+	checkUtxoNotifications(t, ns, note(exchange.UtxoSpent, 1, 11, 1, false))
+
+	// A removed utxo is notified once and no more updated
+	ns = watch.events([]exchange.UtxoEvent{event(exchange.UtxoReceived, pk.ToUint512(), 3, 10, true)}, 11)
+	checkUtxoNotifications(t, ns, note(exchange.UtxoReceived, 3, 10, 2, true))
+
+	// The notifications stop once they reach the depth
+	checkUtxoNotifications(t, watch.head(12), note(exchange.UtxoReceived, 1, 10, 3, false), note(exchange.UtxoSpent, 1, 11, 2, false))
+	checkUtxoNotifications(t, watch.head(13), note(exchange.UtxoSpent, 1, 11, 3, false))
+	if ns := watch.head(14); len(ns) != 0 {
+		t.Fatalf("notifications past the depth: %v", ns)
+	}
+}
+
+func TestReservationFields(t *testing.T) {
+	if fields := reservationFields(nil); fields == nil || len(fields) != 0 {
+		t.Fatalf("no reservations must be an empty list: %v", fields)
+	}
+
+	created := time.Unix(1000, 0)
+	hash := c_type.Uint256{9}
+	fields := reservationFields([]exchange.Reservation{
+		{Root: c_type.Uint256{1}, Owner: "genTx", Created: created, Expiry: created.Add(10 * time.Minute)},
+		{Root: c_type.Uint256{2}, Owner: "payout", TxHash: &hash, Created: created, Expiry: created.Add(time.Hour)},
+	})
+	data, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("reservations mismatch: %s", data)
+	}
+	if _, ok := decoded[0]["txHash"]; ok {
+		t.Errorf("unbound reservation has a txHash: %v", decoded[0])
+	}
+	if decoded[0]["owner"] != "genTx" || decoded[0]["created"] != float64(1000) || decoded[0]["expiry"] != float64(1600) {
+		t.Errorf("first reservation mismatch: %v", decoded[0])
+	}
+	if decoded[1]["owner"] != "payout" || decoded[1]["expiry"] != float64(4600) {
+		t.Errorf("second reservation mismatch: %v", decoded[1])
+	}
+	for key, want := range map[string]c_type.Uint256{"root": {2}, "txHash": hash} {
+		have, _ := json.Marshal(decoded[1][key])
+		if data, _ := json.Marshal(want); string(have) != string(data) {
+			t.Errorf("second reservation %s mismatch: have %s, want %s", key, have, data)
+		}
+	}
+}
@@ -2,9 +2,13 @@ package ethapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/rpc"
 	"github.com/sero-cash/go-sero/zero/wallet/light"
 )
 
@@ -50,3 +54,186 @@ func (plna PublicLightNodeApi) CheckNil(Nils []c_type.Uint256) (nilResps []light
 
 	return plna.b.CheckNil(Nils)
 }
+
+// Backfill schedules the indexing of the blocks [start, end] and returns the
+// ranges waiting to be indexed.
+func (plna PublicLightNodeApi) Backfill(start uint64, end uint64) (light.Ranges, error) {
+	return plna.b.LightBackfill(start, end)
+}
+
+// GetCoverage returns the blocks the outs are served for.
+func (plna PublicLightNodeApi) GetCoverage() (light.Coverage, error) {
+	return plna.b.LightCoverage()
+}
+
+const (
+	LightOutsConfirmed = "confirmed"
+	LightOutsPending   = "pending"
+	LightOutsSpent     = "spent"
+)
+
+type LightOutsNotification struct {
+	Kind      string
+	BlockOuts []light.BlockOut `json:",omitempty"`
+	Nils      []light.NilValue `json:",omitempty"`
+}
+
+type lightOutKey struct {
+	txHash c_type.Uint256
+	root   c_type.Uint256
+	num    uint64
+}
+
+// lightOutsWatch turns the outs of the watched pkrs and the spending of the
+// watched nils into notifications. The roots of the notified outs are watched
+// as nils too.
+type lightOutsWatch struct {
+	pkrs    []c_type.PKr
+	watched map[c_type.PKr]bool
+	nils    map[c_type.Uint256]bool
+	sent    map[lightOutKey]bool
+}
+
+func newLightOutsWatch(pkrs []c_type.PKr, nils []c_type.Uint256) *lightOutsWatch {
+	watch := &lightOutsWatch{
+		pkrs:    pkrs,
+		watched: map[c_type.PKr]bool{},
+		nils:    map[c_type.Uint256]bool{},
+		sent:    map[lightOutKey]bool{},
+	}
+	for _, pkr := range pkrs {
+		watch.watched[pkr] = true
+	}
+	for _, n := range nils {
+		watch.nils[n] = true
+	}
+	return watch
+}
+
+// pending returns the notification of the pending outs not notified yet.
+func (self *lightOutsWatch) pending(br light.BlockOutResp) *LightOutsNotification {
+	current := map[lightOutKey]bool{}
+	var blockOuts []light.BlockOut
+	for _, blockOut := range br.BlockOuts {
+		var data []light.BlockData
+		for _, d := range blockOut.Data {
+			key := lightOutKey{d.TxInfo.TxHash, d.Out.Root, blockOut.Num}
+			current[key] = true
+			if !self.sent[key] {
+				data = append(data, d)
+				self.nils[d.Out.Root] = true
+			}
+		}
+		if len(data) > 0 {
+			blockOuts = append(blockOuts, light.BlockOut{Num: blockOut.Num, Data: data})
+		}
+	}
+	self.sent = current
+	if len(blockOuts) == 0 {
+		return nil
+	}
+	return &LightOutsNotification{Kind: LightOutsPending, BlockOuts: blockOuts}
+}
+
+// indexed returns the notifications of the confirmed outs and the spent nils
+// of ev.
+func (self *lightOutsWatch) indexed(ev light.OutsEvent) (ns []LightOutsNotification) {
+	var blockOuts []light.BlockOut
+	for pkr, outs := range ev.Outs {
+		if !self.watched[pkr] {
+			continue
+		}
+		for _, blockOut := range outs {
+			for _, d := range blockOut.Data {
+				self.nils[d.Out.Root] = true
+			}
+		}
+		blockOuts = append(blockOuts, outs...)
+	}
+	if len(blockOuts) > 0 {
+		sort.Slice(blockOuts, func(i, j int) bool { return blockOuts[i].Num < blockOuts[j].Num })
+		ns = append(ns, LightOutsNotification{Kind: LightOutsConfirmed, BlockOuts: blockOuts})
+	}
+	var spent []light.NilValue
+	for n, value := range ev.Nils {
+		if self.nils[n] {
+			spent = append(spent, value)
+			delete(self.nils, n)
+		}
+	}
+	if len(spent) > 0 {
+		sort.Slice(spent, func(i, j int) bool { return spent[i].Num < spent[j].Num })
+		ns = append(ns, LightOutsNotification{Kind: LightOutsSpent, Nils: spent})
+	}
+	return
+}
+
+// Outs creates a subscription that pushes the outs of pkrs as the light node
+// indexes them and as they enter the tx pool, and the spending of nils as
+// CheckNil reports it. The roots of the pushed outs are watched as nils too.
+func (plna PublicLightNodeApi) Outs(ctx context.Context, addresses []*MixAdrress, nils []c_type.Uint256) (*rpc.Subscription, error) {
+	lightNode := light.Current_light
+	if lightNode == nil {
+		return nil, errors.New("not start light")
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	pkrs := []c_type.PKr{}
+	for _, address := range addresses {
+		addr := *address
+		if len(addr) != 96 {
+			return nil, fmt.Errorf("address is invalid")
+		}
+		var pkr c_type.PKr
+		copy(pkr[:], addr[:])
+		pkrs = append(pkrs, pkr)
+	}
+	watch := newLightOutsWatch(pkrs, nils)
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		outsCh := make(chan light.OutsEvent, 16)
+		txsCh := make(chan core.NewTxsEvent, 16)
+		heads := make(chan core.ChainHeadEvent, 16)
+		outsSub := lightNode.SubscribeOutsEvent(outsCh)
+		txsSub := plna.b.SubscribeNewTxsEvent(txsCh)
+		headSub := plna.b.SubscribeChainHeadEvent(heads)
+		defer outsSub.Unsubscribe()
+		defer txsSub.Unsubscribe()
+		defer headSub.Unsubscribe()
+
+		pushPending := func() {
+			br, err := lightNode.GetPendingOuts(watch.pkrs)
+			if err != nil {
+				return
+			}
+			if n := watch.pending(br); n != nil {
+				notifier.Notify(rpcSub.ID, *n)
+			}
+		}
+		pushPending()
+
+		for {
+			select {
+			case ev := <-outsCh:
+				for _, n := range watch.indexed(ev) {
+					notifier.Notify(rpcSub.ID, n)
+				}
+			case <-txsCh:
+				pushPending()
+			case <-heads:
+				pushPending()
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
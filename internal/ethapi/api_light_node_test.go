@@ -0,0 +1,90 @@
+package ethapi
+
+import (
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/wallet/light"
+)
+
+func newLightBlockOut(num uint64, roots ...byte) light.BlockOut {
+	out := light.BlockOut{Num: num}
+	for _, root := range roots {
+		out.Data = append(out.Data, light.BlockData{
+			TxInfo: light.TxInfo{TxHash: c_type.Uint256{root, 1}},
+			Out:    txtool.Out{Root: c_type.Uint256{root}},
+		})
+	}
+	return out
+}
+
+func lightOutRoots(ns LightOutsNotification) (roots []byte) {
+	for _, blockOut := range ns.BlockOuts {
+		for _, d := range blockOut.Data {
+			roots = append(roots, d.Out.Root[0])
+		}
+	}
+	return
+}
+
+func TestLightOutsWatch(t *testing.T) {
+	pkr, other := c_type.PKr{1}, c_type.PKr{2}
+	watched := c_type.Uint256{9, 9}
+	watch := newLightOutsWatch([]c_type.PKr{pkr}, []c_type.Uint256{watched})
+
+	// The pending outs are pushed once
+	pending := light.BlockOutResp{BlockOuts: []light.BlockOut{newLightBlockOut(0, 1, 2)}}
+	n := watch.pending(pending)
+	if n == nil || n.Kind != LightOutsPending || string(lightOutRoots(*n)) != string([]byte{1, 2}) {
+		t.Fatalf("pending notification mismatch: %v", n)
+	}
+	if n := watch.pending(pending); n != nil {
+		t.Fatalf("pending outs pushed twice: %v", n)
+	}
+	pending.BlockOuts = append(pending.BlockOuts, newLightBlockOut(0, 3))
+	if n := watch.pending(pending); n == nil || string(lightOutRoots(*n)) != string([]byte{3}) {
+		t.Fatalf("new pending out mismatch: %v", n)
+	}
+
+	// The confirmed outs of the watched pkr are pushed by block
+	ev := light.OutsEvent{
+		Outs: map[c_type.PKr][]light.BlockOut{
+			pkr:   {newLightBlockOut(12, 4), newLightBlockOut(10, 1)},
+			other: {newLightBlockOut(11, 5)},
+		},
+		Nils: map[c_type.Uint256]light.NilValue{},
+	}
+	ns := watch.indexed(ev)
+	if len(ns) != 1 || ns[0].Kind != LightOutsConfirmed || string(lightOutRoots(ns[0])) != string([]byte{1, 4}) {
+		t.Fatalf("confirmed notifications mismatch: %v", ns)
+	}
+	if ns[0].BlockOuts[0].Num != 10 || ns[0].BlockOuts[1].Num != 12 {
+		t.Fatalf("confirmed outs not ordered by block: %v", ns[0].BlockOuts)
+	}
+
+	// The watched nils and the roots of the pushed outs are reported spent
+	// once, the other ones not at all
+	spends := func(keys ...c_type.Uint256) light.OutsEvent {
+		ev := light.OutsEvent{Nils: map[c_type.Uint256]light.NilValue{}}
+		for i, key := range keys {
+			ev.Nils[key] = light.NilValue{Nil: key, Num: uint64(20 + i)}
+		}
+		return ev
+	}
+	ns = watch.indexed(spends(watched, c_type.Uint256{4}, c_type.Uint256{3}, c_type.Uint256{5}))
+	if len(ns) != 1 || ns[0].Kind != LightOutsSpent || len(ns[0].Nils) != 3 {
+		t.Fatalf("spent notifications mismatch: %v", ns)
+	}
+	for i, key := range []c_type.Uint256{watched, {4}, {3}} {
+		if ns[0].Nils[i].Nil != key {
+			t.Errorf("spent nil %d mismatch: have %x, want %x", i, ns[0].Nils[i].Nil[:2], key[:2])
+		}
+	}
+	if ns := watch.indexed(spends(watched, c_type.Uint256{4})); len(ns) != 0 {
+		t.Fatalf("spent nils reported twice: %v", ns)
+	}
+	if ns := watch.indexed(spends(c_type.Uint256{2})); len(ns) != 1 || ns[0].Nils[0].Nil != (c_type.Uint256{2}) {
+		t.Fatalf("pending out spend mismatch: %v", ns)
+	}
+}
@@ -16,12 +16,26 @@ func NewProofServiceApi() *ProofServiceApi {
 }
 
 func (nodeApi *ProofServiceApi) Fee() map[string]hexutil.Big {
-	fee := proofservice.Instance().Fee()
+	schedule := proofservice.Instance().FeeSchedule()
 	ret := make(map[string]hexutil.Big)
-	ret["fixedFee"] = hexutil.Big(*fee.FixedFee)
+	ret["zinFee"] = *schedule.ZinFee
+	ret["oinFee"] = *schedule.OinFee
+	ret["outFee"] = *schedule.OutFee
+	ret["fixedFee"] = *schedule.FixedFee
 	return ret
 }
 
+func (nodeApi *ProofServiceApi) FeeSchedule() *proofservice.FeeSchedule {
+	return proofservice.Instance().FeeSchedule()
+}
+
+func (nodeApi *ProofServiceApi) Quote(param *txtool.GTxParam, currency *string) (*txtool.GOut, error) {
+	if currency == nil {
+		return proofservice.Instance().Quote(param, "")
+	}
+	return proofservice.Instance().Quote(param, *currency)
+}
+
 func (nodeApi *ProofServiceApi) SubmitProofWork(tx *stx.T, param *txtool.GTxParam) error {
 	return proofservice.Instance().SubmitWork(tx, param)
 }
@@ -29,3 +43,11 @@ func (nodeApi *ProofServiceApi) SubmitProofWork(tx *stx.T, param *txtool.GTxPara
 func (nodeApi *ProofServiceApi) FindTxHash(hash common.Hash) common.Hash {
 	return proofservice.Instance().FindTxHash(hash)
 }
+
+func (nodeApi *ProofServiceApi) JobStatus(hash common.Hash) (*proofservice.JobStatus, error) {
+	return proofservice.Instance().JobStatus(hash)
+}
+
+func (nodeApi *ProofServiceApi) Workers() []proofservice.WorkerStats {
+	return proofservice.Instance().Workers()
+}
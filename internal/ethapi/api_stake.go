@@ -141,6 +141,42 @@ func (s *PublicStakeApI) EstimateShares(ctx context.Context, args BuyShareTxArg)
 	return result, nil
 }
 
+// Simulate projects the returns of buying shares for amount over horizon
+// blocks, voted by poolId or solo, using the stake state at blockNr, the
+// latest block when not given.
+func (s *PublicStakeApI) Simulate(ctx context.Context, amount hexutil.Big, poolId *common.Hash, horizon hexutil.Uint64, blockNr *rpc.BlockNumber) (map[string]interface{}, error) {
+	number := rpc.LatestBlockNumber
+	if blockNr != nil {
+		number = *blockNr
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, number)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	p, err := stake.NewStakeState(state).Simulate(amount.ToInt(), poolId, header.Number.Uint64(), uint64(horizon))
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	result["blockNumber"] = hexutil.Uint64(header.Number.Uint64())
+	result["total"] = hexutil.Uint64(p.Shares)
+	result["avPrice"] = hexutil.Big(*p.AvgPrice)
+	result["basePrice"] = hexutil.Big(*p.BasePrice)
+	result["poolSize"] = hexutil.Uint64(p.PoolSize)
+	result["fee"] = hexutil.Uint64(p.Fee)
+	result["missRate"] = p.MissRate
+	result["expectedVotes"] = p.ExpectedVotes
+	result["expectedMissed"] = p.ExpectedMissed
+	result["expectedExpired"] = p.ExpectedExpired
+	result["voteReward"] = hexutil.Big(*p.VoteReward)
+	result["expectedReward"] = hexutil.Big(*p.ExpectedReward)
+	if p.BreakEven != 0 {
+		result["breakEven"] = hexutil.Uint64(p.BreakEven)
+	}
+	return result, nil
+}
+
 func (s *PublicStakeApI) BuyShare(ctx context.Context, args BuyShareTxArg) (common.Hash, error) {
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return common.Hash{}, err
@@ -882,3 +918,106 @@ func (s *PublicStakeApI) GetShareAtNumber(ctx context.Context, shareId common.Ha
 	share = stake.GetShareByBlockNumber(s.b.ChainDb(), shareId, header.Hash(), header.Number.Uint64())
 	return
 }
+
+func newRPCLedgerEntry(entry stakeservice.LedgerEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"blockNumber": hexutil.Uint64(entry.Num),
+		"selected":    hexutil.Uint64(entry.Selected),
+		"voted":       hexutil.Uint64(entry.Voted),
+		"missed":      hexutil.Uint64(entry.Missed),
+		"expired":     hexutil.Uint64(entry.Expired),
+		"reward":      hexutil.Big(*entry.Reward),
+		"income":      hexutil.Big(*entry.Income),
+		"paid":        hexutil.Big(*entry.Paid),
+	}
+}
+
+func newRPCLedgerPage(page stakeservice.LedgerPage) map[string]interface{} {
+	entries := []map[string]interface{}{}
+	for _, entry := range page.Entries {
+		entries = append(entries, newRPCLedgerEntry(entry))
+	}
+	total := newRPCLedgerEntry(page.Total)
+	delete(total, "blockNumber")
+	return map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"count":   hexutil.Uint64(page.Count),
+	}
+}
+
+func ledgerPageArgs(offset, limit *hexutil.Uint64) (int, int) {
+	o, l := 0, 100
+	if offset != nil {
+		o = int(*offset)
+	}
+	if limit != nil {
+		l = int(*limit)
+	}
+	return o, l
+}
+
+// GetShareLedger returns the per block rewards, payments, missed votes and
+// expirations of a share for the blocks [start, end), with totals over the range.
+func (s *PublicStakeApI) GetShareLedger(ctx context.Context, shareId common.Hash, start, end hexutil.Uint64, offset, limit *hexutil.Uint64) (map[string]interface{}, error) {
+	if start >= end {
+		return nil, errors.New("start must < end")
+	}
+	o, l := ledgerPageArgs(offset, limit)
+	return newRPCLedgerPage(stakeservice.CurrentStakeService().ShareLedger(shareId, uint64(start), uint64(end), o, l)), nil
+}
+
+// GetPoolLedger returns the ledger of a stake pool for the blocks [start, end).
+func (s *PublicStakeApI) GetPoolLedger(ctx context.Context, poolId common.Hash, start, end hexutil.Uint64, offset, limit *hexutil.Uint64) (map[string]interface{}, error) {
+	if start >= end {
+		return nil, errors.New("start must < end")
+	}
+	o, l := ledgerPageArgs(offset, limit)
+	return newRPCLedgerPage(stakeservice.CurrentStakeService().PoolLedger(poolId, uint64(start), uint64(end), o, l)), nil
+}
+
+// PoolMetrics returns the vote participation, missed votes, pool and solo vote
+// ratios and fee income of a stake pool per window of the blocks [start, end),
+// the window defaulting to one day of blocks.
+func (s *PublicStakeApI) PoolMetrics(ctx context.Context, poolId common.Hash, start, end hexutil.Uint64, window *hexutil.Uint64) (map[string]interface{}, error) {
+	if start >= end {
+		return nil, errors.New("start must < end")
+	}
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, -1)
+	if err != nil {
+		return nil, err
+	}
+	pool := stake.NewStakeState(state).GetStakePool(poolId)
+	if pool == nil {
+		return nil, errors.New("stake pool not exists")
+	}
+	size := uint64(0)
+	if window != nil {
+		size = uint64(*window)
+	}
+
+	windows := []map[string]interface{}{}
+	for _, each := range stakeservice.CurrentStakeService().PoolMetrics(poolId, uint64(start), uint64(end), size) {
+		windows = append(windows, map[string]interface{}{
+			"start":         hexutil.Uint64(each.Start),
+			"end":           hexutil.Uint64(each.End),
+			"selected":      hexutil.Uint64(each.Selected),
+			"voted":         hexutil.Uint64(each.Voted),
+			"soloVoted":     hexutil.Uint64(each.SoloVoted),
+			"missed":        hexutil.Uint64(each.Missed),
+			"expired":       hexutil.Uint64(each.Expired),
+			"participation": each.Participation(),
+			"soloRatio":     each.SoloRatio(),
+			"feeIncome":     hexutil.Big(*each.Reward),
+		})
+	}
+	return map[string]interface{}{
+		"id":          poolId,
+		"shareNum":    hexutil.Uint64(pool.CurrentShareNum),
+		"wishVoteNum": hexutil.Uint64(pool.WishVoteNum),
+		"choicedNum":  hexutil.Uint64(pool.ChoicedShareNum),
+		"missedNum":   hexutil.Uint64(pool.MissedVoteNum),
+		"closed":      pool.Closed,
+		"windows":     windows,
+	}, nil
+}
@@ -77,6 +77,8 @@ type Backend interface {
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	//GetPoolNonce(ctx context.Context, addr common.Data) (uint64, error)
 	Stats() (pending int, queued int, all int, total int)
+	JournalStats() core.JournalStats
+	TxPoolConfig() core.TxPoolConfig
 	TxPoolContent() (types.Transactions, types.Transactions, types.Transactions)
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
 
@@ -104,6 +106,8 @@ type Backend interface {
 	//Light node api
 	GetOutByPKr(pkrs []c_type.PKr, start uint64, end *uint64) (br light.BlockOutResp, e error)
 	CheckNil(Nils []c_type.Uint256) (nilResps []light.NilValue, e error)
+	LightBackfill(start, end uint64) (light.Ranges, error)
+	LightCoverage() (light.Coverage, error)
 }
 
 func GetAPIs(apiBackend Backend) []rpc.API {
@@ -16,6 +16,7 @@ import (
 	"github.com/sero-cash/go-sero/zero/txs/assets"
 	"github.com/sero-cash/go-sero/zero/txtool/prepare"
 	"github.com/sero-cash/go-sero/zero/utils"
+	"github.com/sero-cash/go-sero/zero/wallet/exchange"
 )
 
 type PkgCloseArgs struct {
@@ -192,6 +193,7 @@ type GenTxArgs struct {
 	Gas        uint64
 	GasPrice   *Big
 	Roots      []c_type.Uint256
+	Selector   string
 }
 
 func (args GenTxArgs) check() error {
@@ -208,6 +210,12 @@ func (args GenTxArgs) check() error {
 		}
 	}
 
+	if args.Selector != "" {
+		if _, err := exchange.GetCoinSelector(args.Selector); err != nil {
+			return err
+		}
+	}
+
 	if args.Cmds != nil {
 		if args.Cmds.RegistPool != nil || args.Cmds.ClosePool != nil {
 			if args.RefundTo == nil {
@@ -265,8 +273,8 @@ func (args GenTxArgs) toTxParam() prepare.PreTxParam {
 		}
 		assets := assets.Asset{tkn, tkt}
 		receptions = append(receptions, prepare.Reception{
-			pkr,
-			assets,
+			Addr:  pkr,
+			Asset: assets,
 		})
 	}
 	var refundPkr *c_type.PKr
@@ -288,5 +296,6 @@ func (args GenTxArgs) toTxParam() prepare.PreTxParam {
 		},
 		gasPrice,
 		args.Roots,
+		args.Selector,
 	}
 }
@@ -140,6 +140,16 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'addCheckpoint',
+			call: 'admin_addCheckpoint',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'checkpoints',
+			call: 'admin_checkpoints',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -439,6 +449,11 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'sero_cancelTransaction',
+			params: 3
+		}),
         new web3._extend.Method({
 			name: 'getBlockTotalRewardByNumber',
 			call: 'sero_getBlockTotalRewardByNumber',
@@ -463,6 +478,12 @@ web3._extend({
 			call: 'sero_sendRawTransaction',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getPKrFilters',
+			call: 'sero_getPKrFilters',
+			params: 2,
+			inputFormatter: [web3._extend.utils.toHex, web3._extend.utils.toHex]
+		}),
         new web3._extend.Method({
 			name: 'getCommittedTx',
 			call: 'sero_getCommittedTx',
@@ -705,6 +726,10 @@ web3._extend({
 				return status;
 			}
 		}),
+		new web3._extend.Property({
+			name: 'journal',
+			getter: 'txpool_journal'
+		}),
 	]
 });
 `
@@ -985,6 +1010,16 @@ web3._extend({
 			call: 'light_checkNil',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'backfill',
+			call: 'light_backfill',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getCoverage',
+			call: 'light_getCoverage',
+			params: 0
+		}),
 	]
 });
 `
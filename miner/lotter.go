@@ -12,6 +12,7 @@ import (
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/core/state"
 	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/voter"
 	"github.com/sero-cash/go-sero/zero/stake"
 )
 
@@ -147,7 +148,7 @@ func (self *Lotter) wait() bool {
 			return false
 		}
 
-		if time.Since(startTime) > 5*time.Minute {
+		if time.Since(startTime) > voter.LotteryWait {
 			return false
 		}
 
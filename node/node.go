@@ -570,7 +570,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (serodb.Database, e
 	if n.config.DataDir == "" {
 		return serodb.NewMemDatabase(), nil
 	}
-	return serodb.NewLDBDatabase(n.config.ResolvePath(name), cache, handles)
+	return serodb.NewDatabase(n.config.ResolvePath(name), cache, handles)
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
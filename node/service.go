@@ -43,7 +43,7 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (se
 	if ctx.config.DataDir == "" {
 		return serodb.NewMemDatabase(), nil
 	}
-	db, err := serodb.NewLDBDatabase(ctx.config.ResolvePath(name), cache, handles)
+	db, err := serodb.NewDatabase(ctx.config.ResolvePath(name), cache, handles)
 	if err != nil {
 		return nil, err
 	}
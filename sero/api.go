@@ -32,6 +32,7 @@ import (
 	"github.com/sero-cash/go-sero/common/address"
 	"github.com/sero-cash/go-sero/common/hexutil"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/pkrfilter"
 	"github.com/sero-cash/go-sero/core/rawdb"
 	"github.com/sero-cash/go-sero/core/state"
 	"github.com/sero-cash/go-sero/core/types"
@@ -41,6 +42,7 @@ import (
 	"github.com/sero-cash/go-sero/params"
 	"github.com/sero-cash/go-sero/rlp"
 	"github.com/sero-cash/go-sero/trie"
+	"github.com/sero-cash/go-sero/zero/zconfig"
 )
 
 // PublicSeroAPI provides an API to access Sero full node-related
@@ -81,6 +83,13 @@ func (api *PublicSeroAPI) StopHashrate() {
 	api.e.Miner().StropHashRate()
 }
 
+// GetPKrFilters returns the compact filters over the output PKrs of count
+// blocks from start, light wallets match their PKrs locally and only fetch the
+// blocks that match.
+func (api *PublicSeroAPI) GetPKrFilters(start hexutil.Uint64, count hexutil.Uint64) []pkrfilter.BlockFilter {
+	return readPKrFilters(api.e.chainDb, uint64(start), uint64(count))
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -308,6 +317,26 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// AddCheckpoint trusts the signed check point, saves it to the check point
+// file and returns the last block the check points cover.
+func (api *PrivateAdminAPI) AddCheckpoint(cp zconfig.SignedCheckpoint) (hexutil.Uint64, error) {
+	if err := zconfig.CheckPoints.Add(&cp); err != nil {
+		return 0, err
+	}
+	max := zconfig.CheckPoints.MaxNum()
+	log.Info("Added signed check point", "num", cp.Num, "root", cp.Root)
+	log.Warn("Blocks up to the check points skip the full verification", "max", max)
+	if err := zconfig.SaveCheckpoints(); err != nil {
+		return 0, fmt.Errorf("check point %d added but not saved: %v", cp.Num, err)
+	}
+	return hexutil.Uint64(max), nil
+}
+
+// Checkpoints returns the signed check points trusted by the node.
+func (api *PrivateAdminAPI) Checkpoints() []zconfig.SignedCheckpoint {
+	return zconfig.CheckPoints.Signed()
+}
+
 func (api *PrivateAdminAPI) Close() {
 	api.eth.Stop()
 }
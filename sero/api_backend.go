@@ -209,6 +209,14 @@ func (b *SeroAPIBackend) Stats() (pending int, queued int, all int, total int) {
 	return b.sero.txPool.Stats()
 }
 
+func (b *SeroAPIBackend) JournalStats() core.JournalStats {
+	return b.sero.txPool.JournalStats()
+}
+
+func (b *SeroAPIBackend) TxPoolConfig() core.TxPoolConfig {
+	return b.sero.txPool.Config()
+}
+
 func (b *SeroAPIBackend) TxPoolContent() (types.Transactions, types.Transactions, types.Transactions) {
 	return b.sero.TxPool().Content()
 }
@@ -387,3 +395,18 @@ func (b *SeroAPIBackend) CheckNil(Nils []c_type.Uint256) (nilResps []light.NilVa
 	}
 	return b.sero.lightNode.CheckNil(Nils)
 }
+
+func (b *SeroAPIBackend) LightBackfill(start, end uint64) (light.Ranges, error) {
+	if b.sero.lightNode == nil {
+		return nil, errors.New("not start light")
+	}
+	return b.sero.lightNode.Backfill(start, end)
+}
+
+func (b *SeroAPIBackend) LightCoverage() (coverage light.Coverage, e error) {
+	if b.sero.lightNode == nil {
+		e = errors.New("not start light")
+		return
+	}
+	return b.sero.lightNode.Coverage(), nil
+}
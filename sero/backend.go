@@ -101,6 +101,8 @@ type Sero struct {
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
+	pkrFilterIndexer *core.ChainIndexer // Pkr filter indexer operating during block imports
+
 	APIBackend *SeroAPIBackend
 
 	miner    *miner.Miner
@@ -179,16 +181,23 @@ func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	sero.bloomIndexer.Start(sero.blockchain)
+	sero.pkrFilterIndexer = NewPKrFilterIndexer(chainDb)
+	sero.pkrFilterIndexer.Start(sero.blockchain)
 
-	// if config.TxPool.Journal != "" {
-	//	config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
-	// }
+	if config.TxPool.Journal != "" {
+		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
+	}
 
 	config.TxPool.StartLight = config.StartLight
 
 	sero.txPool = core.NewTxPool(config.TxPool, sero.chainConfig, sero.blockchain)
 
-	sero.voter = voter.NewVoter(sero.chainConfig, sero.blockchain, sero)
+	if config.Voter.Journal != "" {
+		config.Voter.Journal = ctx.ResolvePath(config.Voter.Journal)
+	}
+	if sero.voter, err = voter.NewVoter(config.Voter, sero.chainConfig, sero.blockchain, sero); err != nil {
+		return nil, err
+	}
 
 	sero.miner = miner.New(sero, sero.chainConfig, sero.EventMux(), sero.voter, sero.engine)
 
@@ -262,7 +271,7 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (serodb.Dat
 	if err != nil {
 		return nil, err
 	}
-	if db, ok := db.(*serodb.LDBDatabase); ok {
+	if db, ok := db.(serodb.KeyValueStore); ok {
 		db.Meter("sero/db/chaindata/")
 	}
 	return db, nil
@@ -474,6 +483,7 @@ func (s *Sero) Start(srvr *p2p.Server) error {
 // Sero protocol.
 func (s *Sero) Stop() error {
 	s.bloomIndexer.Close()
+	s.pkrFilterIndexer.Close()
 	s.voter.Close()
 	s.miner.Close()
 	s.blockchain.Stop()
@@ -27,11 +27,11 @@ import (
 var committedTxPrefix = []byte("COMMITEDTX")
 
 type Backup struct {
-	db *serodb.LDBDatabase
+	db serodb.KeyValueStore
 }
 
 func NewBackup(dbpath string) *Backup {
-	db, err := serodb.NewLDBDatabase(dbpath, 1024, 1024)
+	db, err := serodb.NewDatabase(dbpath, 1024, 1024)
 	if err != nil {
 		panic(err)
 	}
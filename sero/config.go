@@ -32,6 +32,7 @@ import (
 	"github.com/sero-cash/go-sero/params"
 	"github.com/sero-cash/go-sero/sero/downloader"
 	"github.com/sero-cash/go-sero/sero/gasprice"
+	"github.com/sero-cash/go-sero/voter"
 )
 
 // DefaultConfig contains default settings for use on the Sero main net.
@@ -52,6 +53,7 @@ var DefaultConfig = Config{
 	GasPrice:      big.NewInt(params.Gta),
 
 	TxPool: core.DefaultTxPoolConfig,
+	Voter:  voter.DefaultConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
@@ -114,6 +116,9 @@ type Config struct {
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
+	// Voter options
+	Voter voter.Config
+
 	Proof *proofservice.Config
 
 	// Gas Price Oracle options
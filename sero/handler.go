@@ -31,6 +31,7 @@ import (
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/consensus"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/pkrfilter"
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/log"
@@ -72,6 +73,7 @@ type ProtocolManager struct {
 	miner         sero_miner
 	voter         shareVoter
 	blockchain    *core.BlockChain
+	chaindb       serodb.Database
 	chainconfig   *params.ChainConfig
 	maxPeers      int
 
@@ -90,6 +92,8 @@ type ProtocolManager struct {
 	lotteryCh     chan core.NewLotteryEvent
 	lotterySub    event.Subscription
 
+	pkrFiltersFeed event.Feed
+
 	// channels for fetcher, syncer, txsyncLoop
 	newPeerCh   chan *peer
 	txsyncCh    chan *txsync
@@ -113,6 +117,7 @@ func NewProtocolManager(config *params.ChainConfig, closeAcceptTx bool, mode dow
 		miner:         miner,
 		voter:         voter,
 		blockchain:    blockchain,
+		chaindb:       chaindb,
 		chainconfig:   config,
 		peers:         newPeerSet(),
 		newPeerCh:     make(chan *peer),
@@ -578,6 +583,20 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			log.Debug("Failed to deliver receipts", "err", err)
 		}
 
+	case p.version >= sero64 && msg.Code == GetPKrFiltersMsg:
+		var query getPKrFiltersData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		return p.SendPKrFilters(readPKrFilters(pm.chaindb, query.Start, query.Count))
+
+	case p.version >= sero64 && msg.Code == PKrFiltersMsg:
+		var filters []pkrfilter.BlockFilter
+		if err := msg.Decode(&filters); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		pm.pkrFiltersFeed.Send(PKrFiltersEvent{Peer: p.id, Filters: filters})
+
 	case msg.Code == NewBlockHashesMsg:
 		var announces newBlockHashesData
 		if err := msg.Decode(&announces); err != nil {
@@ -839,3 +858,19 @@ func (pm *ProtocolManager) NodeInfo() *NodeInfo {
 		Head:       currentBlock.Hash(),
 	}
 }
+
+// SubscribePKrFilters registers a subscription of the pkr filters delivered by
+// the peers.
+func (pm *ProtocolManager) SubscribePKrFilters(ch chan<- PKrFiltersEvent) event.Subscription {
+	return pm.pkrFiltersFeed.Subscribe(ch)
+}
+
+// RequestPKrFilters asks a peer running sero/64 for the pkr filters of count
+// blocks from start, they are delivered to the SubscribePKrFilters channels.
+func (pm *ProtocolManager) RequestPKrFilters(start, count uint64) error {
+	peers := pm.peers.PeersWithVersion(sero64)
+	if len(peers) == 0 {
+		return errors.New("no peer serves pkr filters")
+	}
+	return peers[0].RequestPKrFilters(start, count)
+}
@@ -25,6 +25,7 @@ import (
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/pkrfilter"
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/p2p"
 	"github.com/sero-cash/go-sero/rlp"
@@ -379,6 +380,17 @@ func (p *peer) SendReceiptsRLP(receipts []rlp.RawValue) error {
 	return p2p.Send(p.rw, ReceiptsMsg, receipts)
 }
 
+// SendPKrFilters sends a batch of pkr filters to the remote peer.
+func (p *peer) SendPKrFilters(filters []pkrfilter.BlockFilter) error {
+	return p2p.Send(p.rw, PKrFiltersMsg, filters)
+}
+
+// RequestPKrFilters fetches the pkr filters of count blocks from start.
+func (p *peer) RequestPKrFilters(start, count uint64) error {
+	p.Log().Debug("Fetching batch of pkr filters", "start", start, "count", count)
+	return p2p.Send(p.rw, GetPKrFiltersMsg, &getPKrFiltersData{Start: start, Count: count})
+}
+
 // RequestOneHeader is a wrapper around the header query functions to fetch a
 // single header. It is used solely by the fetcher.
 func (p *peer) RequestOneHeader(hash common.Hash) error {
@@ -641,6 +653,20 @@ func (ps *peerSet) PeersWithoutLottery(hash common.Hash) []*peer {
 	return list
 }
 
+// PeersWithVersion retrieves a list of peers running at least the protocol version.
+func (ps *peerSet) PeersWithVersion(version int) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.version >= version {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
 // BestPeer retrieves the known peer with the currently highest total difficulty.
 func (ps *peerSet) BestPeer() *peer {
 	ps.lock.RLock()
@@ -0,0 +1,94 @@
+package sero
+
+import (
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/pkrfilter"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/localdb"
+)
+
+const (
+	// pkrFilterSection is the number of blocks the pkr filters are indexed by.
+	pkrFilterSection = 16
+
+	// pkrFilterConfirms is the number of confirmation blocks before the pkr
+	// filters of a section are indexed.
+	pkrFilterConfirms = 12
+
+	// maxPKrFilters is the maximum number of pkr filters served in one request.
+	maxPKrFilters = 2000
+)
+
+// PKrFilterIndexer implements a core.ChainIndexer, building the compact filters
+// over the PKrs of the outputs of every canonical block.
+type PKrFilterIndexer struct {
+	db    serodb.Database
+	batch serodb.Batch
+}
+
+// NewPKrFilterIndexer returns a chain indexer that generates the pkr filters of
+// the canonical chain.
+func NewPKrFilterIndexer(db serodb.Database) *core.ChainIndexer {
+	backend := &PKrFilterIndexer{
+		db: db,
+	}
+	table := serodb.NewTable(db, string(rawdb.PKrFilterIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, pkrFilterSection, pkrFilterConfirms, bloomThrottling, "pkrfilter")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new pkr filter section.
+func (b *PKrFilterIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	b.batch = b.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, building the filter of the outputs
+// of the block.
+func (b *PKrFilterIndexer) Process(header *types.Header) {
+	hash := header.Hash()
+	number := header.Number.Uint64()
+	block := localdb.GetBlock(b.db, number, hash.HashToUint256())
+	if block == nil {
+		log.Warn("Pkr filter missing outputs", "number", number, "hash", hash)
+		return
+	}
+	var items [][]byte
+	for i := range block.Roots {
+		if out := localdb.GetRoot(b.db, &block.Roots[i]); out != nil {
+			items = append(items, out.OS.ToPKr()[:])
+		}
+	}
+	rawdb.WritePKrFilter(b.batch, number, hash, pkrfilter.New(pkrfilter.Key(hash), items).Bytes())
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the filters of the
+// section.
+func (b *PKrFilterIndexer) Commit() error {
+	return b.batch.Write()
+}
+
+// readPKrFilters returns the filters of the canonical blocks from start on, up
+// to the first block not indexed yet.
+func readPKrFilters(db serodb.Database, start uint64, count uint64) (filters []pkrfilter.BlockFilter) {
+	if count > maxPKrFilters {
+		count = maxPKrFilters
+	}
+	for number := start; number < start+count; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			break
+		}
+		filter, err := rawdb.ReadPKrFilter(db, number, hash)
+		if err != nil {
+			break
+		}
+		filters = append(filters, pkrfilter.BlockFilter{Number: hexutil.Uint64(number), Hash: hash, Filter: filter})
+	}
+	return
+}
@@ -0,0 +1,115 @@
+package sero
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/pkrfilter"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/p2p/discover"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// writeTestPKrFilters indexes the filters of the canonical blocks [0, count),
+// the items of every filter are its block number.
+func writeTestPKrFilters(db serodb.Database, count uint64) {
+	for number := uint64(0); number < count; number++ {
+		hash := common.Hash{byte(number), 1}
+		rawdb.WriteCanonicalHash(db, hash, number)
+		filter := pkrfilter.New(pkrfilter.Key(hash), [][]byte{{byte(number)}})
+		rawdb.WritePKrFilter(db, number, hash, filter.Bytes())
+	}
+}
+
+func TestReadPKrFilters(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	writeTestPKrFilters(db, 4)
+	// A canonical block not indexed yet ends the filters
+	rawdb.WriteCanonicalHash(db, common.Hash{4, 1}, 4)
+
+	filters := readPKrFilters(db, 1, 10)
+	if len(filters) != 3 {
+		t.Fatalf("filters: have %d, want 3", len(filters))
+	}
+	for i, f := range filters {
+		number := uint64(i + 1)
+		if uint64(f.Number) != number || f.Hash != (common.Hash{byte(number), 1}) {
+			t.Fatalf("filter %d mismatch: have %d %x", i, f.Number, f.Hash)
+		}
+		filter, err := pkrfilter.FromBytes(f.Filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !filter.Match(pkrfilter.Key(f.Hash), []byte{byte(number)}) {
+			t.Errorf("filter of block %d does not match its item", number)
+		}
+	}
+	if filters := readPKrFilters(db, 2, 1); len(filters) != 1 || filters[0].Number != 2 {
+		t.Fatalf("counted filters mismatch: %v", filters)
+	}
+	if filters := readPKrFilters(db, 5, 10); len(filters) != 0 {
+		t.Fatalf("filters past the head: %v", filters)
+	}
+}
+
+func TestPKrFiltersMsg(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	writeTestPKrFilters(db, 3)
+	pm := &ProtocolManager{chaindb: db}
+
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+	p := newPeer(sero64, p2p.NewPeer(discover.NodeID{1}, "test", nil), app)
+
+	// A filter query is answered with the indexed filters
+	errc := make(chan error, 1)
+	go func() { errc <- pm.handleMsg(p) }()
+	if err := p2p.Send(net, GetPKrFiltersMsg, &getPKrFiltersData{Start: 1, Count: 5}); err != nil {
+		t.Fatal(err)
+	}
+	want := readPKrFilters(db, 1, 5)
+	if err := p2p.ExpectMsg(net, PKrFiltersMsg, want); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	// The delivered filters are posted to the subscribers
+	ch := make(chan PKrFiltersEvent, 1)
+	sub := pm.SubscribePKrFilters(ch)
+	defer sub.Unsubscribe()
+	go func() { errc <- pm.handleMsg(p) }()
+	if err := p2p.Send(net, PKrFiltersMsg, want); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-ch:
+		if ev.Peer != p.id || len(ev.Filters) != len(want) {
+			t.Fatalf("filters event mismatch: %v", ev)
+		}
+		for i := range want {
+			if ev.Filters[i].Hash != want[i].Hash || !bytes.Equal(ev.Filters[i].Filter, want[i].Filter) {
+				t.Errorf("filter %d mismatch", i)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("filters event not posted")
+	}
+
+	// A query is sent as the request of the peer
+	go func() { errc <- p.RequestPKrFilters(7, 9) }()
+	if err := p2p.ExpectMsg(net, GetPKrFiltersMsg, &getPKrFiltersData{Start: 7, Count: 9}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
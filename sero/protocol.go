@@ -23,6 +23,7 @@ import (
 
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/pkrfilter"
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/rlp"
@@ -32,16 +33,17 @@ import (
 const (
 	sero62 = 62
 	sero63 = 63
+	sero64 = 64
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "sero"
 
 // ProtocolVersions are the upported versions of the sero protocol (first is primary).
-var ProtocolVersions = []uint{sero63, sero62}
+var ProtocolVersions = []uint{sero64, sero63, sero62}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{24, 8}
+var ProtocolLengths = []uint64{26, 24, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -65,6 +67,10 @@ const (
 
 	NewVoteMsg    = 0x16
 	NewLotteryMsg = 0x17
+
+	// Protocol messages belonging to sero/64
+	GetPKrFiltersMsg = 0x18
+	PKrFiltersMsg    = 0x19
 )
 
 type errCode int
@@ -181,6 +187,18 @@ func (hn *hashOrNumber) DecodeRLP(s *rlp.Stream) error {
 	return err
 }
 
+// getPKrFiltersData represents a pkr filter query.
+type getPKrFiltersData struct {
+	Start uint64 // Number of the first block
+	Count uint64 // Maximum number of filters to retrieve
+}
+
+// PKrFiltersEvent is posted when a peer delivers pkr filters.
+type PKrFiltersEvent struct {
+	Peer    string
+	Filters []pkrfilter.BlockFilter
+}
+
 // newBlockData is the network packet for the block propagation message.
 type newBlockData struct {
 	Block *types.Block
@@ -0,0 +1,85 @@
+package seroclient
+
+import (
+	"context"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/pkrfilter"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+// pkrFilterPage is the number of pkr filters requested at once.
+const pkrFilterPage = 1000
+
+// PKrFilters returns the compact filters over the output PKrs of count blocks
+// from start. Fewer filters are returned when the node has not indexed them yet.
+func (ec *Client) PKrFilters(ctx context.Context, start, count uint64) ([]pkrfilter.BlockFilter, error) {
+	var filters []pkrfilter.BlockFilter
+	err := ec.c.CallContext(ctx, &filters, "sero_getPKrFilters", hexutil.Uint64(start), hexutil.Uint64(count))
+	return filters, err
+}
+
+// MatchPKrs tests the pkr filters of the blocks [start, end) against pkrs
+// locally and returns the numbers of the blocks that may have outputs to them.
+// next is the first block not tested, as the node may not have the filters of
+// the latest blocks yet.
+func (ec *Client) MatchPKrs(ctx context.Context, pkrs []c_type.PKr, start, end uint64) (matched []uint64, next uint64, err error) {
+	items := make([][]byte, len(pkrs))
+	for i := range pkrs {
+		items[i] = pkrs[i][:]
+	}
+	for next = start; next < end; {
+		count := end - next
+		if count > pkrFilterPage {
+			count = pkrFilterPage
+		}
+		filters, err := ec.PKrFilters(ctx, next, count)
+		if err != nil {
+			return matched, next, err
+		}
+		if len(filters) == 0 {
+			return matched, next, nil
+		}
+		for _, f := range filters {
+			filter, err := pkrfilter.FromBytes(f.Filter)
+			if err != nil {
+				return matched, next, err
+			}
+			if filter.MatchAny(pkrfilter.Key(f.Hash), items) {
+				matched = append(matched, uint64(f.Number))
+			}
+			next = uint64(f.Number) + 1
+		}
+	}
+	return matched, next, nil
+}
+
+// OutsByPKrs fetches the blocks of [start, end) matching the pkr filters and
+// returns their outputs owned by pkrs, the node only learns which blocks are
+// downloaded. next is the first block not scanned.
+func (ec *Client) OutsByPKrs(ctx context.Context, pkrs []c_type.PKr, start, end uint64) (outs []txtool.Out, next uint64, err error) {
+	matched, next, err := ec.MatchPKrs(ctx, pkrs, start, end)
+	if err != nil {
+		return nil, next, err
+	}
+	owned := make(map[c_type.PKr]bool, len(pkrs))
+	for _, pkr := range pkrs {
+		owned[pkr] = true
+	}
+	for _, num := range matched {
+		var blocks []txtool.Block
+		if err := ec.c.CallContext(ctx, &blocks, "flight_getBlocksInfo", num, 1); err != nil {
+			return outs, num, err
+		}
+		for _, block := range blocks {
+			for _, out := range block.Outs {
+				if owned[*out.State.OS.ToPKr()] {
+					outs = append(outs, out)
+				}
+			}
+		}
+	}
+	return outs, next, nil
+}
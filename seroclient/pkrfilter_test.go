@@ -0,0 +1,74 @@
+package seroclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/pkrfilter"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+// testFilterAPI serves the pkr filters of the blocks [0, head), the outputs
+// of every block are the pkrs of its owners.
+type testFilterAPI struct {
+	head   uint64
+	owners map[uint64][]c_type.PKr
+	calls  int
+}
+
+func (api *testFilterAPI) GetPKrFilters(start hexutil.Uint64, count hexutil.Uint64) []pkrfilter.BlockFilter {
+	api.calls++
+	var filters []pkrfilter.BlockFilter
+	for number := uint64(start); number < uint64(start+count) && number < api.head; number++ {
+		hash := common.Hash{byte(number), byte(number >> 8), 1}
+		var items [][]byte
+		for _, pkr := range api.owners[number] {
+			items = append(items, append([]byte{}, pkr[:]...))
+		}
+		filter := pkrfilter.New(pkrfilter.Key(hash), items)
+		filters = append(filters, pkrfilter.BlockFilter{Number: hexutil.Uint64(number), Hash: hash, Filter: filter.Bytes()})
+	}
+	return filters
+}
+
+func TestMatchPKrs(t *testing.T) {
+	mine, other := c_type.PKr{1}, c_type.PKr{2}
+	api := &testFilterAPI{head: 1500, owners: map[uint64][]c_type.PKr{
+		3:    {mine, other},
+		7:    {other},
+		1200: {mine},
+	}}
+	server := rpc.NewServer()
+	if err := server.RegisterName("sero", api); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(rpc.DialInProc(server))
+	defer client.Close()
+
+	// The blocks are matched page by page up to the last indexed block
+	matched, next, err := client.MatchPKrs(context.Background(), []c_type.PKr{mine}, 0, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 2 || matched[0] != 3 || matched[1] != 1200 {
+		t.Fatalf("matched blocks mismatch: %v", matched)
+	}
+	if next != api.head {
+		t.Fatalf("next block: have %d, want %d", next, api.head)
+	}
+	if api.calls != 3 {
+		t.Fatalf("filter requests: have %d, want 3", api.calls)
+	}
+
+	// The range ends the matching before the head
+	matched, next, err = client.MatchPKrs(context.Background(), []c_type.PKr{other}, 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 1 || matched[0] != 7 || next != 10 {
+		t.Fatalf("ranged match mismatch: %v %d", matched, next)
+	}
+}
@@ -28,7 +28,6 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/filter"
-	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
@@ -105,6 +104,11 @@ func (db *LDBDatabase) Put(key []byte, value []byte) error {
 	return db.db.Put(key, value, nil)
 }
 
+// PutSync puts the given key / value and syncs it to the disk
+func (db *LDBDatabase) PutSync(key []byte, value []byte) error {
+	return db.db.Put(key, value, &opt.WriteOptions{Sync: true})
+}
+
 func (db *LDBDatabase) Has(key []byte) (bool, error) {
 	return db.db.Has(key, nil)
 }
@@ -123,15 +127,34 @@ func (db *LDBDatabase) Delete(key []byte) error {
 	return db.db.Delete(key, nil)
 }
 
-func (db *LDBDatabase) NewIterator() iterator.Iterator {
+func (db *LDBDatabase) NewIterator() Iterator {
 	return db.db.NewIterator(nil, nil)
 }
 
 // NewIteratorWithPrefix returns a iterator to iterate over subset of database content with a particular prefix.
-func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
 	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
 }
 
+// Engine returns the storage engine of the database.
+func (db *LDBDatabase) Engine() string {
+	return EngineLevelDB
+}
+
+// Stat returns the leveldb property of the database.
+func (db *LDBDatabase) Stat(property string) (string, error) {
+	if !strings.HasPrefix(property, "leveldb.") {
+		property = "leveldb." + property
+	}
+	return db.db.GetProperty(property)
+}
+
+// Compact compacts the keys [start, limit) of the database, nil is the start
+// or the end of the keys.
+func (db *LDBDatabase) Compact(start []byte, limit []byte) error {
+	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
+}
+
 func (db *LDBDatabase) Close() {
 	// Stop the metrics collection to avoid internal database races
 	db.quitLock.Lock()
@@ -0,0 +1,359 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package serodb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func newTestDB(t *testing.T, engine string) (KeyValueStore, func()) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "serodb_test_")
+	if err != nil {
+		t.Fatal("failed to create test file: " + err.Error())
+	}
+	db, err := OpenEngine(engine, dirname, 0, 0, false)
+	if err != nil {
+		t.Fatal("failed to create test database: " + err.Error())
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dirname)
+	}
+}
+
+var test_values = []string{"", "a", "1251", "\x00123\x00"}
+
+func TestEnginePutGet(t *testing.T) {
+	for _, engine := range Engines() {
+		t.Run(engine, func(t *testing.T) {
+			db, remove := newTestDB(t, engine)
+			defer remove()
+			testPutGet(db, t)
+		})
+	}
+}
+
+func TestMemoryDB_PutGet(t *testing.T) {
+	testPutGet(NewMemDatabase(), t)
+}
+
+func testPutGet(db Database, t *testing.T) {
+	for _, k := range test_values {
+		err := db.Put([]byte(k), nil)
+		if err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+	}
+
+	for _, k := range test_values {
+		data, err := db.Get([]byte(k))
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		if len(data) != 0 {
+			t.Fatalf("get returned wrong result, got %q expected nil", string(data))
+		}
+	}
+
+	_, err := db.Get([]byte("non-exist-key"))
+	if err == nil {
+		t.Fatalf("expect to return a not found error")
+	}
+
+	for _, v := range test_values {
+		err := db.Put([]byte(v), []byte(v))
+		if err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+	}
+
+	for _, v := range test_values {
+		data, err := db.Get([]byte(v))
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		if !bytes.Equal(data, []byte(v)) {
+			t.Fatalf("get returned wrong result, got %q expected %q", string(data), v)
+		}
+		if has, _ := db.Has([]byte(v)); !has {
+			t.Fatalf("has returned false for %q", v)
+		}
+	}
+
+	for _, v := range test_values {
+		err := db.Put([]byte(v), []byte("?"))
+		if err != nil {
+			t.Fatalf("put override failed: %v", err)
+		}
+	}
+
+	for _, v := range test_values {
+		data, err := db.Get([]byte(v))
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		if !bytes.Equal(data, []byte("?")) {
+			t.Fatalf("get returned wrong result, got %q expected ?", string(data))
+		}
+	}
+
+	for _, v := range test_values {
+		orig, err := db.Get([]byte(v))
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		orig[0] = byte(0xff)
+		data, err := db.Get([]byte(v))
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		if !bytes.Equal(data, []byte("?")) {
+			t.Fatalf("get returned wrong result, got %q expected ?", string(data))
+		}
+	}
+
+	for _, v := range test_values {
+		err := db.Delete([]byte(v))
+		if err != nil {
+			t.Fatalf("delete %q failed: %v", v, err)
+		}
+	}
+
+	for _, v := range test_values {
+		_, err := db.Get([]byte(v))
+		if err == nil {
+			t.Fatalf("got deleted value %q", v)
+		}
+		if has, _ := db.Has([]byte(v)); has {
+			t.Fatalf("has returned true for deleted %q", v)
+		}
+	}
+}
+
+func TestEngineParallelPutGet(t *testing.T) {
+	for _, engine := range Engines() {
+		t.Run(engine, func(t *testing.T) {
+			db, remove := newTestDB(t, engine)
+			defer remove()
+			testParallelPutGet(db, t)
+		})
+	}
+}
+
+func TestMemoryDB_ParallelPutGet(t *testing.T) {
+	testParallelPutGet(NewMemDatabase(), t)
+}
+
+func testParallelPutGet(db Database, t *testing.T) {
+	const n = 8
+	var pending sync.WaitGroup
+
+	pending.Add(n)
+	for i := 0; i < n; i++ {
+		go func(key string) {
+			defer pending.Done()
+			err := db.Put([]byte(key), []byte("v"+key))
+			if err != nil {
+				panic("put failed: " + err.Error())
+			}
+		}(strconv.Itoa(i))
+	}
+	pending.Wait()
+
+	pending.Add(n)
+	for i := 0; i < n; i++ {
+		go func(key string) {
+			defer pending.Done()
+			data, err := db.Get([]byte(key))
+			if err != nil {
+				panic("get failed: " + err.Error())
+			}
+			if !bytes.Equal(data, []byte("v"+key)) {
+				panic(fmt.Sprintf("get failed, got %q expected %q", []byte(data), []byte("v"+key)))
+			}
+		}(strconv.Itoa(i))
+	}
+	pending.Wait()
+
+	pending.Add(n)
+	for i := 0; i < n; i++ {
+		go func(key string) {
+			defer pending.Done()
+			err := db.Delete([]byte(key))
+			if err != nil {
+				panic("delete failed: " + err.Error())
+			}
+		}(strconv.Itoa(i))
+	}
+	pending.Wait()
+
+	pending.Add(n)
+	for i := 0; i < n; i++ {
+		go func(key string) {
+			defer pending.Done()
+			_, err := db.Get([]byte(key))
+			if err == nil {
+				panic("get succeeded")
+			}
+		}(strconv.Itoa(i))
+	}
+	pending.Wait()
+}
+
+func TestEngineBatch(t *testing.T) {
+	for _, engine := range Engines() {
+		t.Run(engine, func(t *testing.T) {
+			db, remove := newTestDB(t, engine)
+			defer remove()
+
+			batch := db.NewBatch()
+			for i := 0; i < 10; i++ {
+				batch.Put([]byte{byte(i)}, []byte{byte(i)})
+			}
+			if has, _ := db.Has([]byte{0}); has {
+				t.Fatalf("batch written before Write")
+			}
+			if err := batch.Write(); err != nil {
+				t.Fatalf("batch write failed: %v", err)
+			}
+			batch.Reset()
+			batch.Delete([]byte{0})
+			if err := batch.Write(); err != nil {
+				t.Fatalf("batch write failed: %v", err)
+			}
+			if has, _ := db.Has([]byte{0}); has {
+				t.Fatalf("batch delete not written")
+			}
+			for i := 1; i < 10; i++ {
+				if data, err := db.Get([]byte{byte(i)}); err != nil || !bytes.Equal(data, []byte{byte(i)}) {
+					t.Fatalf("get %d: have %x %v", i, data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEngineIterator(t *testing.T) {
+	for _, engine := range Engines() {
+		t.Run(engine, func(t *testing.T) {
+			db, remove := newTestDB(t, engine)
+			defer remove()
+
+			keys := []string{"a1", "a2", "b1", "b2", "b3", "c1"}
+			for i := len(keys) - 1; i >= 0; i-- {
+				db.Put([]byte(keys[i]), []byte("v"+keys[i]))
+			}
+
+			var have []string
+			it := db.NewIterator()
+			for it.Next() {
+				if !bytes.Equal(it.Value(), []byte("v"+string(it.Key()))) {
+					t.Fatalf("value of %s: have %s", it.Key(), it.Value())
+				}
+				have = append(have, string(it.Key()))
+			}
+			it.Release()
+			if fmt.Sprint(have) != fmt.Sprint(keys) {
+				t.Fatalf("iteration mismatch: have %v, want %v", have, keys)
+			}
+
+			have = nil
+			it = db.NewIteratorWithPrefix([]byte("b"))
+			for it.Next() {
+				have = append(have, string(it.Key()))
+			}
+			it.Release()
+			if fmt.Sprint(have) != fmt.Sprint(keys[2:5]) {
+				t.Fatalf("prefix iteration mismatch: have %v, want %v", have, keys[2:5])
+			}
+
+			it = db.NewIteratorWithPrefix([]byte("b"))
+			if !it.Last() || string(it.Key()) != "b3" {
+				t.Fatalf("last of the prefix: have %s", it.Key())
+			}
+			if !it.Seek([]byte("b2")) || string(it.Key()) != "b2" {
+				t.Fatalf("seek: have %s", it.Key())
+			}
+			if !it.Next() || string(it.Key()) != "b3" {
+				t.Fatalf("next after seek: have %s", it.Key())
+			}
+			if it.Next() {
+				t.Fatalf("iterated out of the prefix to %s", it.Key())
+			}
+			it.Release()
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	for _, from := range Engines() {
+		for _, to := range Engines() {
+			if from == to {
+				continue
+			}
+			t.Run(from+"-"+to, func(t *testing.T) {
+				dir, err := ioutil.TempDir(os.TempDir(), "serodb_test_")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(dir)
+				file := filepath.Join(dir, "db")
+
+				db, err := OpenEngine(from, file, 0, 0, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < 100; i++ {
+					db.Put([]byte(strconv.Itoa(i)), []byte{byte(i)})
+				}
+				db.Close()
+				if engine := DetectEngine(file); engine != from {
+					t.Fatalf("detected engine: have %s, want %s", engine, from)
+				}
+
+				count, err := Migrate(file, to, 0, 0)
+				if err != nil {
+					t.Fatalf("migrate failed: %v", err)
+				}
+				if count != 100 {
+					t.Fatalf("migrated entries: have %d, want 100", count)
+				}
+				if engine := DetectEngine(file); engine != to {
+					t.Fatalf("detected engine: have %s, want %s", engine, to)
+				}
+				db, err = OpenEngine(to, file, 0, 0, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer db.Close()
+				for i := 0; i < 100; i++ {
+					if data, err := db.Get([]byte(strconv.Itoa(i))); err != nil || !bytes.Equal(data, []byte{byte(i)}) {
+						t.Fatalf("get %d: have %x %v", i, data, err)
+					}
+				}
+			})
+		}
+	}
+}
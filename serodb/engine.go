@@ -0,0 +1,149 @@
+package serodb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/sero-cash/go-sero/log"
+)
+
+// The storage engines of the databases on disk.
+const (
+	EngineLevelDB = "leveldb"
+	EnginePebble  = "pebble"
+)
+
+// DefaultEngine is the storage engine of the new databases.
+var DefaultEngine = EngineLevelDB
+
+// Engines returns the supported storage engines.
+func Engines() []string {
+	return []string{EngineLevelDB, EnginePebble}
+}
+
+// ValidEngine reports whether engine is a supported storage engine.
+func ValidEngine(engine string) bool {
+	for _, e := range Engines() {
+		if e == engine {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectEngine returns the storage engine of the database in file, or an empty
+// string if there is none. Only pebble writes the OPTIONS files.
+func DetectEngine(file string) string {
+	files, err := ioutil.ReadDir(file)
+	if err != nil {
+		return ""
+	}
+	engine := ""
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), "OPTIONS-") {
+			return EnginePebble
+		}
+		if f.Name() == "CURRENT" {
+			engine = EngineLevelDB
+		}
+	}
+	return engine
+}
+
+// OpenEngine opens the database in file with the storage engine.
+func OpenEngine(engine string, file string, cache int, handles int, readOnly bool) (KeyValueStore, error) {
+	switch engine {
+	case EngineLevelDB:
+		return NewLDBDatabaseEx(file, cache, handles, readOnly)
+	case EnginePebble:
+		return NewPebbleDatabase(file, cache, handles, readOnly)
+	default:
+		return nil, fmt.Errorf("unknown database engine %q", engine)
+	}
+}
+
+// NewDatabaseEx opens the database in file with the storage engine it was
+// created with, a new database is created with the default engine.
+func NewDatabaseEx(file string, cache int, handles int, readOnly bool) (KeyValueStore, error) {
+	engine := DetectEngine(file)
+	if engine == "" {
+		engine = DefaultEngine
+	} else if engine != DefaultEngine {
+		log.Warn("Database engine is not the configured one, run gero db migrate to convert it", "database", file, "engine", engine, "configured", DefaultEngine)
+	}
+	return OpenEngine(engine, file, cache, handles, readOnly)
+}
+
+// NewDatabase opens the database in file for writing.
+func NewDatabase(file string, cache int, handles int) (KeyValueStore, error) {
+	return NewDatabaseEx(file, cache, handles, false)
+}
+
+// Copy writes every entry of src to dst and returns the number of entries.
+func Copy(dst Database, src Iteratee) (count int, err error) {
+	it := src.NewIterator()
+	defer it.Release()
+	batch := dst.NewBatch()
+	for it.Next() {
+		if err = batch.Put(it.Key(), it.Value()); err != nil {
+			return
+		}
+		count++
+		if batch.ValueSize() >= IdealBatchSize {
+			if err = batch.Write(); err != nil {
+				return
+			}
+			batch.Reset()
+		}
+	}
+	if err = it.Error(); err != nil {
+		return
+	}
+	err = batch.Write()
+	return
+}
+
+// Migrate converts the database in file to the storage engine, the converted
+// database replaces the original once every entry is copied.
+func Migrate(file string, engine string, cache int, handles int) (count int, err error) {
+	from := DetectEngine(file)
+	if from == "" {
+		return 0, fmt.Errorf("no database in %s", file)
+	}
+	if from == engine {
+		return 0, nil
+	}
+	src, err := OpenEngine(from, file, cache, handles, true)
+	if err != nil {
+		return 0, err
+	}
+	tmp := file + ".migrate"
+	if err = os.RemoveAll(tmp); err != nil {
+		src.Close()
+		return 0, err
+	}
+	dst, err := OpenEngine(engine, tmp, cache, handles, false)
+	if err != nil {
+		src.Close()
+		return 0, err
+	}
+	count, err = Copy(dst, src)
+	dst.Close()
+	src.Close()
+	if err != nil {
+		os.RemoveAll(tmp)
+		return 0, err
+	}
+
+	old := file + ".old"
+	if err = os.Rename(file, old); err != nil {
+		return 0, err
+	}
+	if err = os.Rename(tmp, file); err != nil {
+		os.Rename(old, file)
+		return 0, err
+	}
+	return count, os.RemoveAll(old)
+}
@@ -60,3 +60,36 @@ type Tri interface {
 	TryUpdate(key, value []byte) error
 	TryDelete(key []byte) error
 }
+
+// Iterator iterates over the key-value pairs of a database in key order. A new
+// iterator is positioned before the first pair.
+type Iterator interface {
+	First() bool
+	Last() bool
+	Seek(key []byte) bool
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Error() error
+	Release()
+}
+
+// Iteratee wraps the creation of the iterators of a database.
+type Iteratee interface {
+	NewIterator() Iterator
+	NewIteratorWithPrefix(prefix []byte) Iterator
+}
+
+// KeyValueStore is a database on disk, stored by one of the storage engines.
+type KeyValueStore interface {
+	Database
+	Iteratee
+	Path() string
+	Engine() string
+	Stat(property string) (string, error)
+	Compact(start []byte, limit []byte) error
+	Meter(prefix string)
+
+	// PutSync is a Put synced to the disk before it returns.
+	PutSync(key []byte, value []byte) error
+}
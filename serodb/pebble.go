@@ -0,0 +1,241 @@
+package serodb
+
+import (
+	"github.com/cockroachdb/pebble"
+	"github.com/sero-cash/go-sero/log"
+)
+
+// PebbleDatabase is a database stored by pebble.
+type PebbleDatabase struct {
+	fn string     // filename for reporting
+	db *pebble.DB // Pebble instance
+
+	log log.Logger // Contextual logger tracking the database path
+}
+
+// NewPebbleDatabase returns a pebble wrapped object.
+func NewPebbleDatabase(file string, cache int, handles int, readOnly bool) (*PebbleDatabase, error) {
+	logger := log.New("database", file)
+
+	// Ensure we have some minimal caching and file guarantees
+	if cache < 16 {
+		cache = 16
+	}
+	if handles < 16 {
+		handles = 16
+	}
+	logger.Info("Allocated cache and file handles", "engine", EnginePebble, "cache", cache, "handles", handles)
+
+	opts := &pebble.Options{
+		Cache:        pebble.NewCache(int64(cache / 2 * 1024 * 1024)),
+		MaxOpenFiles: handles,
+		MemTableSize: cache / 4 * 1024 * 1024,
+		ReadOnly:     readOnly,
+	}
+	defer opts.Cache.Unref()
+	db, err := pebble.Open(file, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PebbleDatabase{
+		fn:  file,
+		db:  db,
+		log: logger,
+	}, nil
+}
+
+// Path returns the path to the database directory.
+func (db *PebbleDatabase) Path() string {
+	return db.fn
+}
+
+// Engine returns the storage engine of the database.
+func (db *PebbleDatabase) Engine() string {
+	return EnginePebble
+}
+
+// Put puts the given key / value to the queue
+func (db *PebbleDatabase) Put(key []byte, value []byte) error {
+	return db.db.Set(key, value, pebble.NoSync)
+}
+
+// PutSync puts the given key / value and syncs it to the disk
+func (db *PebbleDatabase) PutSync(key []byte, value []byte) error {
+	return db.db.Set(key, value, pebble.Sync)
+}
+
+func (db *PebbleDatabase) Has(key []byte) (bool, error) {
+	_, closer, err := db.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	closer.Close()
+	return true, nil
+}
+
+// Get returns the given key if it's present.
+func (db *PebbleDatabase) Get(key []byte) ([]byte, error) {
+	dat, closer, err := db.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	ret := append([]byte{}, dat...)
+	closer.Close()
+	return ret, nil
+}
+
+// Delete deletes the key from the queue and database
+func (db *PebbleDatabase) Delete(key []byte) error {
+	return db.db.Delete(key, pebble.NoSync)
+}
+
+func (db *PebbleDatabase) NewIterator() Iterator {
+	return &pebbleIterator{iter: db.db.NewIter(nil)}
+}
+
+// NewIteratorWithPrefix returns a iterator to iterate over subset of database content with a particular prefix.
+func (db *PebbleDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return &pebbleIterator{iter: db.db.NewIter(&pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: upperBound(prefix),
+	})}
+}
+
+// upperBound returns the first key after the keys with the prefix, nil if
+// there is none.
+func upperBound(prefix []byte) []byte {
+	limit := append([]byte{}, prefix...)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return limit[:i+1]
+		}
+	}
+	return nil
+}
+
+// Stat returns the metrics of pebble, it has no other properties.
+func (db *PebbleDatabase) Stat(property string) (string, error) {
+	return db.db.Metrics().String(), nil
+}
+
+// Compact compacts the keys [start, limit) of the database, nil is the start
+// or the end of the keys.
+func (db *PebbleDatabase) Compact(start []byte, limit []byte) error {
+	if limit == nil {
+		it := db.db.NewIter(nil)
+		if it.Last() {
+			limit = append(append([]byte{}, it.Key()...), 0)
+		}
+		it.Close()
+		if limit == nil {
+			return nil
+		}
+	}
+	return db.db.Compact(start, limit)
+}
+
+// Meter is a no-op, the metrics of pebble are reported by Stat.
+func (db *PebbleDatabase) Meter(prefix string) {
+}
+
+func (db *PebbleDatabase) Close() {
+	if err := db.db.Close(); err == nil {
+		db.log.Info("Database closed")
+	} else {
+		db.log.Error("Failed to close database", "err", err)
+	}
+}
+
+func (db *PebbleDatabase) NewBatch() Batch {
+	return &pebbleBatch{db: db.db, b: db.db.NewBatch()}
+}
+
+type pebbleBatch struct {
+	db   *pebble.DB
+	b    *pebble.Batch
+	size int
+}
+
+func (b *pebbleBatch) Put(key, value []byte) error {
+	b.size += len(value)
+	return b.b.Set(key, value, nil)
+}
+
+func (b *pebbleBatch) Delete(key []byte) error {
+	b.size += 1
+	return b.b.Delete(key, nil)
+}
+
+// Write commits the batch, pebble does not commit a batch twice so the writes
+// after it go to a new empty batch.
+func (b *pebbleBatch) Write() error {
+	err := b.b.Commit(pebble.NoSync)
+	b.b.Close()
+	b.b = b.db.NewBatch()
+	b.size = 0
+	return err
+}
+
+func (b *pebbleBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *pebbleBatch) Reset() {
+	b.b.Reset()
+	b.size = 0
+}
+
+// pebbleIterator positions the pebble iterator on the first key at the first
+// Next as the leveldb iterators do.
+type pebbleIterator struct {
+	iter    *pebble.Iterator
+	started bool
+}
+
+func (it *pebbleIterator) First() bool {
+	it.started = true
+	return it.iter.First()
+}
+
+func (it *pebbleIterator) Last() bool {
+	it.started = true
+	return it.iter.Last()
+}
+
+func (it *pebbleIterator) Seek(key []byte) bool {
+	it.started = true
+	return it.iter.SeekGE(key)
+}
+
+func (it *pebbleIterator) Next() bool {
+	if !it.started {
+		return it.First()
+	}
+	return it.iter.Next()
+}
+
+func (it *pebbleIterator) Key() []byte {
+	if !it.iter.Valid() {
+		return nil
+	}
+	return it.iter.Key()
+}
+
+func (it *pebbleIterator) Value() []byte {
+	if !it.iter.Valid() {
+		return nil
+	}
+	return it.iter.Value()
+}
+
+func (it *pebbleIterator) Error() error {
+	return it.iter.Error()
+}
+
+func (it *pebbleIterator) Release() {
+	it.iter.Close()
+}
@@ -0,0 +1,137 @@
+package serodb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPebbleReopen(t *testing.T) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "serodb_pebble_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dirname)
+
+	db, err := NewPebbleDatabase(dirname, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutSync([]byte("sync"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	batch := db.NewBatch()
+	batch.Put([]byte("batch"), []byte("2"))
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	// The batch is still usable and empty after the write
+	if size := batch.ValueSize(); size != 0 {
+		t.Fatalf("value size after the write mismatch: have %d, want 0", size)
+	}
+	batch.Put([]byte("again"), []byte("3"))
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if engine := DetectEngine(dirname); engine != EnginePebble {
+		t.Fatalf("detected engine %q, want %q", engine, EnginePebble)
+	}
+	ro, err := NewDatabaseEx(dirname, 0, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	if ro.Engine() != EnginePebble {
+		t.Fatalf("reopened with engine %q", ro.Engine())
+	}
+	for k, v := range map[string]string{"sync": "1", "batch": "2", "again": "3"} {
+		if data, err := ro.Get([]byte(k)); err != nil || string(data) != v {
+			t.Errorf("key %q: have %q, %v, want %q", k, data, err, v)
+		}
+	}
+	if err := ro.Put([]byte("sync"), []byte("4")); err == nil {
+		t.Fatal("read-only database accepted a write")
+	}
+}
+
+func TestPebblePrefixIterator(t *testing.T) {
+	db, remove := newTestDB(t, EnginePebble)
+	defer remove()
+
+	keys := [][]byte{{0x01}, {0x01, 0xff}, {0x01, 0xff, 0xff}, {0x02}, {0xff}, {0xff, 0xff}, {0xff, 0xff, 0x00}}
+	for _, k := range keys {
+		if err := db.Put(k, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		prefix []byte
+		want   [][]byte
+	}{
+		{nil, keys},
+		{[]byte{0x01}, keys[0:3]},
+		{[]byte{0x01, 0xff}, keys[1:3]},
+		{[]byte{0xff}, keys[4:]},
+		{[]byte{0xff, 0xff}, keys[5:]},
+		{[]byte{0x03}, nil},
+	}
+	for _, test := range tests {
+		var have [][]byte
+		it := db.NewIteratorWithPrefix(test.prefix)
+		for it.Next() {
+			if !bytes.Equal(it.Key(), it.Value()) {
+				t.Errorf("prefix %x: key %x has value %x", test.prefix, it.Key(), it.Value())
+			}
+			have = append(have, append([]byte{}, it.Key()...))
+		}
+		it.Release()
+		if len(have) != len(test.want) {
+			t.Errorf("prefix %x: have %x, want %x", test.prefix, have, test.want)
+			continue
+		}
+		for i := range have {
+			if !bytes.Equal(have[i], test.want[i]) {
+				t.Errorf("prefix %x: have %x, want %x", test.prefix, have, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPebbleCompact(t *testing.T) {
+	db, remove := newTestDB(t, EnginePebble)
+	defer remove()
+
+	// An empty database has nothing to compact
+	if err := db.Compact(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 256; i++ {
+		if err := db.Put([]byte{byte(i)}, []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 128; i++ {
+		if err := db.Delete([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Compact(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Compact([]byte{0x80}, []byte{0x90}); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := db.Has([]byte{0x7f}); has {
+		t.Fatal("deleted key survived the compaction")
+	}
+	if data, err := db.Get([]byte{0xff}); err != nil || !bytes.Equal(data, []byte{0xff}) {
+		t.Fatalf("last key after the compaction: %x, %v", data, err)
+	}
+	if stats, err := db.Stat("leveldb.stats"); err != nil || stats == "" {
+		t.Fatalf("stats missing: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package voter
+
+import (
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/superzk"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/stake"
+)
+
+// LotteryWait is how long a miner waits for the votes of a lottery.
+const LotteryWait = 5 * time.Minute
+
+// Config are the configuration parameters of the voter.
+type Config struct {
+	// Failover lets the node vote for its own shares delegated to a pool
+	// when the pool has not voted for them within FailoverWait.
+	Failover bool
+	// FailoverWait is the fraction of LotteryWait to wait for the pool vote.
+	FailoverWait float64
+
+	// Journal is the database recording the signed votes, none when empty.
+	Journal string
+
+	// Signer is the endpoint of a remote vote signer, the keystore of the
+	// node signs when empty.
+	Signer string
+	// SignerToken authenticates the node to the remote signer.
+	SignerToken string `toml:",omitempty"`
+}
+
+var DefaultConfig = Config{
+	FailoverWait: 0.1,
+	Journal:      "votes",
+}
+
+func (c *Config) failoverDelay() time.Duration {
+	wait := c.FailoverWait
+	if wait <= 0 || wait >= 1 {
+		wait = DefaultConfig.FailoverWait
+	}
+	return time.Duration(float64(LotteryWait) * wait)
+}
+
+type shareVoteKey struct {
+	shareHash common.Hash
+	index     uint32
+	poshash   common.Hash
+}
+
+func voteKeyOf(info voteInfo) shareVoteKey {
+	return shareVoteKey{info.shareHash, info.index, info.poshash}
+}
+
+// poolVoteKey returns the vote key of the pool the share of vote is delegated
+// to and the stake hash the pool signs, nil if the pool can not vote.
+func (self *Voter) poolVoteKey(vote *types.Vote) (*c_type.PKr, common.Hash) {
+	parentHeader := self.chain.GetHeaderByNumber(vote.ParentNum)
+	if parentHeader == nil {
+		return nil, common.Hash{}
+	}
+	state, err := self.chain.StateAt(parentHeader)
+	if err != nil {
+		return nil, common.Hash{}
+	}
+	stakeState := stake.NewStakeState(state)
+	share := stakeState.GetShare(vote.ShareId)
+	if share == nil || share.PoolId == nil {
+		return nil, common.Hash{}
+	}
+	pool := stakeState.GetStakePool(*share.PoolId)
+	if pool == nil || !pool.CanBeVote() {
+		return nil, common.Hash{}
+	}
+	parentPos := parentHeader.HashPos()
+	return &pool.VotePKr, types.StakeHash(&vote.PosHash, &parentPos, true)
+}
+
+// checkPoolVote records the pool vote received from a peer once it is signed
+// by the vote key of the pool.
+func (self *Voter) checkPoolVote(vote *types.Vote) {
+	pkr, stakeHash := self.poolVoteKey(vote)
+	if pkr == nil {
+		return
+	}
+	self.voteMu.Lock()
+	defer self.voteMu.Unlock()
+	self.markPoolVote(vote, pkr, stakeHash)
+}
+
+// markPoolVote records the pool vote if it is signed with pkr, a forged vote
+// must not suppress the failover. The caller holds voteMu.
+func (self *Voter) markPoolVote(vote *types.Vote, pkr *c_type.PKr, stakeHash common.Hash) bool {
+	if !vote.IsPool {
+		return false
+	}
+	if !superzk.VerifyPKr_ByHeight(vote.ParentNum+1, stakeHash.HashToUint256(), &vote.Sign, pkr) {
+		log.Info("voter pool vote rejected, bad signature", "poshash", vote.PosHash, "block", vote.ParentNum+1, "share", vote.ShareId, "idx", vote.Idx)
+		return false
+	}
+	self.poolVotes[shareVoteKey{vote.ShareId, vote.Idx, vote.PosHash}] = time.Now()
+	return true
+}
+
+func (self *Voter) hasPoolVote(key shareVoteKey) bool {
+	self.voteMu.RLock()
+	defer self.voteMu.RUnlock()
+	_, ok := self.poolVotes[key]
+	return ok
+}
+
+// vote signs info now, or once the failover delay has passed without a vote of
+// the pool the share is delegated to.
+func (self *Voter) vote(info voteInfo) {
+	if !info.failover {
+		self.sign(info)
+		return
+	}
+	key := voteKeyOf(info)
+	self.voteMu.Lock()
+	if _, ok := self.failovers[key]; ok {
+		self.voteMu.Unlock()
+		return
+	}
+	self.failovers[key] = time.Now()
+	self.voteMu.Unlock()
+
+	delay := self.config.failoverDelay()
+	log.Info("voter failover scheduled", "poshash", info.poshash, "block", info.parentNum+1, "share", info.shareHash, "idx", info.index, "delay", delay)
+	go func() {
+		select {
+		case <-time.After(delay):
+		case <-self.stopVote:
+			return
+		}
+		if current := self.chain.CurrentBlock().NumberU64(); current > info.parentNum+delayNum {
+			log.Info("voter failover not needed, block sealed", "poshash", info.poshash, "block", info.parentNum+1, "share", info.shareHash, "current", current)
+			return
+		}
+		if self.hasPoolVote(key) {
+			log.Info("voter failover not needed, pool voted", "poshash", info.poshash, "block", info.parentNum+1, "share", info.shareHash, "idx", info.index)
+			return
+		}
+		log.Warn("voter failover, pool missed the vote, voting solo", "poshash", info.poshash, "block", info.parentNum+1, "share", info.shareHash, "idx", info.index)
+		self.sign(info)
+	}()
+}
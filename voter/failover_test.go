@@ -0,0 +1,58 @@
+package voter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-czero-import/superzk"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/core/types"
+)
+
+func TestMarkPoolVote(t *testing.T) {
+	cpt.ZeroInit_NoCircuit()
+
+	var seed address.Seed
+	seed[0] = 1
+	sk := superzk.Seed2Sk(seed.SeedToUint256(), 1)
+	tk, err := superzk.Sk2Tk(&sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := superzk.Tk2Pk(&tk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rand := c_type.Uint256{2}
+	pkr := superzk.Pk2PKr(&pk, &rand)
+
+	stakeHash := common.Hash{3}
+	vote := &types.Vote{Idx: 4, ParentNum: 9, ShareId: common.Hash{5}, PosHash: common.Hash{6}, IsPool: true}
+	key := shareVoteKey{vote.ShareId, vote.Idx, vote.PosHash}
+	voter := &Voter{poolVotes: make(map[shareVoteKey]time.Time)}
+
+	// A vote not signed by the pool is ignored
+	vote.Sign = c_type.Uint512{7}
+	if voter.markPoolVote(vote, &pkr, stakeHash) || voter.hasPoolVote(key) {
+		t.Fatal("forged pool vote recorded")
+	}
+	other := superzk.Pk2PKr(&pk, &c_type.Uint256{8})
+	if vote.Sign, err = SignStakeHash(&seed, vote.ParentNum+1, stakeHash, other); err != nil {
+		t.Fatal(err)
+	}
+	if voter.markPoolVote(vote, &pkr, stakeHash) || voter.hasPoolVote(key) {
+		t.Fatal("pool vote signed for another pkr recorded")
+	}
+
+	// The vote signed with the vote key of the pool is recorded
+	if vote.Sign, err = SignStakeHash(&seed, vote.ParentNum+1, stakeHash, pkr); err != nil {
+		t.Fatal(err)
+	}
+	if !voter.markPoolVote(vote, &pkr, stakeHash) || !voter.hasPoolVote(key) {
+		t.Fatal("valid pool vote not recorded")
+	}
+}
@@ -0,0 +1,178 @@
+package voter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// journalDepth is how many blocks of signed votes the journal keeps.
+const journalDepth = 10000
+
+var (
+	journalPrefix = []byte("VOTE")
+	signPrefix    = []byte("SIGN")
+)
+
+// "VOTE" + block + poshash + shareId + idx + isPool => JournalEntry
+func journalKey(block uint64, poshash common.Hash, shareId common.Hash, idx uint32, isPool bool) []byte {
+	key := append(append([]byte{}, journalPrefix...), utils.EncodeNumber(block)...)
+	key = append(key, poshash[:]...)
+	key = append(key, shareId[:]...)
+	key = append(key, utils.EncodeNumber32(idx)...)
+	if isPool {
+		return append(key, 1)
+	}
+	return append(key, 0)
+}
+
+// "SIGN" + block + pkr + stakeHash => signature
+func signKey(block uint64, pkr c_type.PKr, stakeHash common.Hash) []byte {
+	key := append(append([]byte{}, signPrefix...), utils.EncodeNumber(block)...)
+	key = append(key, pkr[:]...)
+	return append(key, stakeHash[:]...)
+}
+
+// blockOfKey decodes the block of a journal or a sign key, both prefixes have
+// the same length.
+func blockOfKey(key []byte) uint64 {
+	return utils.DecodeNumber(key[len(journalPrefix) : len(journalPrefix)+8])
+}
+
+// JournalEntry is a vote signed by this node.
+type JournalEntry struct {
+	Vote types.Vote
+	Time uint64
+}
+
+// VoteJournal is a durable record of the signed votes. A vote is written to it
+// before it is broadcast, so the node never signs twice for the same share
+// slot of a lottery, even across restarts.
+type VoteJournal struct {
+	db   serodb.KeyValueStore
+	lock sync.Mutex
+}
+
+// NewVoteJournal opens the journal at path with the storage engine it was
+// created with.
+func NewVoteJournal(path string, readOnly bool) (*VoteJournal, error) {
+	db, err := serodb.NewDatabaseEx(path, 16, 16, readOnly)
+	if err != nil {
+		return nil, err
+	}
+	return &VoteJournal{db: db}, nil
+}
+
+func (journal *VoteJournal) get(key []byte) *types.Vote {
+	data, err := journal.db.Get(key)
+	if err != nil {
+		return nil
+	}
+	var entry JournalEntry
+	if err := rlp.DecodeBytes(data, &entry); err != nil {
+		log.Error("Invalid vote journal entry", "key", common.Bytes2Hex(key), "err", err)
+		return nil
+	}
+	return &entry.Vote
+}
+
+// signOnce returns the vote journaled for info, or signs a new one with sign
+// and journals it durably before returning it.
+func (journal *VoteJournal) signOnce(info voteInfo, sign func() (*types.Vote, error)) (vote *types.Vote, signed bool, err error) {
+	key := journalKey(info.parentNum+1, info.poshash, info.shareHash, info.index, info.isPool)
+
+	journal.lock.Lock()
+	defer journal.lock.Unlock()
+
+	if vote = journal.get(key); vote != nil {
+		return vote, false, nil
+	}
+	if vote, err = sign(); err != nil {
+		return nil, false, err
+	}
+	data, err := rlp.EncodeToBytes(&JournalEntry{*vote, uint64(time.Now().Unix())})
+	if err != nil {
+		return nil, false, err
+	}
+	if err = journal.db.PutSync(key, data); err != nil {
+		return nil, false, err
+	}
+	return vote, true, nil
+}
+
+// signStakeHashOnce returns the signature journaled for the stake hash of block
+// and pkr, or signs it with sign and journals it durably before returning it,
+// so a signer never hands out two signatures for the same vote.
+func (journal *VoteJournal) signStakeHashOnce(block uint64, stakeHash common.Hash, pkr c_type.PKr, sign func() (c_type.Uint512, error)) (c_type.Uint512, error) {
+	key := signKey(block, pkr, stakeHash)
+
+	journal.lock.Lock()
+	defer journal.lock.Unlock()
+
+	if data, err := journal.db.Get(key); err == nil && len(data) == len(c_type.Uint512{}) {
+		var signature c_type.Uint512
+		copy(signature[:], data)
+		return signature, nil
+	}
+	signature, err := sign()
+	if err != nil {
+		return signature, err
+	}
+	if err = journal.db.PutSync(key, signature[:]); err != nil {
+		return c_type.Uint512{}, err
+	}
+	return signature, nil
+}
+
+// List returns the journaled votes of the blocks [start, end).
+func (journal *VoteJournal) List(start, end uint64) (entries []JournalEntry) {
+	iterator := journal.db.NewIteratorWithPrefix(journalPrefix)
+	defer iterator.Release()
+	for ok := iterator.Seek(append(append([]byte{}, journalPrefix...), utils.EncodeNumber(start)...)); ok; ok = iterator.Next() {
+		key := iterator.Key()
+		if blockOfKey(key) >= end {
+			break
+		}
+		var entry JournalEntry
+		if err := rlp.DecodeBytes(iterator.Value(), &entry); err != nil {
+			log.Error("Invalid vote journal entry", "key", common.Bytes2Hex(key), "err", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return
+}
+
+// prune drops the votes and the signatures of the blocks before block.
+func (journal *VoteJournal) prune(block uint64) {
+	batch := journal.db.NewBatch()
+	journal.prunePrefix(batch, journalPrefix, block)
+	journal.prunePrefix(batch, signPrefix, block)
+	if err := batch.Write(); err != nil {
+		log.Error("Prune vote journal", "err", err)
+	}
+}
+
+func (journal *VoteJournal) prunePrefix(batch serodb.Batch, prefix []byte, block uint64) {
+	iterator := journal.db.NewIteratorWithPrefix(prefix)
+	for iterator.Next() {
+		key := iterator.Key()
+		if blockOfKey(key) >= block {
+			break
+		}
+		batch.Delete(common.CopyBytes(key))
+	}
+	iterator.Release()
+}
+
+func (journal *VoteJournal) Close() {
+	journal.db.Close()
+}
@@ -0,0 +1,58 @@
+package voter
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+)
+
+func TestVoteJournalSignOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "votejournal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	journal, err := NewVoteJournal(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := voteInfo{index: 1, shareHash: common.Hash{1}, poshash: common.Hash{2}, parentNum: 99}
+	signs := 0
+	sign := func() (*types.Vote, error) {
+		signs++
+		vote := &types.Vote{info.index, info.parentNum, info.shareHash, info.poshash, info.isPool, c_type.Uint512{byte(signs)}}
+		return vote, nil
+	}
+	first, signed, err := journal.signOnce(info, sign)
+	if err != nil || !signed {
+		t.Fatalf("first sign failed: %v %v", signed, err)
+	}
+	journal.Close()
+
+	// a restarted node must not sign the same vote again
+	if journal, err = NewVoteJournal(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	second, signed, err := journal.signOnce(info, sign)
+	if err != nil || signed || signs != 1 {
+		t.Fatalf("vote signed twice: %v %v %d", signed, err, signs)
+	}
+	if second.Hash() != first.Hash() || second.Sign != first.Sign {
+		t.Errorf("journaled vote mismatch")
+	}
+
+	if entries := journal.List(100, 101); len(entries) != 1 {
+		t.Errorf("list mismatch: have %d votes, want 1", len(entries))
+	}
+	journal.prune(101)
+	if entries := journal.List(0, 1000); len(entries) != 0 {
+		t.Errorf("pruned votes listed: %d", len(entries))
+	}
+}
@@ -0,0 +1,225 @@
+package voter
+
+import (
+	"crypto/subtle"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_superzk"
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/superzk"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+// VoteSigner signs the votes of the voting keys it holds.
+type VoteSigner interface {
+	// Key returns the identifier of the key signing for pkr, and false when
+	// the signer does not hold it. A share and its pool voted by the same key
+	// only get one vote.
+	Key(pkr c_type.PKr) (common.Hash, bool)
+
+	// Sign signs the stake hash of a vote for block with the key of pkr.
+	Sign(block uint64, stakeHash common.Hash, pkr c_type.PKr) (c_type.Uint512, error)
+}
+
+// SignStakeHash signs the stake hash of a vote for block with the seed owning pkr.
+func SignStakeHash(seed *address.Seed, block uint64, stakeHash common.Hash, pkr c_type.PKr) (c_type.Uint512, error) {
+	data := c_type.Uint256{}
+	copy(data[:], stakeHash[:])
+	version := 1
+	if c_superzk.IsSzkPKr(&pkr) {
+		version = 2
+	}
+	sk := superzk.Seed2Sk(seed.SeedToUint256(), version)
+	return superzk.SignPKr_ByHeight(block, &sk, &data, &pkr)
+}
+
+// KeystoreSigner signs with the unlocked wallets of the node.
+type KeystoreSigner struct {
+	am *accounts.Manager
+}
+
+func NewKeystoreSigner(am *accounts.Manager) *KeystoreSigner {
+	return &KeystoreSigner{am}
+}
+
+func (self *KeystoreSigner) wallet(pkr c_type.PKr) accounts.Wallet {
+	for _, w := range self.am.Wallets() {
+		if w.IsMine(pkr) {
+			return w
+		}
+	}
+	return nil
+}
+
+func (self *KeystoreSigner) Key(pkr c_type.PKr) (common.Hash, bool) {
+	w := self.wallet(pkr)
+	if w == nil {
+		return common.Hash{}, false
+	}
+	if _, err := w.GetSeed(); err != nil {
+		log.Trace("KeystoreSigner key", "err", err)
+		return common.Hash{}, false
+	}
+	account := w.Accounts()[0]
+	return crypto.Keccak256Hash(account.Address[:]), true
+}
+
+func (self *KeystoreSigner) Sign(block uint64, stakeHash common.Hash, pkr c_type.PKr) (c_type.Uint512, error) {
+	seed := GetSeedByVotePkr(self.am.Wallets(), pkr)
+	if seed == nil {
+		return c_type.Uint512{}, errors.New("no unlocked wallet for the vote pkr")
+	}
+	return SignStakeHash(seed, block, stakeHash, pkr)
+}
+
+var (
+	errSignerToken = errors.New("invalid signer token")
+	errSignerPKr   = errors.New("vote pkr not allowed by the signer")
+)
+
+// SignerService serves a VoteSigner in the "signer" RPC namespace. Every call
+// carries the token shared by the signer and the node. Only the allowed vote
+// PKrs are signed for, and every signature is journaled before it is returned
+// so a stake hash is never signed twice.
+type SignerService struct {
+	signer  VoteSigner
+	token   string
+	allowed map[c_type.PKr]bool
+	journal *VoteJournal
+
+	lock   sync.Mutex
+	pruned uint64
+}
+
+func NewSignerService(signer VoteSigner, token string, allowed []c_type.PKr, journal *VoteJournal) *SignerService {
+	service := &SignerService{
+		signer:  signer,
+		token:   token,
+		allowed: make(map[c_type.PKr]bool),
+		journal: journal,
+	}
+	for _, pkr := range allowed {
+		service.allowed[pkr] = true
+	}
+	return service
+}
+
+func (self *SignerService) auth(token string) error {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(self.token)) != 1 {
+		return errSignerToken
+	}
+	return nil
+}
+
+type SignerKey struct {
+	Key  common.Hash `json:"key"`
+	Held bool        `json:"held"`
+}
+
+func (self *SignerService) Key(token string, pkr hexutil.Bytes) (*SignerKey, error) {
+	if err := self.auth(token); err != nil {
+		return nil, err
+	}
+	votePKr := c_type.NewPKrByBytes(pkr)
+	if !self.allowed[votePKr] {
+		return &SignerKey{}, nil
+	}
+	key, ok := self.signer.Key(votePKr)
+	return &SignerKey{key, ok}, nil
+}
+
+func (self *SignerService) Sign(token string, block hexutil.Uint64, stakeHash common.Hash, pkr hexutil.Bytes) (hexutil.Bytes, error) {
+	if err := self.auth(token); err != nil {
+		return nil, err
+	}
+	votePKr := c_type.NewPKrByBytes(pkr)
+	if !self.allowed[votePKr] {
+		return nil, errSignerPKr
+	}
+	sign, err := self.journal.signStakeHashOnce(uint64(block), stakeHash, votePKr, func() (c_type.Uint512, error) {
+		return self.signer.Sign(uint64(block), stakeHash, votePKr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	self.prune(uint64(block))
+	return sign[:], nil
+}
+
+// prune drops the journaled signatures journalDepth blocks behind block, at
+// most every tenth of journalDepth blocks.
+func (self *SignerService) prune(block uint64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if block <= journalDepth || block < self.pruned+journalDepth/10 {
+		return
+	}
+	self.pruned = block
+	self.journal.prune(block - journalDepth)
+}
+
+// signerKeyLife is how long RemoteSigner caches the keys held by the signer.
+const signerKeyLife = 10 * time.Minute
+
+type signerKey struct {
+	SignerKey
+	time time.Time
+}
+
+// RemoteSigner signs with a SignerService reached over IPC, HTTP or websocket,
+// so the voting keys never have to be on the node.
+type RemoteSigner struct {
+	client *rpc.Client
+	token  string
+
+	lock sync.Mutex
+	keys map[c_type.PKr]signerKey
+}
+
+func NewRemoteSigner(endpoint string, token string) (*RemoteSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteSigner{client: client, token: token, keys: make(map[c_type.PKr]signerKey)}, nil
+}
+
+func (self *RemoteSigner) Key(pkr c_type.PKr) (common.Hash, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if key, ok := self.keys[pkr]; ok && time.Since(key.time) < signerKeyLife {
+		return key.Key, key.Held
+	}
+	var key SignerKey
+	if err := self.client.Call(&key, "signer_key", self.token, hexutil.Bytes(pkr[:])); err != nil {
+		log.Warn("Remote signer key", "err", err)
+		return common.Hash{}, false
+	}
+	self.keys[pkr] = signerKey{key, time.Now()}
+	return key.Key, key.Held
+}
+
+func (self *RemoteSigner) Sign(block uint64, stakeHash common.Hash, pkr c_type.PKr) (sign c_type.Uint512, err error) {
+	var result hexutil.Bytes
+	if err = self.client.Call(&result, "signer_sign", self.token, hexutil.Uint64(block), stakeHash, hexutil.Bytes(pkr[:])); err != nil {
+		return
+	}
+	if len(result) != len(sign) {
+		return sign, errors.New("invalid remote signature length")
+	}
+	copy(sign[:], result)
+	return
+}
+
+func (self *RemoteSigner) Close() {
+	self.client.Close()
+}
@@ -0,0 +1,187 @@
+package voter
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+type testSigner struct {
+	held  c_type.PKr
+	calls int
+	signs int
+}
+
+func (self *testSigner) Key(pkr c_type.PKr) (common.Hash, bool) {
+	self.calls++
+	if pkr != self.held {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(pkr[:32]), true
+}
+
+func (self *testSigner) Sign(block uint64, stakeHash common.Hash, pkr c_type.PKr) (sign c_type.Uint512, err error) {
+	if pkr != self.held {
+		return sign, errors.New("unknown pkr")
+	}
+	self.signs++
+	copy(sign[:], stakeHash[:])
+	sign[63] = byte(block)
+	return
+}
+
+func TestRemoteSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "votesigner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "votesigner.ipc")
+
+	journal, err := NewVoteJournal(filepath.Join(dir, "journal"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	signer := &testSigner{held: c_type.PKr{1, 2, 3}}
+	listener, server, err := rpc.StartIPCEndpoint(socket, []rpc.API{
+		{Namespace: "signer", Version: "1.0", Service: NewSignerService(signer, "secret", []c_type.PKr{signer.held}, journal)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	defer listener.Close()
+
+	remote, err := NewRemoteSigner(socket, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+
+	key, ok := remote.Key(signer.held)
+	if !ok || key != common.BytesToHash(signer.held[:32]) {
+		t.Errorf("key mismatch: %v %v", key, ok)
+	}
+	if _, ok := remote.Key(c_type.PKr{4}); ok {
+		t.Errorf("key of an unknown pkr")
+	}
+	remote.Key(signer.held)
+	if signer.calls != 1 {
+		t.Errorf("keys not cached: %d calls", signer.calls)
+	}
+
+	stakeHash := common.Hash{9}
+	sign, err := remote.Sign(7, stakeHash, signer.held)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := signer.Sign(7, stakeHash, signer.held); sign != want {
+		t.Errorf("signature mismatch")
+	}
+	if _, err := remote.Sign(7, stakeHash, c_type.PKr{4}); err == nil || err.Error() != errSignerPKr.Error() {
+		t.Errorf("signed for a pkr not allowed: %v", err)
+	}
+
+	intruder, err := NewRemoteSigner(socket, "guess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer intruder.Close()
+	if _, err := intruder.Sign(7, stakeHash, signer.held); err == nil || err.Error() != errSignerToken.Error() {
+		t.Errorf("signed with a wrong token: %v", err)
+	}
+	if _, ok := intruder.Key(signer.held); ok {
+		t.Errorf("key with a wrong token")
+	}
+}
+
+func TestSignerServiceAllowed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "votesigner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	journal, err := NewVoteJournal(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	// The signer holds the key, but the pkr is not allowed
+	signer := &testSigner{held: c_type.PKr{1, 2, 3}}
+	service := NewSignerService(signer, "secret", []c_type.PKr{{4}}, journal)
+	if key, err := service.Key("secret", signer.held[:]); err != nil || key.Held {
+		t.Errorf("key of a pkr not allowed: %v %v", key, err)
+	}
+	if _, err := service.Sign("secret", 7, common.Hash{9}, signer.held[:]); err != errSignerPKr {
+		t.Errorf("signed for a pkr not allowed: %v", err)
+	}
+	if signer.calls != 0 || signer.signs != 0 {
+		t.Errorf("signer reached for a pkr not allowed")
+	}
+}
+
+func TestSignerServiceSignOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "votesigner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	journal, err := NewVoteJournal(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := &testSigner{held: c_type.PKr{1, 2, 3}}
+	service := NewSignerService(signer, "secret", []c_type.PKr{signer.held}, journal)
+	first, err := service.Sign("secret", 7, common.Hash{9}, signer.held[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := service.Sign("secret", 7, common.Hash{9}, signer.held[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.signs != 1 || !bytes.Equal(first, second) {
+		t.Fatalf("stake hash signed %d times", signer.signs)
+	}
+	if _, err := service.Sign("secret", 7, common.Hash{10}, signer.held[:]); err != nil {
+		t.Fatal(err)
+	}
+	if signer.signs != 2 {
+		t.Fatalf("other stake hash not signed")
+	}
+
+	// The guard survives a restart of the signer
+	journal.Close()
+	if journal, err = NewVoteJournal(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	service = NewSignerService(signer, "secret", []c_type.PKr{signer.held}, journal)
+	third, err := service.Sign("secret", 7, common.Hash{9}, signer.held[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.signs != 2 || !bytes.Equal(first, third) {
+		t.Fatalf("stake hash signed again after a restart")
+	}
+
+	journal.prune(8)
+	if _, err := service.Sign("secret", 7, common.Hash{9}, signer.held[:]); err != nil {
+		t.Fatal(err)
+	}
+	if signer.signs != 3 {
+		t.Fatalf("pruned signature not signed again")
+	}
+}
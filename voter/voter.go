@@ -1,14 +1,12 @@
 package voter
 
 import (
+	"fmt"
 	"math/big"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/sero-cash/go-czero-import/c_superzk"
-	"github.com/sero-cash/go-czero-import/superzk"
-
 	"github.com/sero-cash/go-sero/accounts"
 
 	"github.com/sero-cash/go-sero/serodb"
@@ -51,6 +49,7 @@ type blockChain interface {
 }
 
 type Voter struct {
+	config       Config
 	chain        blockChain
 	sero         Backend
 	lotteryCh    chan *types.Lottery
@@ -63,31 +62,61 @@ type Voter struct {
 	lotteryMu sync.RWMutex
 	wg        sync.WaitGroup
 
-	votes    map[common.Hash]time.Time
-	lotterys map[common.Hash]time.Time
+	votes     map[common.Hash]time.Time
+	lotterys  map[common.Hash]time.Time
+	poolVotes map[shareVoteKey]time.Time
+	failovers map[shareVoteKey]time.Time
 
 	stopLottery  chan struct{}
 	stopVote     chan struct{}
 	lotteryQueue *PriorityQueue
+	journal      *VoteJournal
+	signer       VoteSigner
 	running      int32
 }
 
-func NewVoter(chainconfig *params.ChainConfig, chain blockChain, sero Backend) *Voter {
+// NewVoter creates the voter, it refuses to vote without the vote journal or
+// the remote signer when they are configured.
+func NewVoter(config Config, chainconfig *params.ChainConfig, chain blockChain, sero Backend) (*Voter, error) {
 	// Sanitize the input to ensure no vulnerable gas prices are set
 
 	// Create the transaction pool with its initial settings
 	voter := &Voter{
+		config:       config,
 		sero:         sero,
 		chain:        chain,
 		lotteryCh:    make(chan *types.Lottery, chainLotterySize),
 		votes:        make(map[common.Hash]time.Time),
 		lotterys:     make(map[common.Hash]time.Time),
+		poolVotes:    make(map[shareVoteKey]time.Time),
+		failovers:    make(map[shareVoteKey]time.Time),
 		lotteryQueue: &PriorityQueue{},
 		stopLottery:  make(chan struct{}),
 		stopVote:     make(chan struct{}),
 	}
 	voter.lotteryQueue.Init(lotteryQueueSize)
 
+	if config.Journal != "" {
+		journal, err := NewVoteJournal(config.Journal, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open vote journal: %v", err)
+		}
+		voter.journal = journal
+	}
+
+	voter.signer = NewKeystoreSigner(sero.AccountManager())
+	if config.Signer != "" {
+		signer, err := NewRemoteSigner(config.Signer, config.SignerToken)
+		if err != nil {
+			if voter.journal != nil {
+				voter.journal.Close()
+			}
+			return nil, fmt.Errorf("failed to connect the remote vote signer %s: %v", config.Signer, err)
+		}
+		log.Info("Voting with the remote signer", "endpoint", config.Signer)
+		voter.signer = signer
+	}
+
 	// Subscribe events from blockchain
 	//voter.chainHeadSub = voter.chain.SubscribeChainHeadEvent(voter.chainHeadCh)
 
@@ -96,7 +125,7 @@ func NewVoter(chainconfig *params.ChainConfig, chain blockChain, sero Backend) *
 	go voter.lotteryTaskLoop()
 	go voter.voteLoop()
 
-	return voter
+	return voter, nil
 }
 
 func (self *Voter) loop() {
@@ -127,7 +156,20 @@ func (self *Voter) loop() {
 			for _, h := range dropVotes {
 				delete(self.votes, h)
 			}
+			for k, v := range self.poolVotes {
+				if time.Since(v) > lifeTime {
+					delete(self.poolVotes, k)
+				}
+			}
+			for k, v := range self.failovers {
+				if time.Since(v) > lifeTime {
+					delete(self.failovers, k)
+				}
+			}
 			self.voteMu.Unlock()
+			if current := self.chain.CurrentBlock().NumberU64(); self.journal != nil && current > journalDepth {
+				self.journal.prune(current - journalDepth)
+			}
 		}
 	}
 }
@@ -164,7 +206,7 @@ func (self *Voter) lotteryTaskLoop() {
 					log.Error("lotteryTaskLoop", "selfShare error ", err)
 				} else {
 					for _, s := range selfShares {
-						go self.sign(s)
+						go self.vote(s)
 					}
 				}
 			}
@@ -202,7 +244,7 @@ func (self *Voter) voteLoop() {
 					log.Trace("lotteryTaskLoop", "selfShare error ", err)
 				} else {
 					for _, s := range selfShares {
-						self.sign(s)
+						self.vote(s)
 					}
 				}
 
@@ -222,7 +264,8 @@ type voteInfo struct {
 	stakeHash common.Hash
 	votePKr   c_type.PKr
 	isPool    bool
-	seed      address.Seed
+	key       common.Hash
+	failover  bool
 }
 
 func cotainsVoteInfo(voteInfos []voteInfo, item voteInfo, pool *stake.StakePool) bool {
@@ -230,7 +273,7 @@ func cotainsVoteInfo(voteInfos []voteInfo, item voteInfo, pool *stake.StakePool)
 		return false
 	}
 	for _, v := range voteInfos {
-		if v.seed == item.seed && v.index == item.index &&
+		if v.key == item.key && v.index == item.index &&
 			v.shareHash == v.shareHash && v.poshash == item.poshash &&
 			v.parentNum == item.parentNum {
 			return true
@@ -298,7 +341,6 @@ func (self *Voter) SelfShares(poshash common.Hash, parent common.Hash, parentNum
 		var voteInfos []voteInfo
 		if len(ints) > 0 {
 			parentPos := parentHeader.HashPos()
-			wallets := self.sero.AccountManager().Wallets()
 			for i, share := range shares {
 				var pool *stake.StakePool
 				if share.PoolId != nil {
@@ -307,10 +349,11 @@ func (self *Voter) SelfShares(poshash common.Hash, parent common.Hash, parentNum
 						log.Error("lotteryTaskLoop", "GetStakePool", share.PoolId, "note exist")
 					}
 				}
+				poolVoter := false
 				if pool != nil {
 					stakeHash := types.StakeHash(&poshash, &parentPos, true)
-					seed := GetSeedByVotePkr(wallets, pool.VotePKr)
-					if seed != nil {
+					if key, ok := self.signer.Key(pool.VotePKr); ok {
+						poolVoter = true
 						voteInfos = append(voteInfos, voteInfo{
 							ints[i],
 							parentNumber.Uint64(),
@@ -319,11 +362,11 @@ func (self *Voter) SelfShares(poshash common.Hash, parent common.Hash, parentNum
 							stakeHash,
 							pool.VotePKr,
 							true,
-							*seed})
+							key,
+							false})
 					}
 				}
-				shareVoteSeed := GetSeedByVotePkr(wallets, share.VotePKr)
-				if shareVoteSeed != nil {
+				if key, ok := self.signer.Key(share.VotePKr); ok {
 					stakeHash := types.StakeHash(&poshash, &parentPos, false)
 					info := voteInfo{
 						ints[i],
@@ -333,7 +376,8 @@ func (self *Voter) SelfShares(poshash common.Hash, parent common.Hash, parentNum
 						stakeHash,
 						share.VotePKr,
 						false,
-						*shareVoteSeed}
+						key,
+						self.config.Failover && pool != nil && !pool.Closed && !poolVoter}
 					if cotainsVoteInfo(voteInfos, info, pool) {
 						continue
 					} else {
@@ -348,21 +392,33 @@ func (self *Voter) SelfShares(poshash common.Hash, parent common.Hash, parentNum
 
 }
 
+func (self *Voter) signVote(info voteInfo) (*types.Vote, error) {
+	sign, err := self.signer.Sign(info.parentNum+1, info.stakeHash, info.votePKr)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Vote{info.index, info.parentNum, info.shareHash, info.poshash, info.isPool, sign}, nil
+}
+
 func (self *Voter) sign(info voteInfo) {
-	data := c_type.Uint256{}
-	copy(data[:], info.stakeHash[:])
-	version := 1
-	if c_superzk.IsSzkPKr(&info.votePKr) {
-		version = 2
+	var vote *types.Vote
+	var err error
+	if self.journal != nil {
+		var signed bool
+		vote, signed, err = self.journal.signOnce(info, func() (*types.Vote, error) {
+			return self.signVote(info)
+		})
+		if err == nil && !signed {
+			log.Info("voter vote already signed, resend journaled vote", "poshash", info.poshash, "block", info.parentNum+1, "share", info.shareHash, "idx", info.index, "isPool", info.isPool)
+		}
+	} else {
+		vote, err = self.signVote(info)
 	}
-	sk := superzk.Seed2Sk(info.seed.SeedToUint256(), version)
-	sign, err := superzk.SignPKr_ByHeight(info.parentNum+1, &sk, &data, &info.votePKr)
 	if err != nil {
 		log.Error("voter sign", "sign err", err)
 		return
 	}
 	log.Info(">>>>>>>>>>>>>sign vote", "poshas", info.poshash, "block", info.parentNum+1, "share", info.shareHash, "idx", info.index, "isPool", info.isPool)
-	vote := &types.Vote{info.index, info.parentNum, info.shareHash, info.poshash, info.isPool, sign}
 	//go self.voteWorkFeed.Send(core.NewVoteEvent{vote})
 	self.AddVote(vote)
 }
@@ -430,6 +486,9 @@ func (self *Voter) AddVote(vote *types.Vote) {
 		go self.voteWorkFeed.Send(core.NewVoteEvent{vote})
 		self.SendVoteEvent(vote)
 		self.votes[vote.Hash()] = time.Now()
+		if vote.IsPool && self.config.Failover {
+			go self.checkPoolVote(vote)
+		}
 	}
 }
 
@@ -440,4 +499,10 @@ func (self *Voter) Close() {
 	close(self.stopLottery)
 	close(self.stopVote)
 	self.wg.Wait()
+	if self.journal != nil {
+		self.journal.Close()
+	}
+	if remote, ok := self.signer.(*RemoteSigner); ok {
+		remote.Close()
+	}
 }
@@ -0,0 +1,365 @@
+package proofservice
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+const defaultWorkerTimeout = 30 * time.Second
+
+// defaultProveTimeout is how long a worker has to submit the result of a job
+// before the job is handed out again.
+const defaultProveTimeout = 10 * time.Minute
+
+// workerAuthHeader carries the secret of the service in the requests of the
+// prover workers.
+const workerAuthHeader = "Authorization"
+
+var (
+	errWorkerAuth     = errors.New("invalid worker secret")
+	errUnknownWorker  = errors.New("unknown worker")
+	errNotAssigned    = errors.New("job is not assigned to the worker")
+	errWrongResult    = errors.New("result is not the transaction of the job")
+	errNoCoordination = errors.New("proof service has no prover workers")
+)
+
+// ProofWork is a job handed to a prover worker.
+type ProofWork struct {
+	Hash  common.Hash
+	Tx    *stx.T
+	Param *txtool.GTxParam
+}
+
+// WorkerStats is the throughput of a prover worker.
+type WorkerStats struct {
+	Id         string    `json:"id"`
+	Name       string    `json:"name"`
+	Registered time.Time `json:"registered"`
+	LastSeen   time.Time `json:"lastSeen"`
+	Assigned   int       `json:"assigned"`
+	Proved     uint64    `json:"proved"`
+	Failed     uint64    `json:"failed"`
+	AvgProveMs uint64    `json:"avgProveMs"`
+	// Throughput is the proved jobs per minute since the registration.
+	Throughput float64 `json:"throughput"`
+}
+
+type assignment struct {
+	job   *Job
+	start time.Time
+}
+
+type worker struct {
+	stats     WorkerStats
+	proveTime time.Duration
+	jobs      map[common.Hash]*assignment
+}
+
+// coordinator dispatches the jobs of the service to the registered prover
+// workers. The jobs stay in the bounded queue of the service until a worker
+// fetches them, the jobs of a worker missing its heartbeats or not submitted
+// in time are handed out again before the queue.
+type coordinator struct {
+	proof        *ProofService
+	timeout      time.Duration
+	proveTimeout time.Duration
+
+	lock    sync.Mutex
+	lost    []*Job
+	workers map[string]*worker
+}
+
+func newCoordinator(proof *ProofService, timeout time.Duration, proveTimeout time.Duration) *coordinator {
+	if timeout <= 0 {
+		timeout = defaultWorkerTimeout
+	}
+	if proveTimeout <= 0 {
+		proveTimeout = defaultProveTimeout
+	}
+	return &coordinator{
+		proof:        proof,
+		timeout:      timeout,
+		proveTimeout: proveTimeout,
+		workers:      make(map[string]*worker),
+	}
+}
+
+// next returns the next job to prove, nil if there is none, the lock must be
+// held.
+func (self *coordinator) next() *Job {
+	if len(self.lost) > 0 {
+		job := self.lost[0]
+		self.lost = self.lost[1:]
+		return job
+	}
+	select {
+	case job := <-self.proof.queueChan:
+		return job
+	default:
+		return nil
+	}
+}
+
+func (self *coordinator) register(name string) string {
+	var id [16]byte
+	rand.Read(id[:])
+	now := time.Now()
+	w := &worker{
+		stats: WorkerStats{Id: hexutil.Encode(id[:]), Name: name, Registered: now, LastSeen: now},
+		jobs:  make(map[common.Hash]*assignment),
+	}
+	self.lock.Lock()
+	self.workers[w.stats.Id] = w
+	self.lock.Unlock()
+	log.Info("ProofService worker registered", "id", w.stats.Id, "name", name)
+	return w.stats.Id
+}
+
+// seen returns the worker and records its heartbeat, the lock must be held.
+func (self *coordinator) seen(id string) (*worker, error) {
+	w, ok := self.workers[id]
+	if !ok {
+		return nil, errUnknownWorker
+	}
+	w.stats.LastSeen = time.Now()
+	return w, nil
+}
+
+func (self *coordinator) heartbeat(id string) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	_, err := self.seen(id)
+	return err
+}
+
+func (self *coordinator) fetch(id string) (*ProofWork, error) {
+	self.lock.Lock()
+	w, err := self.seen(id)
+	if err != nil {
+		self.lock.Unlock()
+		return nil, err
+	}
+	job := self.next()
+	if job == nil {
+		self.lock.Unlock()
+		return nil, nil
+	}
+	if err := self.proof.setState(job, JobProving); err != nil {
+		self.lock.Unlock()
+		return nil, err
+	}
+	w.jobs[job.Hash] = &assignment{job, time.Now()}
+	self.lock.Unlock()
+	return &ProofWork{job.Hash, job.tx, job.param}, nil
+}
+
+func (self *coordinator) submit(id string, hash common.Hash, gtx *txtool.GTx, reason string) error {
+	self.lock.Lock()
+	w, err := self.seen(id)
+	if err != nil {
+		self.lock.Unlock()
+		return err
+	}
+	a, ok := w.jobs[hash]
+	if !ok {
+		self.lock.Unlock()
+		return errNotAssigned
+	}
+	delete(w.jobs, hash)
+	if reason == "" && gtx != nil {
+		// A result of another transaction is not committed, the job is
+		// handed out again
+		if txHash := gtx.Tx.Tx1_Hash(); common.BytesToHash(txHash[:]) != a.job.Hash {
+			w.stats.Failed++
+			log.Warn("ProofService worker result mismatch", "id", id, "hash", hash)
+			self.requeue([]*Job{a.job})
+			self.lock.Unlock()
+			return errWrongResult
+		}
+		w.stats.Proved++
+		w.proveTime += time.Since(a.start)
+	} else {
+		w.stats.Failed++
+	}
+	self.lock.Unlock()
+
+	if reason != "" {
+		err = errors.New(reason)
+	} else if gtx == nil {
+		err = errors.New("worker returned no transaction")
+	}
+	self.proof.commitJob(a.job, gtx, err)
+	return nil
+}
+
+// reap drops the workers missing their heartbeats and hands out their jobs,
+// and the jobs not submitted in time, again before the queue.
+func (self *coordinator) reap() {
+	var lost []*Job
+	self.lock.Lock()
+	now := time.Now()
+	deadline, proveDeadline := now.Add(-self.timeout), now.Add(-self.proveTimeout)
+	for id, w := range self.workers {
+		if !w.stats.LastSeen.After(deadline) {
+			log.Warn("ProofService worker lost", "id", id, "name", w.stats.Name, "jobs", len(w.jobs))
+			for _, a := range w.jobs {
+				lost = append(lost, a.job)
+			}
+			delete(self.workers, id)
+			continue
+		}
+		for hash, a := range w.jobs {
+			if a.start.After(proveDeadline) {
+				continue
+			}
+			log.Warn("ProofService job timed out", "id", id, "name", w.stats.Name, "hash", hash)
+			lost = append(lost, a.job)
+			delete(w.jobs, hash)
+			w.stats.Failed++
+		}
+	}
+	self.requeue(lost)
+	self.lock.Unlock()
+}
+
+// requeue hands out the jobs again before the queue, the lock must be held.
+func (self *coordinator) requeue(jobs []*Job) {
+	sortJobs(jobs)
+	for _, job := range jobs {
+		if err := self.proof.setState(job, JobQueued); err == nil {
+			self.lost = append(self.lost, job)
+		}
+	}
+}
+
+func (self *coordinator) loop(quit chan struct{}) {
+	ticker := time.NewTicker(self.timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			self.reap()
+		}
+	}
+}
+
+func (self *coordinator) stats() (stats []WorkerStats) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, w := range self.workers {
+		s := w.stats
+		s.Assigned = len(w.jobs)
+		if s.Proved > 0 {
+			s.AvgProveMs = uint64(w.proveTime/time.Millisecond) / s.Proved
+		}
+		if minutes := time.Since(s.Registered).Minutes(); minutes > 0 {
+			s.Throughput = float64(s.Proved) / minutes
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Registered.Before(stats[j].Registered) })
+	return
+}
+
+// WorkerAPI is the rpc api of the prover workers, it is served on its own
+// endpoint by StartWorkerEndpoint.
+type WorkerAPI struct {
+	proof *ProofService
+}
+
+func NewWorkerAPI(proof *ProofService) *WorkerAPI {
+	return &WorkerAPI{proof}
+}
+
+func (api *WorkerAPI) coordinator() (*coordinator, error) {
+	if api.proof.coordinator == nil {
+		return nil, errNoCoordination
+	}
+	return api.proof.coordinator, nil
+}
+
+// RegisterWorker registers a prover worker and returns its id.
+func (api *WorkerAPI) RegisterWorker(name string) (string, error) {
+	c, err := api.coordinator()
+	if err != nil {
+		return "", err
+	}
+	return c.register(name), nil
+}
+
+// Heartbeat keeps the jobs of the worker assigned to it.
+func (api *WorkerAPI) Heartbeat(id string) error {
+	c, err := api.coordinator()
+	if err != nil {
+		return err
+	}
+	return c.heartbeat(id)
+}
+
+// FetchWork assigns the next queued job to the worker, nil if there is none.
+func (api *WorkerAPI) FetchWork(id string) (*ProofWork, error) {
+	c, err := api.coordinator()
+	if err != nil {
+		return nil, err
+	}
+	return c.fetch(id)
+}
+
+// SubmitResult commits the transaction proved by the worker, or fails the job
+// with the reason.
+func (api *WorkerAPI) SubmitResult(id string, hash common.Hash, gtx *txtool.GTx, reason string) error {
+	c, err := api.coordinator()
+	if err != nil {
+		return err
+	}
+	return c.submit(id, hash, gtx, reason)
+}
+
+// NewWorkerServer returns the rpc server of the prover workers, it only
+// serves the requests carrying the secret of the service.
+func NewWorkerServer(proof *ProofService, secret string) (*rpc.Server, http.Handler, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("prover", NewWorkerAPI(proof)); err != nil {
+		return nil, nil, err
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(workerAuthHeader)), []byte(secret)) != 1 {
+			http.Error(w, errWorkerAuth.Error(), http.StatusUnauthorized)
+			return
+		}
+		server.ServeHTTP(w, r)
+	})
+	return server, handler, nil
+}
+
+// StartWorkerEndpoint starts the HTTP endpoint of the prover workers, apart
+// from the public endpoint of the service.
+func StartWorkerEndpoint(endpoint string, proof *ProofService, secret string, timeouts rpc.HTTPTimeouts) (net.Listener, *rpc.Server, error) {
+	server, handler, err := NewWorkerServer(proof, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	httpServer := rpc.NewHTTPServer(nil, []string{"*"}, timeouts, server)
+	httpServer.Handler = handler
+	go httpServer.Serve(listener)
+	return listener, server, nil
+}
@@ -0,0 +1,256 @@
+package proofservice
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+type testClient struct {
+	lock      sync.Mutex
+	committed int
+}
+
+func (self *testClient) CheckNils(nils []c_type.Uint256) bool {
+	return true
+}
+
+func (self *testClient) CommitTx(tx *txtool.GTx) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.committed++
+	return nil
+}
+
+func (self *testClient) count() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.committed
+}
+
+func testProver(tx *stx.T, param *txtool.GTxParam) (txtool.GTx, error) {
+	time.Sleep(10 * time.Millisecond)
+	if tx.Ehash == (c_type.Uint256{0xff}) {
+		return txtool.GTx{}, errors.New("prove failed")
+	}
+	return txtool.GTx{Tx: *tx, Hash: tx.Ehash}, nil
+}
+
+func newTestCoordinator(t *testing.T, timeout time.Duration) (*ProofService, *testClient, string, func()) {
+	zero := big.NewInt(0)
+	proof, err := NewProofService("", nil, &Config{
+		MaxWorkNumber:  5,
+		MaxQueueNumber: 20,
+		Fee:            ServiceFee{zero, zero, zero, zero},
+		WorkerSecret:   "secret",
+		WorkerTimeout:  timeout,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &testClient{}
+	proof.client = client
+
+	server, handler, err := NewWorkerServer(proof, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(handler)
+	return proof, client, srv.URL, func() {
+		srv.Close()
+		server.Stop()
+		proof.Stop()
+	}
+}
+
+func newTestWorker(t *testing.T, url string, name string) *Worker {
+	w, err := NewWorker(url, "secret", name, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.prove = testProver
+	w.heartbeat = 50 * time.Millisecond
+	w.poll = 20 * time.Millisecond
+	return w
+}
+
+func submitTestJob(proof *ProofService, n byte) *Job {
+	tx := &stx.T{Ehash: c_type.Uint256{n}, From: c_type.PKr{n}}
+	hash := tx.Tx1_Hash()
+	job := newJob(common.BytesToHash(hash[:]), tx, &txtool.GTxParam{})
+	proof.storage.Save(job)
+	proof.queueChan <- job
+	return job
+}
+
+func waitJobs(t *testing.T, proof *ProofService, jobs []*Job) {
+	deadline := time.Now().Add(10 * time.Second)
+	for _, job := range jobs {
+		for {
+			if status, err := proof.JobStatus(job.Hash); err == nil && (status.State == JobCommitted || status.State == JobFailed) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("job %x not finished", job.Hash)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestCoordinatorWorkers(t *testing.T) {
+	proof, client, url, stop := newTestCoordinator(t, time.Second)
+	defer stop()
+
+	intruder, err := NewWorker(url, "wrong", "intruder", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := intruder.Start(); err == nil || !strings.Contains(err.Error(), errWorkerAuth.Error()) {
+		t.Fatalf("worker with a wrong secret registered: %v", err)
+	}
+
+	workers := []*Worker{newTestWorker(t, url, "w1"), newTestWorker(t, url, "w2")}
+	for _, w := range workers {
+		if err := w.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer w.Stop()
+	}
+
+	var jobs []*Job
+	for i := 1; i <= 10; i++ {
+		jobs = append(jobs, submitTestJob(proof, byte(i)))
+	}
+	jobs = append(jobs, submitTestJob(proof, 0xff))
+	waitJobs(t, proof, jobs)
+
+	for _, job := range jobs[:10] {
+		if status, _ := proof.JobStatus(job.Hash); status.State != JobCommitted {
+			t.Errorf("job %x state: have %s, want %s", job.Hash, status.State, JobCommitted)
+		}
+	}
+	if status, _ := proof.JobStatus(jobs[10].Hash); status.State != JobFailed || status.Reason != "prove failed" {
+		t.Errorf("failed job mismatch: have %s %q", status.State, status.Reason)
+	}
+	if committed := client.count(); committed != 10 {
+		t.Errorf("committed transactions: have %d, want 10", committed)
+	}
+
+	stats := proof.Workers()
+	if len(stats) != 2 {
+		t.Fatalf("workers: have %d, want 2", len(stats))
+	}
+	var proved, failed uint64
+	for _, s := range stats {
+		proved += s.Proved
+		failed += s.Failed
+	}
+	if proved != 10 || failed != 1 {
+		t.Errorf("worker stats mismatch: have %d proved %d failed", proved, failed)
+	}
+}
+
+func TestCoordinatorReassign(t *testing.T) {
+	proof, _, url, stop := newTestCoordinator(t, 200*time.Millisecond)
+	defer stop()
+
+	// A worker fetching a job and never coming back.
+	client, err := rpc.DialHTTPWithClient(url, &http.Client{Transport: &authTransport{"secret", http.DefaultTransport}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var id string
+	if err := client.Call(&id, "prover_registerWorker", "lost"); err != nil {
+		t.Fatal(err)
+	}
+	job := submitTestJob(proof, 1)
+	var work *ProofWork
+	for work == nil {
+		if err := client.Call(&work, "prover_fetchWork", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if status, _ := proof.JobStatus(job.Hash); status.State != JobProving {
+		t.Fatalf("fetched job state: have %s, want %s", status.State, JobProving)
+	}
+
+	w := newTestWorker(t, url, "healthy")
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	waitJobs(t, proof, []*Job{job})
+
+	if status, _ := proof.JobStatus(job.Hash); status.State != JobCommitted {
+		t.Fatalf("reassigned job state: have %s, want %s", status.State, JobCommitted)
+	}
+	if err := client.Call(nil, "prover_submitResult", id, job.Hash, &txtool.GTx{}, ""); err == nil {
+		t.Fatalf("lost worker submitted a reassigned job")
+	}
+	if stats := proof.Workers(); len(stats) != 1 || stats[0].Name != "healthy" || stats[0].Proved != 1 {
+		t.Fatalf("worker stats mismatch: %+v", stats)
+	}
+}
+
+func TestCoordinatorDeadline(t *testing.T) {
+	proof, client, _, stop := newTestCoordinator(t, time.Minute)
+	defer stop()
+	c := proof.coordinator
+
+	// A job not submitted in time is handed out again, its worker stays
+	id := c.register("slow")
+	job := submitTestJob(proof, 1)
+	work, err := c.fetch(id)
+	if err != nil || work == nil || work.Hash != job.Hash {
+		t.Fatalf("fetched work mismatch: %v %v", work, err)
+	}
+	c.lock.Lock()
+	c.workers[id].jobs[job.Hash].start = time.Now().Add(-2 * defaultProveTimeout)
+	c.lock.Unlock()
+	c.reap()
+	if status, _ := proof.JobStatus(job.Hash); status.State != JobQueued {
+		t.Fatalf("timed out job state: have %s, want %s", status.State, JobQueued)
+	}
+	if stats := proof.Workers(); len(stats) != 1 || stats[0].Assigned != 0 || stats[0].Failed != 1 {
+		t.Fatalf("worker stats mismatch: %+v", stats)
+	}
+	if err := c.submit(id, job.Hash, &txtool.GTx{Tx: *job.tx}, ""); err != errNotAssigned {
+		t.Fatalf("late result mismatch: have %v, want %v", err, errNotAssigned)
+	}
+
+	// A result of another transaction is refused and the job handed out again
+	if work, err = c.fetch(id); err != nil || work == nil || work.Hash != job.Hash {
+		t.Fatalf("refetched work mismatch: %v %v", work, err)
+	}
+	other := &stx.T{Ehash: c_type.Uint256{2}, From: c_type.PKr{2}}
+	if err := c.submit(id, job.Hash, &txtool.GTx{Tx: *other}, ""); err != errWrongResult {
+		t.Fatalf("wrong result mismatch: have %v, want %v", err, errWrongResult)
+	}
+	if status, _ := proof.JobStatus(job.Hash); status.State != JobQueued {
+		t.Fatalf("refused job state: have %s, want %s", status.State, JobQueued)
+	}
+
+	// The result of the transaction is committed
+	if work, err = c.fetch(id); err != nil || work == nil {
+		t.Fatalf("refetched work mismatch: %v %v", work, err)
+	}
+	if err := c.submit(id, job.Hash, &txtool.GTx{Tx: *job.tx}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := proof.JobStatus(job.Hash); status.State != JobCommitted || client.count() != 1 {
+		t.Fatalf("submitted job mismatch: have %s, %d committed", status.State, client.count())
+	}
+}
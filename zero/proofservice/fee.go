@@ -0,0 +1,171 @@
+package proofservice
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+const feeCurrency = "SERO"
+
+// FeeSchedule is the fee of the service in SERO and the other currencies it
+// accepts for it, a rate is the units of the currency paying a unit of SERO.
+type FeeSchedule struct {
+	PKr      c_type.PKr        `json:"pkr"`
+	ZinFee   *hexutil.Big      `json:"zinFee"`
+	OinFee   *hexutil.Big      `json:"oinFee"`
+	OutFee   *hexutil.Big      `json:"outFee"`
+	FixedFee *hexutil.Big      `json:"fixedFee"`
+	Rates    map[string]string `json:"rates"`
+}
+
+// ParseRates parses the comma separated CURRENCY=rate list of the currencies
+// accepted for the fee, a rate is a decimal or a fraction.
+func ParseRates(s string) (map[string]*big.Rat, error) {
+	rates := make(map[string]*big.Rat)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid fee rate %q, want CURRENCY=rate", item)
+		}
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(kv[1]))
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid fee rate %q", item)
+		}
+		rates[strings.ToUpper(strings.TrimSpace(kv[0]))] = rate
+	}
+	return rates, nil
+}
+
+func orZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+// rate returns the rate of the currency, SERO is always accepted.
+func (proof *ProofService) rate(currency string) (*big.Rat, bool) {
+	currency = strings.ToUpper(currency)
+	if rate, ok := proof.config.Rates[currency]; ok {
+		return rate, true
+	}
+	if currency == feeCurrency {
+		return big.NewRat(1, 1), true
+	}
+	return nil, false
+}
+
+// fee returns the fee in SERO of proving the transaction, the outputs paying
+// the service are not charged.
+func (proof *ProofService) fee(param *txtool.GTxParam) (*big.Int, error) {
+	fees := proof.config.Fee
+	if fixed := orZero(fees.FixedFee); fixed.Sign() > 0 {
+		return fixed, nil
+	}
+	fee := new(big.Int)
+	for _, in := range param.Ins {
+		if in.Out.State.OS.Out_P != nil {
+			fee.Add(fee, orZero(fees.ZinFee))
+		} else if in.Out.State.OS.Out_C != nil {
+			fee.Add(fee, orZero(fees.OinFee))
+		} else {
+			return nil, fmt.Errorf("input %s can not be proved", hexutil.Encode(in.Out.Root[:]))
+		}
+	}
+	for _, out := range param.Outs {
+		if out.PKr != proof.config.PKr {
+			fee.Add(fee, orZero(fees.OutFee))
+		}
+	}
+	return fee, nil
+}
+
+// feeIn converts the fee in SERO to the currency of the rate, rounding up.
+func feeIn(fee *big.Int, rate *big.Rat) *big.Int {
+	amount := new(big.Rat).Mul(new(big.Rat).SetInt(fee), rate)
+	q, m := new(big.Int).QuoRem(amount.Num(), amount.Denom(), new(big.Int))
+	if m.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// checkFee checks an output of the transaction pays the fee to the service in
+// an accepted currency.
+func (proof *ProofService) checkFee(param *txtool.GTxParam) error {
+	fee, err := proof.fee(param)
+	if err != nil {
+		return err
+	}
+	if fee.Sign() == 0 {
+		return nil
+	}
+	for _, out := range param.Outs {
+		if out.PKr != proof.config.PKr || out.Asset.Tkn == nil {
+			continue
+		}
+		rate, ok := proof.rate(utils.Uint256ToCurrency(&out.Asset.Tkn.Currency))
+		if !ok {
+			continue
+		}
+		if out.Asset.Tkn.Value.ToInt().Cmp(feeIn(fee, rate)) >= 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("fee not paid: the service needs %s ta of SERO or of an accepted currency at its rate", fee)
+}
+
+// Quote returns the output paying the fee of the transaction in the currency,
+// the output is added to the outputs of the transaction. It is nil if the
+// service needs no fee.
+func (proof *ProofService) Quote(param *txtool.GTxParam, currency string) (*txtool.GOut, error) {
+	if currency == "" {
+		currency = feeCurrency
+	}
+	rate, ok := proof.rate(currency)
+	if !ok {
+		return nil, fmt.Errorf("currency %s is not accepted for the fee", currency)
+	}
+	fee, err := proof.fee(param)
+	if err != nil {
+		return nil, err
+	}
+	if fee.Sign() == 0 {
+		return nil, nil
+	}
+	amount := feeIn(fee, rate)
+	return &txtool.GOut{
+		PKr: proof.config.PKr,
+		Asset: assets.Asset{Tkn: &assets.Token{
+			Currency: utils.CurrencyToUint256(currency),
+			Value:    utils.U256(*amount),
+		}},
+	}, nil
+}
+
+func (proof *ProofService) FeeSchedule() *FeeSchedule {
+	fees := proof.config.Fee
+	schedule := &FeeSchedule{
+		PKr:      proof.config.PKr,
+		ZinFee:   (*hexutil.Big)(orZero(fees.ZinFee)),
+		OinFee:   (*hexutil.Big)(orZero(fees.OinFee)),
+		OutFee:   (*hexutil.Big)(orZero(fees.OutFee)),
+		FixedFee: (*hexutil.Big)(orZero(fees.FixedFee)),
+		Rates:    map[string]string{feeCurrency: "1"},
+	}
+	for currency, rate := range proof.config.Rates {
+		schedule.Rates[currency] = rate.RatString()
+	}
+	return schedule
+}
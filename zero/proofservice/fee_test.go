@@ -0,0 +1,92 @@
+package proofservice
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/zero/localdb"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/stx/stx_v1"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func feeOut(pkr c_type.PKr, currency string, value int64) txtool.GOut {
+	return txtool.GOut{
+		PKr: pkr,
+		Asset: assets.Asset{Tkn: &assets.Token{
+			Currency: utils.CurrencyToUint256(currency),
+			Value:    utils.U256(*big.NewInt(value)),
+		}},
+	}
+}
+
+func TestFee(t *testing.T) {
+	rates, err := ParseRates("abc=3/2, XYZ=0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseRates("abc=-1"); err == nil {
+		t.Fatalf("negative rate accepted")
+	}
+	service := c_type.PKr{1}
+	proof := &ProofService{config: &Config{
+		PKr:   service,
+		Fee:   ServiceFee{ZinFee: big.NewInt(10), OinFee: big.NewInt(20), OutFee: big.NewInt(5)},
+		Rates: rates,
+	}}
+
+	param := &txtool.GTxParam{
+		Ins: []txtool.GIn{
+			{Out: txtool.Out{State: localdb.RootState{OS: localdb.OutState{Out_P: &stx_v1.Out_P{}}}}},
+			{Out: txtool.Out{State: localdb.RootState{OS: localdb.OutState{Out_C: &stx_v1.Out_C{}}}}},
+		},
+		Outs: []txtool.GOut{feeOut(c_type.PKr{2}, "SERO", 100), feeOut(c_type.PKr{3}, "SERO", 100)},
+	}
+	if err := proof.checkFee(param); err == nil {
+		t.Fatalf("transaction without fee accepted")
+	}
+
+	for _, test := range []struct {
+		currency string
+		value    int64
+	}{{"", 40}, {"SERO", 40}, {"abc", 60}, {"XYZ", 20}} {
+		out, err := proof.Quote(param, test.currency)
+		if err != nil {
+			t.Fatalf("quote %q: %v", test.currency, err)
+		}
+		if out.PKr != service || out.Asset.Tkn.Value.ToInt().Int64() != test.value {
+			t.Fatalf("quote %q: have %d, want %d", test.currency, out.Asset.Tkn.Value.ToInt(), test.value)
+		}
+		paid := *param
+		paid.Outs = append(append([]txtool.GOut{}, param.Outs...), *out)
+		if err := proof.checkFee(&paid); err != nil {
+			t.Errorf("quoted fee in %q rejected: %v", test.currency, err)
+		}
+		paid.Outs[len(paid.Outs)-1].Asset.Tkn.Value = utils.U256(*big.NewInt(test.value - 1))
+		if err := proof.checkFee(&paid); err == nil {
+			t.Errorf("underpaid fee in %q accepted", test.currency)
+		}
+	}
+	if _, err := proof.Quote(param, "NOPE"); err == nil {
+		t.Errorf("quote in an unaccepted currency")
+	}
+	paid := *param
+	paid.Outs = append(append([]txtool.GOut{}, param.Outs...), feeOut(service, "NOPE", 1000))
+	if err := proof.checkFee(&paid); err == nil {
+		t.Errorf("fee in an unaccepted currency accepted")
+	}
+
+	proof.config.Fee.FixedFee = big.NewInt(7)
+	if out, _ := proof.Quote(param, "abc"); out.Asset.Tkn.Value.ToInt().Int64() != 11 {
+		t.Errorf("fixed fee quote: have %d, want 11", out.Asset.Tkn.Value.ToInt())
+	}
+	proof.config.Fee = ServiceFee{}
+	if out, err := proof.Quote(param, ""); out != nil || err != nil {
+		t.Errorf("quote of a free service: %v %v", out, err)
+	}
+	if err := proof.checkFee(param); err != nil {
+		t.Errorf("free service rejected the transaction: %v", err)
+	}
+}
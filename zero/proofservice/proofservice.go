@@ -23,17 +23,88 @@ type ServiceFee struct {
 	FixedFee *big.Int
 }
 
+// The states of a job, a queued or proving job is queued again when the
+// service restarts.
+const (
+	JobQueued    = "queued"
+	JobProving   = "proving"
+	JobCommitted = "committed"
+	JobFailed    = "failed"
+)
+
+// jobRetention is how long a finished job is kept for its status.
+const jobRetention = 2 * time.Hour
+
 type Job struct {
+	lock sync.RWMutex
+
+	Hash      common.Hash
 	TxHash    common.Hash
 	Timestamp time.Time
+	Updated   time.Time
+	State     string
 	Error     error
 
 	tx    *stx.T
 	param *txtool.GTxParam
 }
 
-func newJob(tx *stx.T, param *txtool.GTxParam) *Job {
-	return &Job{tx: tx, param: param}
+func newJob(hash common.Hash, tx *stx.T, param *txtool.GTxParam) *Job {
+	now := time.Now()
+	return &Job{Hash: hash, Timestamp: now, Updated: now, State: JobQueued, tx: tx, param: param}
+}
+
+func finishedState(state string) bool {
+	return state == JobCommitted || state == JobFailed
+}
+
+func (job *Job) finished() bool {
+	job.lock.RLock()
+	defer job.lock.RUnlock()
+	return finishedState(job.State)
+}
+
+func (job *Job) setState(state string, err error) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	job.State = state
+	job.Error = err
+	job.Updated = time.Now()
+}
+
+func (job *Job) setCommitted(txHash common.Hash) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	job.TxHash = txHash
+	job.State = JobCommitted
+	job.Error = nil
+	job.Updated = time.Now()
+}
+
+// JobStatus is the state of a job reported to the clients.
+type JobStatus struct {
+	Hash      common.Hash `json:"hash"`
+	State     string      `json:"state"`
+	Reason    string      `json:"reason,omitempty"`
+	TxHash    common.Hash `json:"txHash"`
+	Submitted time.Time   `json:"submitted"`
+	Updated   time.Time   `json:"updated"`
+}
+
+func (job *Job) Status() *JobStatus {
+	job.lock.RLock()
+	defer job.lock.RUnlock()
+	status := &JobStatus{
+		Hash:      job.Hash,
+		State:     job.State,
+		TxHash:    job.TxHash,
+		Submitted: job.Timestamp,
+		Updated:   job.Updated,
+	}
+	if job.Error != nil {
+		status.Reason = job.Error.Error()
+	}
+	return status
 }
 
 var instance *ProofService
@@ -43,6 +114,15 @@ type Config struct {
 	MaxWorkNumber  int
 	MaxQueueNumber int
 	Fee            ServiceFee
+	// Rates are the currencies accepted for the fee besides SERO.
+	Rates   map[string]*big.Rat
+	DataDir string
+	// WorkerSecret enables the prover workers, they authenticate with it on
+	// the worker endpoint.
+	WorkerSecret  string
+	WorkerTimeout time.Duration
+	// ProveTimeout is how long a worker has to submit the result of a job.
+	ProveTimeout time.Duration
 }
 
 type ProofService struct {
@@ -51,11 +131,14 @@ type ProofService struct {
 
 	queueChan chan *Job
 	workChan  chan *Job
-	jobs      sync.Map
 	workNum   int32
 	client    SeroClient
 	// redisClient *RedisClient
-	storage Storage
+	storage     Storage
+	coordinator *coordinator
+
+	quit chan struct{}
+	wg   sync.WaitGroup
 }
 
 func Instance() *ProofService {
@@ -74,97 +157,170 @@ type SeroClient interface {
 
 type Storage interface {
 	Exists(common.Hash) bool
-	Save(job *Job)
+	// Save stores the job, a job it fails to store must not go on as it would
+	// be lost or proved again after a restart.
+	Save(job *Job) error
+	Delete(hash common.Hash)
 	Get(hash common.Hash) *Job
+	// Unfinished returns the queued and proving jobs by submit time.
+	Unfinished() []*Job
+	// Prune drops the finished jobs last updated before the time.
+	Prune(before time.Time)
+	Close()
 }
 
 type MapStorage struct {
 	cache map[common.Hash]*Job
+	lock  sync.RWMutex
 }
 
 func newMapStorage() *MapStorage {
-	return &MapStorage{make(map[common.Hash]*Job)}
+	return &MapStorage{cache: make(map[common.Hash]*Job)}
 }
 
 func (storage *MapStorage) Exists(hash common.Hash) bool {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
 	_, ok := storage.cache[hash]
 	return ok
 }
 
-func (storage *MapStorage) Save(job *Job) {
-	hash := job.tx.Tx1.Tx1_Hash()
-	storage.cache[common.BytesToHash(hash[:])] = job
+func (storage *MapStorage) Save(job *Job) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+	storage.cache[job.Hash] = job
+	return nil
+}
+
+func (storage *MapStorage) Delete(hash common.Hash) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+	delete(storage.cache, hash)
 }
 
 func (storage *MapStorage) Get(hash common.Hash) *Job {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
 	return storage.cache[hash]
 }
 
-func NewProofService(rpc string, backend Backend, config *Config) *ProofService {
+func (storage *MapStorage) Unfinished() (jobs []*Job) {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+	for _, job := range storage.cache {
+		if !job.finished() {
+			jobs = append(jobs, job)
+		}
+	}
+	sortJobs(jobs)
+	return
+}
+
+func (storage *MapStorage) Prune(before time.Time) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+	for hash, job := range storage.cache {
+		if status := job.Status(); finishedState(status.State) && status.Updated.Before(before) {
+			delete(storage.cache, hash)
+		}
+	}
+}
+
+func (storage *MapStorage) Close() {
+}
+
+func NewProofService(rpc string, backend Backend, config *Config) (*ProofService, error) {
 	proof := &ProofService{
 		rpc:       rpc,
 		config:    config,
 		queueChan: make(chan *Job, config.MaxQueueNumber),
+		quit:      make(chan struct{}),
 	}
 
 	proof.client = NewLocalClient(backend)
-	proof.storage = newMapStorage()
+	if config.DataDir != "" {
+		storage, err := NewDBStorage(config.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		proof.storage = storage
+	} else {
+		proof.storage = newMapStorage()
+	}
+
+	if config.WorkerSecret != "" {
+		proof.coordinator = newCoordinator(proof, config.WorkerTimeout, config.ProveTimeout)
+		proof.wg.Add(1)
+		go func() {
+			defer proof.wg.Done()
+			proof.coordinator.loop(proof.quit)
+		}()
+	}
 
 	instance = proof
-	go proof.loop()
+	proof.wg.Add(2)
+	go func() {
+		defer proof.wg.Done()
+		proof.requeue(proof.storage.Unfinished())
+	}()
+	go func() {
+		defer proof.wg.Done()
+		proof.loop()
+	}()
 	log.Info("ProofService start", "config:", config)
-	return proof
+	return proof, nil
+}
+
+// Stop stops the service once the jobs being proved are committed and closes
+// its job storage, the queued jobs are queued again when the service restarts.
+func (proof *ProofService) Stop() {
+	close(proof.quit)
+	proof.wg.Wait()
+	proof.storage.Close()
+	if instance == proof {
+		instance = nil
+	}
+	log.Info("ProofService stopped")
+}
+
+// requeue queues again the jobs a restart interrupted, waiting for room in the
+// queue.
+func (proof *ProofService) requeue(jobs []*Job) {
+	for _, job := range jobs {
+		log.Info("ProofService requeue job", "hash", job.Hash, "state", job.Status().State)
+		if err := proof.setState(job, JobQueued); err != nil {
+			continue
+		}
+		select {
+		case proof.queueChan <- job:
+		case <-proof.quit:
+			return
+		}
+	}
 }
 
 func (proof *ProofService) FindTxHash(hash common.Hash) common.Hash {
 	job := proof.storage.Get(hash)
 	if job != nil {
-		return job.TxHash
+		return job.Status().TxHash
 	}
 	return common.Hash{}
 }
 
-var sero = *common.BytesToHash(common.LeftPadBytes([]byte("SERO"), 32)).HashToUint256()
-
-func (proof *ProofService) checkFee(param *txtool.GTxParam) bool {
-	if proof.config.Fee.FixedFee.Sign() > 0 {
-		for _, out := range param.Outs {
-			if out.PKr == proof.config.PKr {
-				if out.Asset.Tkn != nil {
-					if out.Asset.Tkn.Currency == sero {
-						amount := out.Asset.Tkn.Value.ToInt()
-						return amount.Cmp(proof.config.Fee.FixedFee) >= 0
-					}
-				}
-				break
-			}
-		}
-	} else {
-		fee := big.NewInt(0)
-		for _, in := range param.Ins {
-			if in.Out.State.OS.Out_P != nil {
-				fee = new(big.Int).Add(fee, proof.config.Fee.ZinFee)
-			} else if in.Out.State.OS.Out_C != nil {
-				fee = new(big.Int).Add(fee, proof.config.Fee.OinFee)
-			} else {
-				return false
-			}
-		}
-		fee = new(big.Int).Add(fee, new(big.Int).Mul(proof.config.Fee.OutFee, big.NewInt(int64(len(param.Outs)-1))))
-		for _, out := range param.Outs {
-			if out.PKr == proof.config.PKr {
-				if out.Asset.Tkn != nil {
-					if out.Asset.Tkn.Currency == sero {
-						amount := out.Asset.Tkn.Value.ToInt()
-						return amount.Cmp(fee) >= 0
-					}
-				}
-				break
-			}
-		}
+func (proof *ProofService) JobStatus(hash common.Hash) (*JobStatus, error) {
+	job := proof.storage.Get(hash)
+	if job == nil {
+		return nil, errors.New("job not found")
 	}
+	return job.Status(), nil
+}
 
-	return false
+// Workers returns the throughput of the prover workers.
+func (proof *ProofService) Workers() []WorkerStats {
+	if proof.coordinator == nil {
+		return nil
+	}
+	return proof.coordinator.stats()
 }
 
 func (proof *ProofService) Fee() ServiceFee {
@@ -173,9 +329,9 @@ func (proof *ProofService) Fee() ServiceFee {
 
 func (proof *ProofService) SubmitWork(tx *stx.T, param *txtool.GTxParam) error {
 	hash := tx.Tx1_Hash()
-	if !proof.checkFee(param) {
-		log.Error("check fee error", "txHash", common.Bytes2Hex(hash[:]))
-		errors.New("checkFee error")
+	if err := proof.checkFee(param); err != nil {
+		log.Error("check fee error", "txHash", common.Bytes2Hex(hash[:]), "error", err)
+		return err
 	}
 
 	if proof.storage.Exists(common.BytesToHash(hash[:])) {
@@ -183,56 +339,97 @@ func (proof *ProofService) SubmitWork(tx *stx.T, param *txtool.GTxParam) error {
 		return errors.New("already exists")
 	}
 
-	job := newJob(tx, param)
+	job := newJob(common.BytesToHash(hash[:]), tx, param)
+	if err := proof.storage.Save(job); err != nil {
+		log.Error("ProofService save job error", "hash", job.Hash, "error", err)
+		return err
+	}
 	if TryEnqueue(job, proof.queueChan) {
-		proof.storage.Save(job)
 		return nil
 	}
+	proof.storage.Delete(job.Hash)
 	return errors.New("server is busy")
 }
 
+// setState moves the job to the state and stores it, the job is failed if the
+// storage fails to store it.
+func (proof *ProofService) setState(job *Job, state string) error {
+	job.setState(state, nil)
+	err := proof.storage.Save(job)
+	if err != nil {
+		log.Error("ProofService save job error", "hash", job.Hash, "state", state, "error", err)
+		proof.failJob(job, err)
+	}
+	return err
+}
+
+// failJob fails the job with the error.
+func (proof *ProofService) failJob(job *Job, err error) {
+	job.setState(JobFailed, err)
+	if err := proof.storage.Save(job); err != nil {
+		log.Error("ProofService save job error", "hash", job.Hash, "state", JobFailed, "error", err)
+	}
+}
+
 func (proof *ProofService) processJob(job *Job) {
+	if err := proof.setState(job, JobProving); err != nil {
+		return
+	}
+
 	gtx, err := flight.ProveTx1(job.tx, job.param)
+	proof.commitJob(job, &gtx, err)
+}
+
+// commitJob commits the proved transaction of the job, or fails the job with
+// the error of the proving.
+func (proof *ProofService) commitJob(job *Job, gtx *txtool.GTx, err error) {
 	if err != nil {
 		log.Error("processJob error", "error", err)
-		job.Error = err
-		proof.storage.Save(job)
+		proof.failJob(job, err)
 		return
 	}
-	if err := proof.client.CommitTx(&gtx); err != nil {
+	if err := proof.client.CommitTx(gtx); err != nil {
 		log.Error("processJob error", "error", err)
-		job.Error = err
-		proof.storage.Save(job)
+		proof.failJob(job, err)
 		return
 	}
 	txHash := gtx.Tx.ToHash()
-	job.TxHash = common.BytesToHash(txHash[:])
-	proof.storage.Save(job)
+	job.setCommitted(common.BytesToHash(txHash[:]))
+	if err := proof.storage.Save(job); err != nil {
+		log.Error("ProofService save job error", "hash", job.Hash, "state", JobCommitted, "error", err)
+	}
 }
 
 func (proof *ProofService) loop() {
 	clear := time.NewTicker(time.Minute * 10)
 	defer clear.Stop()
 
+	// The workers fetch the queued jobs themselves when there is a coordinator
+	var queue chan *Job
+	if proof.coordinator == nil {
+		queue = proof.queueChan
+	}
 	for {
-		for proof.workNum >= 5 {
-			time.Sleep(time.Second)
+		for atomic.LoadInt32(&proof.workNum) >= 5 {
+			select {
+			case <-proof.quit:
+				return
+			case <-time.After(time.Second):
+			}
 		}
 		select {
-		case job := <-proof.queueChan:
+		case <-proof.quit:
+			return
+		case job := <-queue:
 			atomic.AddInt32(&proof.workNum, 1)
+			proof.wg.Add(1)
 			go func() {
+				defer proof.wg.Done()
 				defer atomic.AddInt32(&proof.workNum, -1)
 				proof.processJob(job)
 			}()
 		case <-clear.C:
-			proof.jobs.Range(func(key, value interface{}) bool {
-				job := value.(*Job)
-				if job.Timestamp.Add(time.Hour * 2).Before(time.Now()) {
-					proof.jobs.Delete(key)
-				}
-				return true
-			})
+			proof.storage.Prune(time.Now().Add(-jobRetention))
 		}
 	}
 }
@@ -0,0 +1,162 @@
+package proofservice
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+var jobPrefix = []byte("JOB")
+
+func jobKey(hash common.Hash) []byte {
+	return append(append([]byte{}, jobPrefix...), hash[:]...)
+}
+
+// jobRecord is the stored job, the transaction and its parameters are kept
+// until the job is finished to prove it again after a restart.
+type jobRecord struct {
+	Hash      common.Hash
+	TxHash    common.Hash
+	Timestamp time.Time
+	Updated   time.Time
+	State     string
+	Reason    string           `json:",omitempty"`
+	Tx        *stx.T           `json:",omitempty"`
+	Param     *txtool.GTxParam `json:",omitempty"`
+}
+
+func encodeJob(job *Job) ([]byte, error) {
+	job.lock.RLock()
+	record := jobRecord{
+		Hash:      job.Hash,
+		TxHash:    job.TxHash,
+		Timestamp: job.Timestamp,
+		Updated:   job.Updated,
+		State:     job.State,
+	}
+	if job.Error != nil {
+		record.Reason = job.Error.Error()
+	}
+	if !finishedState(job.State) {
+		record.Tx = job.tx
+		record.Param = job.param
+	}
+	job.lock.RUnlock()
+	return json.Marshal(&record)
+}
+
+func decodeJob(data []byte) (*Job, error) {
+	var record jobRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	job := &Job{
+		Hash:      record.Hash,
+		TxHash:    record.TxHash,
+		Timestamp: record.Timestamp,
+		Updated:   record.Updated,
+		State:     record.State,
+		tx:        record.Tx,
+		param:     record.Param,
+	}
+	if record.Reason != "" {
+		job.Error = errors.New(record.Reason)
+	}
+	return job, nil
+}
+
+func sortJobs(jobs []*Job) {
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Timestamp.Before(jobs[j].Timestamp) })
+}
+
+// DBStorage stores the jobs in a database, so they outlive the service.
+type DBStorage struct {
+	db serodb.KeyValueStore
+}
+
+func NewDBStorage(dir string) (*DBStorage, error) {
+	db, err := serodb.NewDatabase(dir, 16, 16)
+	if err != nil {
+		return nil, err
+	}
+	return &DBStorage{db}, nil
+}
+
+func (storage *DBStorage) Exists(hash common.Hash) bool {
+	ok, _ := storage.db.Has(jobKey(hash))
+	return ok
+}
+
+func (storage *DBStorage) Save(job *Job) error {
+	data, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+	return storage.db.Put(jobKey(job.Hash), data)
+}
+
+func (storage *DBStorage) Delete(hash common.Hash) {
+	if err := storage.db.Delete(jobKey(hash)); err != nil {
+		log.Error("ProofService delete job error", "hash", hash, "error", err)
+	}
+}
+
+func (storage *DBStorage) Get(hash common.Hash) *Job {
+	data, err := storage.db.Get(jobKey(hash))
+	if err != nil {
+		return nil
+	}
+	job, err := decodeJob(data)
+	if err != nil {
+		log.Error("ProofService decode job error", "hash", hash, "error", err)
+		return nil
+	}
+	return job
+}
+
+func (storage *DBStorage) Unfinished() (jobs []*Job) {
+	it := storage.db.NewIteratorWithPrefix(jobPrefix)
+	defer it.Release()
+	for it.Next() {
+		job, err := decodeJob(it.Value())
+		if err != nil {
+			log.Error("ProofService decode job error", "key", common.Bytes2Hex(it.Key()), "error", err)
+			continue
+		}
+		if !job.finished() {
+			jobs = append(jobs, job)
+		}
+	}
+	sortJobs(jobs)
+	return
+}
+
+func (storage *DBStorage) Prune(before time.Time) {
+	it := storage.db.NewIteratorWithPrefix(jobPrefix)
+	defer it.Release()
+	batch := storage.db.NewBatch()
+	for it.Next() {
+		job, err := decodeJob(it.Value())
+		if err != nil || !job.finished() || !job.Updated.Before(before) {
+			continue
+		}
+		batch.Delete(append([]byte{}, it.Key()...))
+	}
+	if batch.ValueSize() == 0 {
+		return
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("ProofService prune jobs error", "error", err)
+	}
+}
+
+func (storage *DBStorage) Close() {
+	storage.db.Close()
+}
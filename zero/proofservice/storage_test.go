@@ -0,0 +1,200 @@
+package proofservice
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txs/stx/stx_v1"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func newStorageTx(n byte) (*stx.T, *txtool.GTxParam) {
+	fee := assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.NewU256(uint64(n) * 1000)}
+	tx := &stx.T{
+		Ehash: c_type.Uint256{n},
+		From:  c_type.PKr{n, 1},
+		Fee:   fee,
+		Tx1: stx_v1.Tx{
+			Ins_C:  []stx_v1.In_C{{Anchor: c_type.Uint256{n, 2}, Nil: c_type.Uint256{n, 3}}},
+			Outs_C: []stx_v1.Out_C{{PKr: c_type.PKr{n, 4}, AssetCM: c_type.Uint256{n, 5}}},
+		},
+	}
+	param := &txtool.GTxParam{Gas: 25000, GasPrice: big.NewInt(int64(n)), Fee: fee}
+	return tx, param
+}
+
+func newStorageJob(n byte) *Job {
+	tx, param := newStorageTx(n)
+	return newJob(common.Hash{n}, tx, param)
+}
+
+func TestDBStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proofservice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage, err := NewDBStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	queued := newStorageJob(1)
+	queued.Timestamp = start.Add(time.Second)
+	proving := newStorageJob(2)
+	proving.Timestamp = start
+	proving.setState(JobProving, nil)
+	committed := newStorageJob(3)
+	committed.setCommitted(common.Hash{0x33})
+	failed := newStorageJob(4)
+	failed.setState(JobFailed, errors.New("prove failed"))
+	for _, job := range []*Job{queued, proving, committed, failed} {
+		if err := storage.Save(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	storage.Close()
+
+	if storage, err = NewDBStorage(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	if !storage.Exists(common.Hash{1}) || storage.Exists(common.Hash{5}) {
+		t.Fatalf("exists mismatch")
+	}
+
+	status := storage.Get(common.Hash{3}).Status()
+	if status.State != JobCommitted || status.TxHash != committed.TxHash {
+		t.Errorf("committed job mismatch: have %v %x", status.State, status.TxHash)
+	}
+	status = storage.Get(common.Hash{4}).Status()
+	if status.State != JobFailed || status.Reason != "prove failed" {
+		t.Errorf("failed job mismatch: have %v %q", status.State, status.Reason)
+	}
+
+	jobs := storage.Unfinished()
+	if len(jobs) != 2 || jobs[0].Hash != proving.Hash || jobs[1].Hash != queued.Hash {
+		t.Fatalf("unfinished jobs mismatch: have %d", len(jobs))
+	}
+	for _, job := range jobs {
+		tx, param := newStorageTx(job.Hash[0])
+		if job.tx == nil || job.param == nil {
+			t.Fatalf("unfinished job %x lost its transaction", job.Hash)
+		}
+		if job.tx.Tx1_Hash() != tx.Tx1_Hash() || job.tx.From != tx.From || job.tx.Tx1.Ins_C[0].Nil != tx.Tx1.Ins_C[0].Nil {
+			t.Errorf("unfinished job %x transaction mismatch", job.Hash)
+		}
+		if job.param.Gas != param.Gas || job.param.GasPrice.Cmp(param.GasPrice) != 0 || job.param.Fee.Value.ToInt().Cmp(param.Fee.Value.ToInt()) != 0 {
+			t.Errorf("unfinished job %x parameters mismatch", job.Hash)
+		}
+	}
+	if storage.Get(common.Hash{3}).tx != nil {
+		t.Errorf("committed job kept its transaction")
+	}
+
+	// Only the finished jobs updated before the time are pruned
+	storage.Prune(time.Now().Add(time.Minute))
+	for _, hash := range []common.Hash{{1}, {2}} {
+		if !storage.Exists(hash) {
+			t.Errorf("unfinished job %x pruned", hash)
+		}
+	}
+	for _, hash := range []common.Hash{{3}, {4}} {
+		if storage.Exists(hash) {
+			t.Errorf("finished job %x not pruned", hash)
+		}
+	}
+}
+
+func TestMapStoragePrune(t *testing.T) {
+	storage := newMapStorage()
+	queued := newStorageJob(1)
+	committed := newStorageJob(2)
+	committed.setCommitted(common.Hash{0x22})
+	for _, job := range []*Job{queued, committed} {
+		storage.Save(job)
+	}
+
+	storage.Prune(time.Now().Add(-time.Minute))
+	if !storage.Exists(committed.Hash) {
+		t.Fatalf("recent finished job pruned")
+	}
+	storage.Prune(time.Now().Add(time.Minute))
+	if storage.Exists(committed.Hash) || !storage.Exists(queued.Hash) {
+		t.Fatalf("prune mismatch")
+	}
+}
+
+// failStorage fails to save the jobs once fail is set.
+type failStorage struct {
+	*MapStorage
+	fail bool
+}
+
+func (storage *failStorage) Save(job *Job) error {
+	if storage.fail {
+		return errors.New("disk full")
+	}
+	return storage.MapStorage.Save(job)
+}
+
+func TestStorageError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proofservice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewDBStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if err := db.Save(newStorageJob(1)); err == nil {
+		t.Fatalf("job saved to a closed database")
+	}
+
+	storage := &failStorage{MapStorage: newMapStorage(), fail: true}
+	proof := &ProofService{config: &Config{}, queueChan: make(chan *Job, 1), storage: storage}
+
+	// A job the storage fails to save is rejected
+	tx, param := newStorageTx(1)
+	if err := proof.SubmitWork(tx, param); err == nil {
+		t.Fatalf("unsaved job accepted")
+	}
+	if len(proof.queueChan) != 0 {
+		t.Fatalf("unsaved job queued")
+	}
+
+	// A job rejected by the full queue is not kept
+	storage.fail = false
+	if err := proof.SubmitWork(tx, param); err != nil {
+		t.Fatal(err)
+	}
+	busy, busyParam := newStorageTx(2)
+	if err := proof.SubmitWork(busy, busyParam); err == nil {
+		t.Fatalf("job accepted by a full queue")
+	}
+	if hash := busy.Tx1_Hash(); storage.Exists(common.BytesToHash(hash[:])) {
+		t.Errorf("job rejected by a full queue kept")
+	}
+
+	// A job the storage fails to move to the next state is failed
+	job := <-proof.queueChan
+	storage.fail = true
+	if err := proof.setState(job, JobProving); err == nil {
+		t.Fatalf("unsaved state transition accepted")
+	}
+	if status := job.Status(); status.State != JobFailed || status.Reason != "disk full" {
+		t.Errorf("job state mismatch: have %s %q, want %s", status.State, status.Reason, JobFailed)
+	}
+}
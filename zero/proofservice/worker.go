@@ -0,0 +1,193 @@
+package proofservice
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/flight"
+)
+
+// submitRetries is how many times a worker submits a result the service could
+// not be reached for.
+const submitRetries = 5
+
+// Prover proves the transaction of a job.
+type Prover func(tx *stx.T, param *txtool.GTxParam) (txtool.GTx, error)
+
+// Worker proves the jobs of a proof service running with prover workers.
+type Worker struct {
+	name    string
+	threads int
+	client  *rpc.Client
+
+	prove     Prover
+	heartbeat time.Duration
+	poll      time.Duration
+
+	lock sync.Mutex
+	id   string
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// authTransport adds the secret of the service to the requests of a worker.
+type authTransport struct {
+	secret string
+	next   http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.WithContext(req.Context())
+	req.Header = cloneHeader(req.Header)
+	req.Header.Set(workerAuthHeader, t.secret)
+	return t.next.RoundTrip(req)
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// NewWorker connects to the worker endpoint of the proof service at url, the
+// worker proves threads jobs at a time.
+func NewWorker(url string, secret string, name string, threads int) (*Worker, error) {
+	client, err := rpc.DialHTTPWithClient(url, &http.Client{
+		Transport: &authTransport{secret, http.DefaultTransport},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	return &Worker{
+		name:      name,
+		threads:   threads,
+		client:    client,
+		prove:     flight.ProveTx1,
+		heartbeat: defaultWorkerTimeout / 3,
+		poll:      time.Second,
+		quit:      make(chan struct{}),
+	}, nil
+}
+
+func (w *Worker) register() (string, error) {
+	var id string
+	if err := w.client.Call(&id, "prover_registerWorker", w.name); err != nil {
+		return "", err
+	}
+	w.lock.Lock()
+	w.id = id
+	w.lock.Unlock()
+	log.Info("Prover worker registered", "id", id)
+	return id, nil
+}
+
+func (w *Worker) currentId() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.id
+}
+
+// Start registers the worker and starts proving.
+func (w *Worker) Start() error {
+	if _, err := w.register(); err != nil {
+		return err
+	}
+	w.wg.Add(w.threads + 1)
+	go w.heartbeatLoop()
+	for i := 0; i < w.threads; i++ {
+		go w.workLoop()
+	}
+	return nil
+}
+
+// Stop stops the worker once the jobs it proves are submitted.
+func (w *Worker) Stop() {
+	close(w.quit)
+	w.wg.Wait()
+	w.client.Close()
+}
+
+func (w *Worker) heartbeatLoop() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.quit:
+			return
+		case <-ticker.C:
+			id := w.currentId()
+			if err := w.client.Call(nil, "prover_heartbeat", id); err != nil {
+				log.Warn("Prover worker heartbeat error", "id", id, "error", err)
+				if err.Error() == errUnknownWorker.Error() {
+					w.register()
+				}
+			}
+		}
+	}
+}
+
+func (w *Worker) workLoop() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.quit:
+			return
+		default:
+		}
+		id := w.currentId()
+		var work *ProofWork
+		if err := w.client.Call(&work, "prover_fetchWork", id); err != nil {
+			log.Warn("Prover worker fetch error", "id", id, "error", err)
+			work = nil
+		}
+		if work == nil {
+			select {
+			case <-w.quit:
+				return
+			case <-time.After(w.poll):
+			}
+			continue
+		}
+
+		reason := ""
+		gtx, err := w.prove(work.Tx, work.Param)
+		if err != nil {
+			reason = err.Error()
+		}
+		w.submit(id, work.Hash, &gtx, reason)
+	}
+}
+
+// submit submits the result of the job, again while the service can not be
+// reached. The service hands out the job again if the result never arrives.
+func (w *Worker) submit(id string, hash common.Hash, gtx *txtool.GTx, reason string) {
+	for i := 1; ; i++ {
+		err := w.client.Call(nil, "prover_submitResult", id, hash, gtx, reason)
+		if err == nil {
+			return
+		}
+		log.Warn("Prover worker submit error", "id", id, "hash", hash, "attempt", i, "error", err)
+		// The service answered, submitting again gets the same answer
+		if _, ok := err.(rpc.Error); ok || i == submitRetries {
+			return
+		}
+		select {
+		case <-w.quit:
+			return
+		case <-time.After(w.poll):
+		}
+	}
+}
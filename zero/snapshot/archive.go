@@ -0,0 +1,479 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/localdb"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+// The archive is the header, the chunks of the database entries, each the
+// length of the gzip compressed data followed by the data, the json manifest
+// and the footer with the offset and the length of the manifest.
+const (
+	archiveMagic      = "SEROSNAP"
+	archiveVersion    = 1
+	archiveHeaderSize = len(archiveMagic) + 4
+	archiveFooterSize = 16 + len(archiveMagic)
+	archiveChunkSize  = 32 * 1024 * 1024
+)
+
+var errArchiveFormat = errors.New("invalid snapshot archive")
+
+// ArchiveCheckpoint is a block the archive is checked at.
+type ArchiveCheckpoint struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Root   common.Hash `json:"root"`
+}
+
+// ArchiveChunk is the compressed data of a run of database entries.
+type ArchiveChunk struct {
+	Size    uint64      `json:"size"`
+	Entries uint64      `json:"entries"`
+	Hash    common.Hash `json:"hash"`
+}
+
+// ArchiveManifest describes the snapshot of an archive.
+type ArchiveManifest struct {
+	Version     uint32              `json:"version"`
+	Number      uint64              `json:"number"`
+	Hash        common.Hash         `json:"hash"`
+	Root        common.Hash         `json:"root"`
+	ZeroRoots   []common.Hash       `json:"zeroRoots"`
+	Checkpoints []ArchiveCheckpoint `json:"checkpoints"`
+	Chunks      []ArchiveChunk      `json:"chunks"`
+}
+
+type archiveWriter struct {
+	w   *bufio.Writer
+	pos uint64
+}
+
+func (self *archiveWriter) Write(p []byte) (int, error) {
+	n, err := self.w.Write(p)
+	self.pos += uint64(n)
+	return n, err
+}
+
+func (self *archiveWriter) writeUint(v uint64, size int) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	_, err := self.Write(buf[8-size:])
+	return err
+}
+
+func putEntry(buf *bytes.Buffer, key []byte, value []byte) {
+	var size [binary.MaxVarintLen64]byte
+	buf.Write(size[:binary.PutUvarint(size[:], uint64(len(key)))])
+	buf.Write(key)
+	buf.Write(size[:binary.PutUvarint(size[:], uint64(len(value)))])
+	buf.Write(value)
+}
+
+func readEntry(r *bufio.Reader) (key []byte, value []byte, err error) {
+	if key, err = readBytes(r); err != nil {
+		return
+	}
+	value, err = readBytes(r)
+	return
+}
+
+func readBytes(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > archiveChunkSize {
+		return nil, errArchiveFormat
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+// Export writes the full snapshot in dir to the archive file.
+func Export(dir string, file string) (*ArchiveManifest, error) {
+	m, err := ReadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	if m.Incremental() {
+		return nil, fmt.Errorf("snapshot %s is incremental, apply it to its base first", dir)
+	}
+	db, err := serodb.NewDatabaseEx(dir, 1024, 256, true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if head := rawdb.ReadHeadBlockHash(db); head != m.Hash {
+		return nil, fmt.Errorf("snapshot head %x is not the manifest head %x", head, m.Hash)
+	}
+
+	am := &ArchiveManifest{
+		Version: archiveVersion,
+		Number:  m.Number,
+		Hash:    m.Hash,
+		Root:    m.Root,
+	}
+	block := localdb.GetBlock(db, m.Number, m.Hash.HashToUint256())
+	if block == nil {
+		return nil, fmt.Errorf("snapshot zero state of the head %d is missing", m.Number)
+	}
+	for _, root := range block.Roots {
+		am.ZeroRoots = append(am.ZeroRoots, common.BytesToHash(root[:]))
+	}
+	for num := uint64(zconfig.CheckPointInterval); num <= m.Number; num += zconfig.CheckPointInterval {
+		hash := rawdb.ReadCanonicalHash(db, num)
+		header := rawdb.ReadHeader(db, hash, num)
+		if header == nil {
+			return nil, fmt.Errorf("snapshot header %d is missing", num)
+		}
+		am.Checkpoints = append(am.Checkpoints, ArchiveCheckpoint{num, hash, header.Root})
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeArchive(f, db, am); err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		os.Remove(file)
+		return nil, err
+	}
+	return am, nil
+}
+
+func writeArchive(f *os.File, db serodb.KeyValueStore, am *ArchiveManifest) error {
+	w := &archiveWriter{w: bufio.NewWriter(f)}
+	if _, err := w.Write([]byte(archiveMagic)); err != nil {
+		return err
+	}
+	if err := w.writeUint(archiveVersion, 4); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	entries := uint64(0)
+	flush := func() error {
+		var data bytes.Buffer
+		gz := gzip.NewWriter(&data)
+		if _, err := gz.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		if err := w.writeUint(uint64(data.Len()), 4); err != nil {
+			return err
+		}
+		if _, err := w.Write(data.Bytes()); err != nil {
+			return err
+		}
+		am.Chunks = append(am.Chunks, ArchiveChunk{uint64(data.Len()), entries, crypto.Keccak256Hash(data.Bytes())})
+		buf.Reset()
+		entries = 0
+		return nil
+	}
+
+	it := db.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		if bytes.Equal(it.Key(), progressKey) {
+			continue
+		}
+		putEntry(&buf, it.Key(), it.Value())
+		entries++
+		if buf.Len() >= archiveChunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if entries > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(am)
+	if err != nil {
+		return err
+	}
+	offset := w.pos
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.writeUint(offset, 8); err != nil {
+		return err
+	}
+	if err := w.writeUint(uint64(len(data)), 8); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(archiveMagic)); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// openArchive reads the manifest of the archive file and returns the offset of
+// the manifest, the end of the chunks.
+func openArchive(file string) (*os.File, *ArchiveManifest, uint64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	am, offset, err := readArchiveManifest(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, 0, err
+	}
+	return f, am, offset, nil
+}
+
+func readArchiveManifest(f *os.File) (*ArchiveManifest, uint64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := uint64(info.Size())
+	if size < uint64(archiveHeaderSize+archiveFooterSize) {
+		return nil, 0, errArchiveFormat
+	}
+	header := make([]byte, archiveHeaderSize)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return nil, 0, err
+	}
+	if string(header[:len(archiveMagic)]) != archiveMagic {
+		return nil, 0, errArchiveFormat
+	}
+	if version := binary.BigEndian.Uint32(header[len(archiveMagic):]); version != archiveVersion {
+		return nil, 0, fmt.Errorf("unsupported snapshot archive version %d", version)
+	}
+	footer := make([]byte, archiveFooterSize)
+	if _, err := f.ReadAt(footer, int64(size)-int64(archiveFooterSize)); err != nil {
+		return nil, 0, err
+	}
+	if string(footer[16:]) != archiveMagic {
+		return nil, 0, errArchiveFormat
+	}
+	offset, length := binary.BigEndian.Uint64(footer), binary.BigEndian.Uint64(footer[8:])
+	if offset < uint64(archiveHeaderSize) || offset+length != size-uint64(archiveFooterSize) {
+		return nil, 0, errArchiveFormat
+	}
+	data := make([]byte, length)
+	if _, err := f.ReadAt(data, int64(offset)); err != nil {
+		return nil, 0, err
+	}
+	am := &ArchiveManifest{}
+	if err := json.Unmarshal(data, am); err != nil {
+		return nil, 0, err
+	}
+	return am, offset, nil
+}
+
+// readChunks checks every chunk against the manifest and passes its entries to fn.
+func readChunks(f *os.File, am *ArchiveManifest, offset uint64, fn func(key []byte, value []byte) error) error {
+	r := bufio.NewReader(io.NewSectionReader(f, int64(archiveHeaderSize), int64(offset)-int64(archiveHeaderSize)))
+	for i, chunk := range am.Chunks {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return fmt.Errorf("snapshot archive chunk %d: %v", i, err)
+		}
+		if uint64(binary.BigEndian.Uint32(size[:])) != chunk.Size {
+			return fmt.Errorf("snapshot archive chunk %d: size mismatch", i)
+		}
+		data := make([]byte, chunk.Size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("snapshot archive chunk %d: %v", i, err)
+		}
+		if crypto.Keccak256Hash(data) != chunk.Hash {
+			return fmt.Errorf("snapshot archive chunk %d: hash mismatch", i)
+		}
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("snapshot archive chunk %d: %v", i, err)
+		}
+		entries := bufio.NewReader(gz)
+		for n := uint64(0); n < chunk.Entries; n++ {
+			key, value, err := readEntry(entries)
+			if err != nil {
+				return fmt.Errorf("snapshot archive chunk %d: %v", i, err)
+			}
+			if err := fn(key, value); err != nil {
+				return err
+			}
+		}
+		if _, err := entries.ReadByte(); err != io.EOF {
+			return fmt.Errorf("snapshot archive chunk %d: entries mismatch", i)
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return errArchiveFormat
+	}
+	return nil
+}
+
+// Verify checks the archive file, the hash of every chunk, the head and the
+// checkpoint headers against the manifest and the checkpoints against the
+// checkpoints of the chain. It returns the highest block whose state root was
+// verified against a checkpoint of the chain, 0 when none was.
+func Verify(file string) (*ArchiveManifest, uint64, error) {
+	f, am, offset, err := openArchive(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	trusted, err := verifyArchive(f, am, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return am, trusted, nil
+}
+
+func verifyArchive(f *os.File, am *ArchiveManifest, offset uint64) (trusted uint64, err error) {
+	if len(am.Checkpoints) != int(am.Number/zconfig.CheckPointInterval) {
+		return 0, fmt.Errorf("snapshot archive has %d checkpoints, %d expected", len(am.Checkpoints), am.Number/zconfig.CheckPointInterval)
+	}
+	wanted := map[string]ArchiveCheckpoint{
+		string(rawdb.HeaderKey(am.Number, am.Hash)): {am.Number, am.Hash, am.Root},
+	}
+	for i, cp := range am.Checkpoints {
+		if cp.Number != uint64(i+1)*zconfig.CheckPointInterval {
+			return 0, fmt.Errorf("snapshot archive checkpoint %d is out of order", cp.Number)
+		}
+		if cp.Number <= zconfig.CheckPoints.MaxNum() {
+			if err := zconfig.CheckPoints.Check(cp.Number, cp.Root[:]); err != nil {
+				return 0, err
+			}
+			trusted = cp.Number
+		}
+		wanted[string(rawdb.HeaderKey(cp.Number, cp.Hash))] = cp
+	}
+
+	err = readChunks(f, am, offset, func(key []byte, value []byte) error {
+		cp, ok := wanted[string(key)]
+		if !ok {
+			return nil
+		}
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(value, header); err != nil {
+			return fmt.Errorf("snapshot archive header %d: %v", cp.Number, err)
+		}
+		if header.Hash() != cp.Hash || header.Root != cp.Root {
+			return fmt.Errorf("snapshot archive header %d does not match the manifest", cp.Number)
+		}
+		delete(wanted, string(key))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, cp := range wanted {
+		return 0, fmt.Errorf("snapshot archive header %d is missing", cp.Number)
+	}
+	return trusted, nil
+}
+
+// Import verifies the archive file and writes the snapshot to the empty chain
+// database dir. A head beyond the highest block verified against the
+// checkpoints of the chain is refused unless untrusted is set.
+func Import(file string, dir string, untrusted bool) (*ArchiveManifest, error) {
+	if files, err := ioutil.ReadDir(dir); err == nil && len(files) > 0 {
+		return nil, fmt.Errorf("chain database %s is not empty", dir)
+	}
+	f, am, offset, err := openArchive(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	trusted, err := verifyArchive(f, am, offset)
+	if err != nil {
+		return nil, err
+	}
+	if am.Number > trusted && !untrusted {
+		return nil, fmt.Errorf("snapshot archive head %d is beyond the highest trusted block %d", am.Number, trusted)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	db, err := serodb.NewDatabase(dir, 1024, 256)
+	if err != nil {
+		return nil, err
+	}
+	if err = importArchive(f, am, offset, db); err == nil {
+		err = checkImported(db, am)
+	}
+	db.Close()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return am, WriteManifest(dir, &Manifest{Number: am.Number, Hash: am.Hash, Root: am.Root})
+}
+
+func importArchive(f *os.File, am *ArchiveManifest, offset uint64, db serodb.KeyValueStore) error {
+	batch := db.NewBatch()
+	err := readChunks(f, am, offset, func(key []byte, value []byte) error {
+		batch.Put(key, value)
+		if batch.ValueSize() >= serodb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// checkImported checks the head, its state and its zero state are in db.
+func checkImported(db serodb.KeyValueStore, am *ArchiveManifest) error {
+	if head := rawdb.ReadHeadBlockHash(db); head != am.Hash {
+		return fmt.Errorf("imported head %x is not the manifest head %x", head, am.Hash)
+	}
+	header := rawdb.ReadHeader(db, am.Hash, am.Number)
+	if header == nil {
+		return fmt.Errorf("imported head header %d is missing", am.Number)
+	}
+	if _, err := state.New(state.NewDatabase(db), header); err != nil {
+		return fmt.Errorf("imported state %x: %v", header.Root, err)
+	}
+	block := localdb.GetBlock(db, am.Number, am.Hash.HashToUint256())
+	if block == nil || len(block.Roots) != len(am.ZeroRoots) {
+		return fmt.Errorf("imported zero state of the head %d does not match the manifest", am.Number)
+	}
+	for i, root := range am.ZeroRoots {
+		if common.BytesToHash(block.Roots[i][:]) != root || localdb.GetRoot(db, root.HashToUint256()) == nil {
+			return fmt.Errorf("imported zero state root %x is missing", root)
+		}
+	}
+	return nil
+}
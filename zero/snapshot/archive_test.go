@@ -0,0 +1,136 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+func TestArchiveChunks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := serodb.NewLDBDatabase(filepath.Join(dir, "db"), 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	want := make(map[string][]byte)
+	for i := 0; i < 1000; i++ {
+		key, value := []byte(fmt.Sprintf("key%d", i)), bytes.Repeat([]byte{byte(i)}, i)
+		db.Put(key, value)
+		want[string(key)] = value
+	}
+	db.Put(progressKey, []byte{1})
+
+	file := filepath.Join(dir, "snapshot.archive")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	am := &ArchiveManifest{Version: archiveVersion}
+	if err := writeArchive(f, db, am); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	f, read, offset, err := openArchive(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Chunks) != len(am.Chunks) || read.Chunks[0] != am.Chunks[0] {
+		t.Fatalf("manifest mismatch: %+v", read.Chunks)
+	}
+	err = readChunks(f, read, offset, func(key []byte, value []byte) error {
+		if !bytes.Equal(want[string(key)], value) {
+			return fmt.Errorf("entry %s mismatch", key)
+		}
+		delete(want, string(key))
+		return nil
+	})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 0 {
+		t.Errorf("%d entries missing", len(want))
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[archiveHeaderSize+10] ^= 0xff
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, read, offset, err = openArchive(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := readChunks(f, read, offset, func([]byte, []byte) error { return nil }); err == nil {
+		t.Errorf("corrupted chunk accepted")
+	}
+}
+
+func TestImportUntrusted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A head before the first checkpoint is not verified against the chain
+	header := &types.Header{Number: big.NewInt(5), Root: common.Hash{1}}
+	data, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := serodb.NewLDBDatabase(filepath.Join(dir, "db"), 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.Put(rawdb.HeaderKey(5, header.Hash()), data)
+
+	file := filepath.Join(dir, "snapshot.archive")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	am := &ArchiveManifest{Version: archiveVersion, Number: 5, Hash: header.Hash(), Root: header.Root}
+	if err := writeArchive(f, db, am); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	_, trusted, err := Verify(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trusted != 0 {
+		t.Fatalf("trusted block mismatch: have %d, want 0", trusted)
+	}
+	// The untrusted head is refused and the chain database is not created
+	chaindata := filepath.Join(dir, "chaindata")
+	if _, err := Import(file, chaindata, false); err == nil {
+		t.Fatalf("untrusted head imported")
+	}
+	if _, err := os.Stat(chaindata); !os.IsNotExist(err) {
+		t.Errorf("chain database created for a refused import")
+	}
+}
@@ -17,27 +17,65 @@ import (
 )
 
 type SnapshotGen struct {
-	src_db *serodb.LDBDatabase
+	src_db serodb.KeyValueStore
 	src_state_db state.Database
 	src_head_block_hash common.Hash
 	src_head_num int64
-	target_db *serodb.LDBDatabase
+	target_db serodb.KeyValueStore
+	target string
+
+	base     *Manifest
+	base_db  serodb.KeyValueStore
+	progress *progress
 
 	target_head_block_hash common.Hash
 	target_head_num int64
 
 	blockStop chan bool
-	stateStop chan bool
+	stateStop chan error
 }
 
+// NewSnapshotGen creates the generator of a full snapshot of src in target, a
+// target left by an interrupted run is resumed.
 func NewSnapshotGen(src string,target string) (ret *SnapshotGen,err error) {
-	sg:=SnapshotGen{}
-	if sg.src_db,err=serodb.NewLDBDatabaseEx(src,1024*8,1024,true);err!=nil {
+	return newSnapshotGen(src, target, "")
+}
+
+// NewIncrementalSnapshotGen creates the generator of the snapshot of the blocks
+// of src after the head of the base manifest, with only the state nodes the base
+// snapshot next to the manifest does not have.
+func NewIncrementalSnapshotGen(src string, target string, baseManifest string) (ret *SnapshotGen, err error) {
+	return newSnapshotGen(src, target, baseManifest)
+}
+
+func newSnapshotGen(src string, target string, baseManifest string) (ret *SnapshotGen, err error) {
+	sg:=SnapshotGen{target: target}
+	if sg.src_db,err=serodb.NewDatabaseEx(src,1024*8,1024,true);err!=nil {
 		return nil,err
 	}
 	sg.src_state_db=state.NewDatabase(sg.src_db)
+	if baseManifest != "" {
+		if sg.base, sg.base_db, err = openBase(baseManifest, true); err != nil {
+			sg.src_db.Close()
+			return nil, err
+		}
+	}
 
-	{
+	if err=os.MkdirAll(target,os.ModePerm);err!=nil {
+		sg.Close()
+		return nil,err
+	}
+	if sg.target_db,err=serodb.NewDatabase(target,1024*8,1024);err!=nil {
+		sg.Close()
+		return nil,err
+	}
+
+	if sg.progress = readProgress(sg.target_db); sg.progress != nil {
+		if err = sg.resume(); err != nil {
+			sg.Close()
+			return nil, err
+		}
+	} else {
 		hash := rawdb.ReadHeadBlockHash(sg.src_db)
 		num := *rawdb.ReadHeaderNumber(sg.src_db, hash)
 		header := rawdb.ReadHeader(sg.src_db, hash, num)
@@ -49,34 +87,95 @@ func NewSnapshotGen(src string,target string) (ret *SnapshotGen,err error) {
 			}
 			header = rawdb.ReadHeader(sg.src_db, header.ParentHash, header.Number.Uint64()-1)
 		}
+		if err = sg.start(); err != nil {
+			sg.Close()
+			return nil, err
+		}
 	}
 
 
 	if err=sg.VerifyDB();err!=nil {
+		sg.Close()
 		return nil,err
 	}
 
-	if err=os.MkdirAll(target,os.ModePerm);err!=nil {
-		return nil,err
-	}
-	if sg.target_db,err=serodb.NewLDBDatabase(target,1024*8,1024);err!=nil {
-		return nil,err
-	}
 	sg.target_head_block_hash=rawdb.ReadHeadBlockHash(sg.target_db)
 	if num:=rawdb.ReadHeaderNumber(sg.target_db,sg.target_head_block_hash);num!=nil {
 		sg.target_head_num=int64(*num)
+	} else if sg.base != nil {
+		sg.target_head_num=int64(sg.base.Number)
+		sg.target_head_block_hash=sg.base.Hash
 	} else {
 		sg.target_head_num=-1
 	}
 
 	sg.blockStop=make(chan bool)
-	sg.stateStop=make(chan bool)
+	sg.stateStop=make(chan error)
 	return &sg,nil
 }
 
 func (self *SnapshotGen) Close() {
 	self.src_db.Close();
-	self.target_db.Close();
+	if self.target_db != nil {
+		self.target_db.Close()
+	}
+	if self.base_db != nil {
+		self.base_db.Close()
+	}
+}
+
+// start records the head the run snapshots.
+func (self *SnapshotGen) start() error {
+	header := rawdb.ReadHeader(self.src_db, self.src_head_block_hash, uint64(self.src_head_num))
+	self.progress = &progress{
+		Number: uint64(self.src_head_num),
+		Hash:   self.src_head_block_hash,
+		Root:   header.Root,
+	}
+	if self.base != nil {
+		if self.base.Number >= self.progress.Number {
+			return fmt.Errorf("the src db head %d is not after the base head %d", self.progress.Number, self.base.Number)
+		}
+		if hash := rawdb.ReadCanonicalHash(self.src_db, self.base.Number); hash != self.base.Hash {
+			return fmt.Errorf("the base head %x is not canonical in the src db", self.base.Hash)
+		}
+		self.progress.BaseNumber = self.base.Number
+		self.progress.BaseHash = self.base.Hash
+	} else if hash := rawdb.ReadHeadBlockHash(self.target_db); hash != (common.Hash{}) {
+		if num := rawdb.ReadHeaderNumber(self.target_db, hash); num == nil || rawdb.ReadCanonicalHash(self.src_db, *num) != hash {
+			return fmt.Errorf("the target db is not a snapshot of the src db")
+		}
+	}
+	return writeProgress(self.target_db, self.progress)
+}
+
+// resume continues the recorded run towards its head.
+func (self *SnapshotGen) resume() error {
+	p := self.progress
+	if self.base == nil && p.BaseHash != (common.Hash{}) {
+		return fmt.Errorf("the target db is an incremental snapshot on %d %x", p.BaseNumber, p.BaseHash)
+	}
+	if self.base != nil && (p.BaseNumber != self.base.Number || p.BaseHash != self.base.Hash) {
+		return fmt.Errorf("the target db is a snapshot on %d %x, not on the base %d %x", p.BaseNumber, p.BaseHash, self.base.Number, self.base.Hash)
+	}
+	if hash := rawdb.ReadCanonicalHash(self.src_db, p.Number); hash != p.Hash {
+		return fmt.Errorf("the snapshot head %d %x is no more canonical in the src db", p.Number, p.Hash)
+	}
+	self.src_head_num = int64(p.Number)
+	self.src_head_block_hash = p.Hash
+	log.Print("Resume snapshot:", p.Number, " state done=", p.StateDone)
+	return nil
+}
+
+// Manifest returns the manifest of the snapshot of the run.
+func (self *SnapshotGen) Manifest() *Manifest {
+	return &Manifest{
+		Number:     self.progress.Number,
+		Hash:       self.progress.Hash,
+		Root:       self.progress.Root,
+		BaseNumber: self.progress.BaseNumber,
+		BaseHash:   self.progress.BaseHash,
+	}
 }
 
 func (self *SnapshotGen) RunBlock() {
@@ -90,21 +189,35 @@ func (self *SnapshotGen) RunBlock() {
 }
 
 func (self *SnapshotGen) RunState() {
+	self.stateStop<-self.processState()
+}
+
+// processState copies the state of the head and records it is done.
+func (self *SnapshotGen) processState() error {
+	if self.progress.StateDone {
+		return nil
+	}
 	num:=uint64(self.src_head_num)
-	hblock := rawdb.ReadCanonicalHash(self.src_db, num)
-	header := rawdb.ReadHeader(self.src_db, hblock, num)
-	if ok,count:=self.ProcessState(header.Root);ok {
+	if ok,count:=self.ProcessState(self.progress.Root);ok {
 		log.Print("End Process State:", num,"count=",count)
+		self.progress.StateDone = true
+		if err := writeProgress(self.target_db, self.progress); err != nil {
+			return err
+		}
 	}
-	self.stateStop<-true
+	return nil
 }
 
-func (self *SnapshotGen) Run() {
+// Run copies the blocks and the state and writes the manifest of the snapshot.
+func (self *SnapshotGen) Run() error {
 	go self.RunBlock()
 	go self.RunState()
 
 	<-self.blockStop
-	<-self.stateStop
+	if err := <-self.stateStop; err != nil {
+		return err
+	}
+	return WriteManifest(self.target, self.Manifest())
 }
 
 func (self *SnapshotGen) VerifyDB() error {
@@ -211,7 +324,11 @@ func (self *SnapshotGen) ProcessBlock(step int) (bool) {
 
 func (self *SnapshotGen) ProcessState(root common.Hash) (bool,int) {
 	const batch_num int=1024*10;
-	sched := state.NewStateSync(root,self.target_db)
+	var reader trie.DatabaseReader = self.target_db
+	if self.base_db != nil {
+		reader = layeredReader{self.target_db, self.base_db}
+	}
+	sched := state.NewStateSync(root,reader)
 	queue := append([]common.Hash{}, sched.Missing(batch_num)...)
 	count:=0
 	for len(queue) > 0 {
@@ -0,0 +1,64 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+func TestResume(t *testing.T) {
+	src, target := serodb.NewMemDatabase(), serodb.NewMemDatabase()
+	rawdb.WriteCanonicalHash(src, common.Hash{5}, 5)
+
+	recorded := &progress{Number: 5, Hash: common.Hash{5}, Root: common.Hash{0x55}, StateDone: true}
+	if err := writeProgress(target, recorded); err != nil {
+		t.Fatal(err)
+	}
+	p := readProgress(target)
+	if p == nil || *p != *recorded {
+		t.Fatalf("progress mismatch: %+v", p)
+	}
+
+	// A full run resumes towards its recorded head
+	sg := &SnapshotGen{src_db: src, target_db: target, progress: p}
+	if err := sg.resume(); err != nil {
+		t.Fatal(err)
+	}
+	if sg.src_head_num != 5 || sg.src_head_block_hash != recorded.Hash {
+		t.Fatalf("resumed head mismatch: %d %x", sg.src_head_num, sg.src_head_block_hash)
+	}
+	if m := sg.Manifest(); m.Number != 5 || m.Root != recorded.Root || m.Incremental() {
+		t.Fatalf("manifest mismatch: %+v", m)
+	}
+	sg.stateStop = make(chan error, 1)
+	sg.RunState()
+	if err := <-sg.stateStop; err != nil {
+		t.Fatalf("done state processed again: %v", err)
+	}
+
+	// A run on another base or off the canonical chain is not resumed
+	sg = &SnapshotGen{src_db: src, target_db: target, progress: p, base: &Manifest{Number: 3, Hash: common.Hash{3}}}
+	if err := sg.resume(); err == nil {
+		t.Fatalf("full run resumed as an incremental one")
+	}
+	incremental := *p
+	incremental.BaseNumber, incremental.BaseHash = 3, common.Hash{3}
+	sg = &SnapshotGen{src_db: src, target_db: target, progress: &incremental}
+	if err := sg.resume(); err == nil {
+		t.Fatalf("incremental run resumed without its base")
+	}
+	sg.base = &Manifest{Number: 3, Hash: common.Hash{4}}
+	if err := sg.resume(); err == nil {
+		t.Fatalf("incremental run resumed on another base")
+	}
+	sg.base = &Manifest{Number: 3, Hash: common.Hash{3}}
+	if err := sg.resume(); err != nil {
+		t.Fatal(err)
+	}
+	rawdb.WriteCanonicalHash(src, common.Hash{6}, 5)
+	if err := sg.resume(); err == nil {
+		t.Fatalf("run resumed towards a reorged head")
+	}
+}
@@ -0,0 +1,146 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/trie"
+)
+
+// layeredReader reports the trie nodes of any of its databases, the state sync
+// of an incremental snapshot skips the subtries the base snapshot already has.
+type layeredReader []trie.DatabaseReader
+
+func (self layeredReader) Get(key []byte) (value []byte, err error) {
+	for _, db := range self {
+		if value, err = db.Get(key); err == nil {
+			return
+		}
+	}
+	return
+}
+
+func (self layeredReader) Has(key []byte) (bool, error) {
+	for _, db := range self {
+		if ok, _ := db.Has(key); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// openBase opens the snapshot of the manifest, checking its head is the one of
+// the manifest.
+func openBase(manifest string, readOnly bool) (*Manifest, serodb.KeyValueStore, error) {
+	base, err := ReadManifest(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := serodb.NewDatabaseEx(filepath.Dir(manifest), 1024, 256, readOnly)
+	if err != nil {
+		return nil, nil, err
+	}
+	if head := rawdb.ReadHeadBlockHash(db); head != base.Hash {
+		db.Close()
+		return nil, nil, fmt.Errorf("base snapshot head %x is not the manifest head %x", head, base.Hash)
+	}
+	return base, db, nil
+}
+
+// headKeys are the rawdb keys of the chain head. They are applied last, so an
+// interrupted apply leaves the base head on blocks the base database has.
+var headKeys = [][]byte{[]byte("LastHeader"), []byte("LastBlock"), []byte("LastFast")}
+
+func isHeadKey(key []byte) bool {
+	for _, head := range headKeys {
+		if bytes.Equal(key, head) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEntries copies the entries of incDb but its progress and head keys to
+// baseDb.
+func applyEntries(baseDb serodb.KeyValueStore, incDb serodb.KeyValueStore) error {
+	it := incDb.NewIterator()
+	defer it.Release()
+	batch := baseDb.NewBatch()
+	for it.Next() {
+		if bytes.Equal(it.Key(), progressKey) || isHeadKey(it.Key()) {
+			continue
+		}
+		batch.Put(it.Key(), it.Value())
+		if batch.ValueSize() >= serodb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// applyHeads moves the head of baseDb to the one of incDb in a single batch.
+func applyHeads(baseDb serodb.KeyValueStore, incDb serodb.KeyValueStore) error {
+	batch := baseDb.NewBatch()
+	for _, key := range headKeys {
+		if value, err := incDb.Get(key); err == nil {
+			batch.Put(key, value)
+		}
+	}
+	return batch.Write()
+}
+
+// Apply writes the incremental snapshot in dir on top of the snapshot of the
+// base manifest and updates the manifest. The head of the base is moved and
+// the manifest written only once every other entry is applied, so an
+// interrupted apply can be run again.
+func Apply(baseManifest string, dir string) (*Manifest, error) {
+	inc, err := ReadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	if !inc.Incremental() {
+		return nil, fmt.Errorf("snapshot %s is not incremental", dir)
+	}
+	base, baseDb, err := openBase(baseManifest, false)
+	if err != nil {
+		return nil, err
+	}
+	defer baseDb.Close()
+	if inc.BaseNumber != base.Number || inc.BaseHash != base.Hash {
+		return nil, fmt.Errorf("snapshot %s is based on %d %x, not on %d %x", dir, inc.BaseNumber, inc.BaseHash, base.Number, base.Hash)
+	}
+
+	incDb, err := serodb.NewDatabaseEx(dir, 1024, 256, true)
+	if err != nil {
+		return nil, err
+	}
+	defer incDb.Close()
+
+	if err := applyEntries(baseDb, incDb); err != nil {
+		return nil, err
+	}
+	if err := applyHeads(baseDb, incDb); err != nil {
+		return nil, err
+	}
+
+	applied := &Manifest{
+		Number:     inc.Number,
+		Hash:       inc.Hash,
+		Root:       inc.Root,
+		BaseNumber: base.BaseNumber,
+		BaseHash:   base.BaseHash,
+	}
+	if err := WriteManifest(filepath.Dir(baseManifest), applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
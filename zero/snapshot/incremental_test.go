@@ -0,0 +1,141 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// writeTestSnapshot writes a snapshot database in dir with the entries and the
+// manifest.
+func writeTestSnapshot(t *testing.T, dir string, m *Manifest, entries map[string]string) {
+	db, err := serodb.NewDatabase(dir, 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawdb.WriteHeadBlockHash(db, m.Hash)
+	for k, v := range entries {
+		db.Put([]byte(k), []byte(v))
+	}
+	db.Close()
+	if err := WriteManifest(dir, m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	baseDir, incDir, otherDir := filepath.Join(dir, "base"), filepath.Join(dir, "inc"), filepath.Join(dir, "other")
+	base := &Manifest{Number: 10, Hash: common.Hash{10}, Root: common.Hash{0x10}}
+	writeTestSnapshot(t, baseDir, base, map[string]string{"a": "1", "b": "2"})
+	inc := &Manifest{Number: 20, Hash: common.Hash{20}, Root: common.Hash{0x20}, BaseNumber: 10, BaseHash: common.Hash{10}}
+	writeTestSnapshot(t, incDir, inc, map[string]string{"b": "3", "c": "4", string(progressKey): "5"})
+	other := &Manifest{Number: 20, Hash: common.Hash{21}, BaseNumber: 10, BaseHash: common.Hash{11}}
+	writeTestSnapshot(t, otherDir, other, nil)
+
+	baseManifest := filepath.Join(baseDir, ManifestName)
+	if _, err := Apply(baseManifest, baseDir); err == nil {
+		t.Fatalf("full snapshot applied")
+	}
+	if _, err := Apply(baseManifest, otherDir); err == nil {
+		t.Fatalf("snapshot of another base applied")
+	}
+
+	applied, err := Apply(baseManifest, incDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Manifest{Number: 20, Hash: common.Hash{20}, Root: common.Hash{0x20}}
+	if *applied != *want || applied.Incremental() {
+		t.Fatalf("applied manifest mismatch: %+v", applied)
+	}
+	if read, err := ReadManifest(baseManifest); err != nil || *read != *want {
+		t.Fatalf("written manifest mismatch: %+v %v", read, err)
+	}
+
+	db, err := serodb.NewDatabase(baseDir, 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for k, v := range map[string]string{"a": "1", "b": "3", "c": "4"} {
+		if value, err := db.Get([]byte(k)); err != nil || string(value) != v {
+			t.Errorf("%s: have %s %v, want %s", k, value, err, v)
+		}
+	}
+	if rawdb.ReadHeadBlockHash(db) != inc.Hash {
+		t.Errorf("head not moved to the incremental head")
+	}
+	if ok, _ := db.Has(progressKey); ok {
+		t.Errorf("progress of the incremental snapshot applied")
+	}
+}
+
+func TestApplyInterrupted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	baseDir, incDir := filepath.Join(dir, "base"), filepath.Join(dir, "inc")
+	base := &Manifest{Number: 10, Hash: common.Hash{10}, Root: common.Hash{0x10}}
+	writeTestSnapshot(t, baseDir, base, map[string]string{"a": "1"})
+	inc := &Manifest{Number: 20, Hash: common.Hash{20}, Root: common.Hash{0x20}, BaseNumber: 10, BaseHash: common.Hash{10}}
+	writeTestSnapshot(t, incDir, inc, map[string]string{"a": "2", "b": "3"})
+
+	// The apply stops once the entries are copied, before the head moves
+	baseDb, err := serodb.NewDatabase(baseDir, 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	incDb, err := serodb.NewDatabaseEx(incDir, 16, 16, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = applyEntries(baseDb, incDb)
+	incDb.Close()
+	baseDb.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The base still opens on its own head
+	baseManifest := filepath.Join(baseDir, ManifestName)
+	read, db, err := openBase(baseManifest, true)
+	if err != nil {
+		t.Fatalf("interrupted base does not open: %v", err)
+	}
+	head := rawdb.ReadHeadBlockHash(db)
+	db.Close()
+	if *read != *base || head != base.Hash {
+		t.Fatalf("interrupted base moved: %+v %x", read, head)
+	}
+
+	// Running the apply again completes it
+	applied, err := Apply(baseManifest, incDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied.Number != inc.Number || applied.Hash != inc.Hash {
+		t.Fatalf("applied manifest mismatch: %+v", applied)
+	}
+	_, db, err = openBase(baseManifest, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if value, _ := db.Get([]byte("b")); string(value) != "3" {
+		t.Errorf("entry not applied: %s", value)
+	}
+}
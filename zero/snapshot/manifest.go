@@ -0,0 +1,90 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// ManifestName is the file name of the manifest in a snapshot directory.
+const ManifestName = "snapshot.json"
+
+// Manifest describes a finished snapshot. An incremental snapshot only holds the
+// blocks after the base head and the state nodes the base does not have.
+type Manifest struct {
+	Number     uint64      `json:"number"`
+	Hash       common.Hash `json:"hash"`
+	Root       common.Hash `json:"root"`
+	BaseNumber uint64      `json:"baseNumber,omitempty"`
+	BaseHash   common.Hash `json:"baseHash"`
+}
+
+// Incremental reports whether the snapshot has to be applied on top of a base.
+func (self *Manifest) Incremental() bool {
+	return self.BaseHash != (common.Hash{})
+}
+
+// ReadManifest reads the manifest file at path.
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WriteManifest writes the manifest of the snapshot in dir.
+func WriteManifest(dir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, ManifestName+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, ManifestName))
+}
+
+var progressKey = []byte("SNAPSHOT_PROGRESS")
+
+// progress is the run recorded in the target database, the copied blocks are
+// tracked by the head of the target and the state by the committed trie nodes,
+// so an interrupted run resumes towards the same head.
+type progress struct {
+	Number     uint64
+	Hash       common.Hash
+	Root       common.Hash
+	BaseNumber uint64
+	BaseHash   common.Hash
+	StateDone  bool
+}
+
+func readProgress(db serodb.Database) *progress {
+	data, err := db.Get(progressKey)
+	if err != nil {
+		return nil
+	}
+	p := &progress{}
+	if err := rlp.DecodeBytes(data, p); err != nil {
+		return nil
+	}
+	return p
+}
+
+func writeProgress(db serodb.Putter, p *progress) error {
+	data, err := rlp.EncodeToBytes(p)
+	if err != nil {
+		return err
+	}
+	return db.Put(progressKey, data)
+}
@@ -0,0 +1,54 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := &Manifest{Number: 10, Hash: common.Hash{1}, Root: common.Hash{2}, BaseNumber: 5, BaseHash: common.Hash{3}}
+	if err := WriteManifest(dir, m); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, m) || !read.Incremental() {
+		t.Errorf("manifest mismatch: %+v", read)
+	}
+}
+
+func TestLayeredReader(t *testing.T) {
+	target, base := serodb.NewMemDatabase(), serodb.NewMemDatabase()
+	target.Put([]byte("a"), []byte("1"))
+	base.Put([]byte("b"), []byte("2"))
+	reader := layeredReader{target, base}
+
+	for key, want := range map[string]string{"a": "1", "b": "2"} {
+		if ok, _ := reader.Has([]byte(key)); !ok {
+			t.Errorf("%s not found", key)
+		}
+		if value, err := reader.Get([]byte(key)); err != nil || string(value) != want {
+			t.Errorf("%s: %s %v", key, value, err)
+		}
+	}
+	if ok, _ := reader.Has([]byte("c")); ok {
+		t.Errorf("c found")
+	}
+	if _, err := reader.Get([]byte("c")); err == nil {
+		t.Errorf("c read")
+	}
+}
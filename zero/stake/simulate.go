@@ -0,0 +1,154 @@
+package stake
+
+import (
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// Projection is the expected outcome of buying shares, assuming the share
+// pool keeps its current size and the miss rate stays what it has been.
+type Projection struct {
+	Shares    uint32
+	AvgPrice  *big.Int
+	BasePrice *big.Int
+	PoolSize  uint32
+	Fee       uint16
+	MissRate  float64
+
+	ExpectedVotes   float64
+	ExpectedMissed  float64
+	ExpectedExpired float64
+	VoteReward      *big.Int
+	ExpectedReward  *big.Int
+	// BreakEven is the first block at which the paid out principal and
+	// rewards are expected to cover the cost, 0 if it lies beyond the horizon.
+	BreakEven uint64
+}
+
+// drawn is how many of n shares are expected to be selected within blocks,
+// when MaxVoteCount shares are drawn per block out of size.
+func drawn(n uint32, size uint32, blocks uint64) float64 {
+	if size == 0 || n == 0 {
+		return 0
+	}
+	p := float64(MaxVoteCount) / float64(size)
+	if p >= 1 {
+		return float64(n)
+	}
+	return float64(n) * (1 - math.Pow(1-p, float64(blocks)))
+}
+
+// MissRate is the share of votes missed by the whole network during the
+// last statistics window.
+func (self *StakeState) MissRate() float64 {
+	missed := utils.DecodeNumber32(self.missedNum.GetValue(missedNumKey))
+	return float64(missed) / float64(getStatisticsMissWindow()*MaxVoteCount)
+}
+
+// PoolMissRate is the share of the votes a pool was selected for that it has
+// not cast itself.
+func PoolMissRate(pool *StakePool) float64 {
+	if pool.ChoicedShareNum == 0 {
+		return 0
+	}
+	return float64(pool.MissedVoteNum) / float64(pool.ChoicedShareNum)
+}
+
+// Simulate projects buying shares for amount at blockNumber over horizon
+// blocks. The shares are voted by poolId, or solo when poolId is nil.
+func (self *StakeState) Simulate(amount *big.Int, poolId *common.Hash, blockNumber uint64, horizon uint64) (*Projection, error) {
+	num, avgPrice, basePrice := self.CaleAvgPrice(amount)
+	if num == 0 {
+		return nil, errors.New("amount is less than the share price")
+	}
+	var pool *StakePool
+	missRate := self.MissRate()
+	if poolId != nil {
+		if pool = self.GetStakePool(*poolId); pool == nil {
+			return nil, errors.New("stake pool not exists")
+		}
+		if pool.Closed {
+			return nil, errors.New("stake pool has closed")
+		}
+		missRate = PoolMissRate(pool)
+	}
+	return project(num, avgPrice, basePrice, self.ShareSize(), pool, missRate, blockNumber, horizon), nil
+}
+
+func project(num uint32, avgPrice, basePrice *big.Int, size uint32, pool *StakePool, missRate float64, blockNumber uint64, horizon uint64) *Projection {
+	if missRate > 1 {
+		missRate = 1
+	}
+	p := &Projection{
+		Shares:    num,
+		AvgPrice:  avgPrice,
+		BasePrice: basePrice,
+		PoolSize:  size,
+		MissRate:  missRate,
+	}
+	size += num
+
+	soloReward, reward := GetPosRewardBySize(uint64(size), int64(blockNumber))
+	if pool != nil {
+		p.Fee = pool.Fee
+		p.VoteReward = new(big.Int).Sub(reward, new(big.Int).Div(new(big.Int).Mul(reward, big.NewInt(int64(pool.Fee))), big.NewInt(10000)))
+	} else {
+		p.VoteReward = soloReward
+	}
+
+	outOfDate := getOutOfDateWindow()
+	missVoted := getMissVotedWindow()
+	window := horizon
+	if window > outOfDate {
+		window = outOfDate
+	}
+	selected := drawn(num, size, window)
+	p.ExpectedVotes = selected * (1 - missRate)
+	p.ExpectedMissed = selected * missRate
+	if horizon >= outOfDate {
+		p.ExpectedExpired = float64(num) - selected
+	}
+	p.ExpectedReward = mulFloat(p.VoteReward, p.ExpectedVotes)
+
+	// value paid back after t blocks, income is paid once per pay period
+	cost := new(big.Int).Mul(avgPrice, big.NewInt(int64(num)))
+	returned := func(t uint64) *big.Int {
+		w := t
+		if w > outOfDate {
+			w = outOfDate
+		}
+		s := drawn(num, size, w)
+		shares := s * (1 - missRate)
+		if t >= outOfDate {
+			shares += float64(num) - s
+		}
+		if t >= missVoted {
+			shares += s * missRate
+		}
+		value := mulFloat(avgPrice, shares)
+		return value.Add(value, mulFloat(p.VoteReward, s*(1-missRate)))
+	}
+	payPeriod := getPayPeriod()
+	if horizon > payPeriod && returned(horizon-payPeriod).Cmp(cost) >= 0 {
+		left, right := uint64(1), horizon-payPeriod
+		for left < right {
+			mid := (left + right) / 2
+			if returned(mid).Cmp(cost) >= 0 {
+				right = mid
+			} else {
+				left = mid + 1
+			}
+		}
+		p.BreakEven = blockNumber + left + payPeriod
+	}
+	return p
+}
+
+func mulFloat(x *big.Int, f float64) *big.Int {
+	ret, _ := new(big.Float).Mul(new(big.Float).SetInt(x), big.NewFloat(f)).Int(nil)
+	return ret
+}
@@ -0,0 +1,30 @@
+package stake
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	price := new(big.Int).Set(basePrice)
+	pool := &StakePool{Fee: 2500}
+
+	p := project(10, price, price, 1000, pool, 0.1, 2000000, getOutOfDateWindow()+getPayPeriod())
+	if p.ExpectedVotes+p.ExpectedMissed+p.ExpectedExpired > 10.0001 {
+		t.Errorf("more than all shares accounted: %v %v %v", p.ExpectedVotes, p.ExpectedMissed, p.ExpectedExpired)
+	}
+	if p.ExpectedVotes <= 0 || p.ExpectedReward.Sign() <= 0 {
+		t.Errorf("expected votes and rewards, have %v %v", p.ExpectedVotes, p.ExpectedReward)
+	}
+	_, reward := GetPosRewardBySize(1010, 2000000)
+	if want := new(big.Int).Sub(reward, new(big.Int).Div(reward, big.NewInt(4))); p.VoteReward.Cmp(want) != 0 {
+		t.Errorf("vote reward mismatch: have %v, want %v", p.VoteReward, want)
+	}
+	if p.BreakEven == 0 || p.BreakEven > 2000000+getOutOfDateWindow()+getPayPeriod() {
+		t.Errorf("break even out of range: %v", p.BreakEven)
+	}
+
+	if short := project(10, price, price, 1000, pool, 0.1, 2000000, 10); short.BreakEven != 0 {
+		t.Errorf("break even within a too short horizon: %v", short.BreakEven)
+	}
+}
@@ -70,6 +70,10 @@ func GetLockingBlockNum() uint64 {
 	return lockingBlockNum
 }
 
+func GetStatisticsMissWindow() uint64 {
+	return getStatisticsMissWindow()
+}
+
 func getStatisticsMissWindow() uint64 {
 	if seroparam.Is_Dev() {
 		return 10
@@ -17,6 +17,9 @@ import (
 	"github.com/sero-cash/go-sero/common/hexutil"
 )
 
+// MaxOIns is the number of o inputs a single transaction may carry.
+const MaxOIns = 2500
+
 func SelectUtxos(param *PreTxParam, generator TxParamGenerator) (utxos Utxos, e error) {
 	if len(param.Roots) > 0 {
 		for _, root := range param.Roots {
@@ -60,7 +63,7 @@ func SelectUtxos(param *PreTxParam, generator TxParamGenerator) (utxos Utxos, e
 		}
 
 		for _, tkn := range ck.Tkns() {
-			outs, remain := generator.FindRoots(&param.From, utils.Uint256ToCurrency(&tkn.Currency), tkn.Value.ToIntRef())
+			outs, remain, _ := generator.FindRoots(&param.From, utils.Uint256ToCurrency(&tkn.Currency), tkn.Value.ToIntRef(), param.Selector)
 			if remain.Sign() <= 0 {
 				utxos = append(utxos, outs...)
 			} else {
@@ -118,8 +121,8 @@ func BuildTxParam(
 		}
 	}
 
-	if oins_count > 2500 {
-		e = fmt.Errorf("o_ins count > 2500")
+	if oins_count > MaxOIns {
+		e = fmt.Errorf("o_ins count > %d", MaxOIns)
 		return
 	}
 
@@ -140,7 +143,7 @@ func BuildTxParam(
 			pkr = CreatePkr(&pk, 0)
 		}
 		ck.AddOut(&reception.Asset)
-		Outs = append(Outs, txtool.GOut{PKr: pkr, Asset: reception.Asset})
+		Outs = append(Outs, txtool.GOut{PKr: pkr, Asset: reception.Asset, Memo: reception.Memo})
 	}
 
 	if cmdsAsset := param.Cmds.OutAsset(); cmdsAsset != nil {
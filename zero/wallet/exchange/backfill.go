@@ -0,0 +1,115 @@
+package exchange
+
+import (
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// backfillWorkers is how many lagging accounts are indexed at the same time.
+var backfillWorkers = 4
+
+// backfillDistance is how far an account may fall behind the accounts that
+// follow the chain head before it is indexed on its own.
+var backfillDistance = fetchCount
+
+// Backfill describes an account that is catching up in the background.
+type Backfill struct {
+	Start   uint64
+	Target  uint64
+	Running bool
+	Created time.Time
+}
+
+// laggingAccounts returns the highest indexed number and the accounts that are
+// more than distance blocks behind it.
+func laggingAccounts(numbers map[c_type.Uint512]uint64, distance uint64) (lead uint64, lagging []c_type.Uint512) {
+	for _, num := range numbers {
+		if num > lead {
+			lead = num
+		}
+	}
+	for pk, num := range numbers {
+		if num+distance < lead {
+			lagging = append(lagging, pk)
+		}
+	}
+	return
+}
+
+// headNumbers returns the numbers of the accounts that follow the chain head.
+func (self *Exchange) headNumbers() map[c_type.Uint512]uint64 {
+	numbers := map[c_type.Uint512]uint64{}
+	self.numbers.Range(func(key, value interface{}) bool {
+		if _, ok := self.backfills.Load(key); !ok {
+			numbers[key.(c_type.Uint512)] = value.(uint64)
+		}
+		return true
+	})
+	return numbers
+}
+
+// scheduleBackfills moves the accounts that fell far behind, such as newly
+// imported ones, out of the head indexing loop into their own workers.
+func (self *Exchange) scheduleBackfills() {
+	lead, lagging := laggingAccounts(self.headNumbers(), backfillDistance)
+	for _, pk := range lagging {
+		value, _ := self.numbers.Load(pk)
+		backfill := &Backfill{Start: value.(uint64), Target: lead, Created: time.Now()}
+		if _, loaded := self.backfills.LoadOrStore(pk, backfill); !loaded {
+			log.Info("Exchange schedule backfill", "pk", *utils.Base58Encode(pk[:]), "from", backfill.Start, "to", lead)
+			go self.backfill(pk, *backfill)
+		}
+	}
+}
+
+// backfill indexes pk alone until it is close enough to the head accounts to
+// rejoin them.
+func (self *Exchange) backfill(pk c_type.Uint512, backfill Backfill) {
+	self.workers <- struct{}{}
+	defer func() {
+		<-self.workers
+		self.backfills.Delete(pk)
+	}()
+
+	state := backfill
+	state.Running = true
+	for {
+		if txtool.Ref_inst.Bc == nil || !txtool.Ref_inst.Bc.IsValid() {
+			return
+		}
+		value, ok := self.numbers.Load(pk)
+		if !ok {
+			return
+		}
+		start := value.(uint64)
+		lead, _ := laggingAccounts(self.headNumbers(), backfillDistance)
+		state.Target = lead
+		snapshot := state
+		self.backfills.Store(pk, &snapshot)
+		if start+backfillDistance >= lead {
+			log.Info("Exchange backfill done", "pk", *utils.Base58Encode(pk[:]), "blockNumber", start)
+			return
+		}
+		count := lead - start
+		if count > fetchCount {
+			count = fetchCount
+		}
+		if self.fetchAndIndexUtxo(start, count, []c_type.Uint512{pk}) < int(count) {
+			return
+		}
+	}
+}
+
+// GetBackfill returns the background indexing state of pk, nil if pk follows
+// the chain head.
+func (self *Exchange) GetBackfill(pk c_type.Uint512) *Backfill {
+	if value, ok := self.backfills.Load(pk); ok {
+		backfill := *value.(*Backfill)
+		return &backfill
+	}
+	return nil
+}
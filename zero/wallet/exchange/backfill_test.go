@@ -0,0 +1,116 @@
+package exchange
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+func TestLaggingAccounts(t *testing.T) {
+	numbers := map[c_type.Uint512]uint64{
+		{1}: 10000,
+		{2}: 9990,
+		{3}: 100,
+		{4}: 0,
+	}
+	lead, lagging := laggingAccounts(numbers, 5000)
+	if lead != 10000 {
+		t.Errorf("lead mismatch: have %d, want %d", lead, 10000)
+	}
+	if len(lagging) != 2 {
+		t.Fatalf("lagging mismatch: have %v, want 2 accounts", lagging)
+	}
+	for _, pk := range lagging {
+		if pk != (c_type.Uint512{3}) && pk != (c_type.Uint512{4}) {
+			t.Errorf("unexpected lagging account %v", pk[0])
+		}
+	}
+
+	if _, lagging := laggingAccounts(map[c_type.Uint512]uint64{{1}: 0}, 5000); len(lagging) != 0 {
+		t.Errorf("single account must not lag: %v", lagging)
+	}
+}
+
+// backfillChain is a valid chain whose head is at head.
+type backfillChain struct {
+	txtool.BlockChain
+	head uint64
+}
+
+func (self *backfillChain) IsValid() bool {
+	return true
+}
+
+func (self *backfillChain) GetCurrenHeader() *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(self.head)}
+}
+
+func TestBackfillNewAccount(t *testing.T) {
+	defer func(count, distance uint64, get func(uint64, uint64) ([]txtool.Block, error)) {
+		fetchCount, backfillDistance, getBlocksInfo = count, distance, get
+	}(fetchCount, backfillDistance, getBlocksInfo)
+	fetchCount, backfillDistance = 10, 10
+
+	chain := &backfillChain{head: 100}
+	bc := txtool.Ref_inst.Bc
+	defer func() { txtool.Ref_inst.Bc = bc }()
+	txtool.Ref_inst.Bc = chain
+
+	// The blocks are empty, the first load waits for the test
+	gate := make(chan struct{})
+	starts := make(chan uint64, 100)
+	getBlocksInfo = func(start uint64, count uint64) (blocks []txtool.Block, e error) {
+		<-gate
+		starts <- start
+		for num := start; num < start+count && num <= chain.head; num++ {
+			blocks = append(blocks, txtool.Block{Num: hexutil.Uint64(num), Hash: c_type.Uint256{byte(num)}})
+		}
+		return
+	}
+
+	ex, remove := newHistoryExchange(t)
+	defer remove()
+	ex.workers = make(chan struct{}, backfillWorkers)
+	head, imported := c_type.Uint512{1}, c_type.Uint512{2}
+	ex.numbers.Store(head, uint64(101))
+	ex.numbers.Store(imported, uint64(20))
+
+	// Only the imported account is moved to a backfill from its start number
+	ex.scheduleBackfills()
+	if backfill := ex.GetBackfill(imported); backfill == nil || backfill.Start != 20 || backfill.Target != 101 {
+		t.Fatalf("backfill mismatch: %v", backfill)
+	}
+	if backfill := ex.GetBackfill(head); backfill != nil {
+		t.Fatalf("head account backfilled: %v", backfill)
+	}
+	if numbers := ex.headNumbers(); len(numbers) != 1 || numbers[head] != 101 {
+		t.Fatalf("head accounts mismatch: %v", numbers)
+	}
+	close(gate)
+
+	for deadline := time.Now().Add(5 * time.Second); ex.GetBackfill(imported) != nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("backfill not done")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if start := <-starts; start != 20 {
+		t.Fatalf("backfill start mismatch: have %d, want 20", start)
+	}
+	if value, _ := ex.numbers.Load(imported); value.(uint64)+backfillDistance < 101 {
+		t.Fatalf("imported account not caught up: %d", value)
+	}
+	if value, _ := ex.numbers.Load(head); value.(uint64) != 101 {
+		t.Fatalf("head account moved: %d", value)
+	}
+
+	// Caught up, the imported account follows the head again
+	if numbers := ex.headNumbers(); len(numbers) != 2 {
+		t.Fatalf("head accounts mismatch: %v", numbers)
+	}
+}
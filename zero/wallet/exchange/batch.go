@@ -0,0 +1,199 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/prepare"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// maxTxOuts is the number of outputs, change included, a transaction may carry.
+var maxTxOuts = seroparam.MAX_Z_OUT_LENGTH_SIP2
+
+var defaultBatchGas = uint64(25000)
+
+type BatchReception struct {
+	Addr     c_type.PKr
+	Currency string
+	Value    *big.Int
+	Memo     c_type.Uint512
+}
+
+type BatchTxParam struct {
+	From       c_type.Uint512
+	RefundTo   *c_type.PKr
+	Receptions []BatchReception
+	Gas        uint64
+	GasPrice   *big.Int
+	Selector   string
+}
+
+type FeeBreakdown struct {
+	Currency string
+	Gas      uint64
+	GasPrice *big.Int
+	Value    *big.Int
+}
+
+type BatchTx struct {
+	Tx         *txtool.GTx
+	Receptions []int
+	Ins        int
+	Outs       int
+	Fee        FeeBreakdown
+}
+
+// splitReceptions groups the receptions into as few transactions as the
+// output limit allows, leaving room for one change output per currency.
+func splitReceptions(receptions []BatchReception) (groups [][]int) {
+	group := []int{}
+	currencies := map[string]bool{"SERO": true}
+	for i, reception := range receptions {
+		currency := strings.ToUpper(reception.Currency)
+		change := len(currencies)
+		if !currencies[currency] {
+			change++
+		}
+		if len(group) > 0 && len(group)+1+change > maxTxOuts {
+			groups = append(groups, group)
+			group = []int{}
+			currencies = map[string]bool{"SERO": true}
+		}
+		group = append(group, i)
+		currencies[currency] = true
+	}
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+	return
+}
+
+// GenBatchTx pays every reception with as few signed transactions as possible.
+// The utxos of every transaction are selected and reserved before any of them
+// is built, and released together: if any transaction cannot be funded or
+// built, nothing stays reserved.
+func (self *Exchange) GenBatchTx(param *BatchTxParam) (txs []BatchTx, e error) {
+	if len(param.Receptions) == 0 {
+		e = errors.New("have no receptions")
+		return
+	}
+	account := self.getAccountByPk(param.From)
+	if account == nil {
+		e = errors.New("not found Pk")
+		return
+	}
+	refundTo := param.RefundTo
+	if refundTo == nil {
+		refundTo = &account.mainPkr
+	}
+	gas := param.Gas
+	if gas == 0 {
+		gas = defaultBatchGas
+	}
+	gasPrice := param.GasPrice
+	if gasPrice == nil || gasPrice.Sign() == 0 {
+		gasPrice = big.NewInt(1000000000)
+	}
+	fee := assets.Token{
+		utils.CurrencyToUint256("SERO"),
+		utils.U256(*new(big.Int).Mul(new(big.Int).SetUint64(gas), gasPrice)),
+	}
+
+	leased := []c_type.Uint256{}
+	defer func() {
+		if e != nil {
+			self.releaseRoots(leased)
+			txs = nil
+		}
+	}()
+
+	groups := splitReceptions(param.Receptions)
+	bparams := []prepare.BeforeTxParam{}
+	for _, group := range groups {
+		amounts := map[string]*big.Int{"SERO": new(big.Int).Set(fee.Value.ToIntRef())}
+		receptions := []prepare.Reception{}
+		for _, i := range group {
+			r := &param.Receptions[i]
+			if r.Value == nil || r.Value.Sign() <= 0 {
+				e = fmt.Errorf("reception %v value must > 0", i)
+				return
+			}
+			currency := strings.ToUpper(r.Currency)
+			if amount, ok := amounts[currency]; ok {
+				amount.Add(amount, r.Value)
+			} else {
+				amounts[currency] = new(big.Int).Set(r.Value)
+			}
+			receptions = append(receptions, prepare.Reception{
+				Addr: r.Addr,
+				Asset: assets.Asset{
+					Tkn: &assets.Token{
+						Currency: utils.CurrencyToUint256(currency),
+						Value:    utils.U256(*r.Value),
+					},
+				},
+				Memo: r.Memo,
+			})
+		}
+
+		var roots prepare.Utxos
+		selected, err := self.selectAndReserve("genBatchTx", func() ([]c_type.Uint256, error) {
+			for currency, amount := range amounts {
+				utxos, remain, _ := self.selectUtxos(&param.From, currency, amount, param.Selector)
+				if remain.Sign() > 0 {
+					return nil, fmt.Errorf("no enough unlocked utxos of %v", currency)
+				}
+				roots = append(roots, UtxoList(utxos).Roots()...)
+			}
+			return roots.Roots(), nil
+		})
+		if err != nil {
+			e = err
+			return
+		}
+		leased = append(leased, selected...)
+
+		bparams = append(bparams, prepare.BeforeTxParam{
+			fee,
+			*gasPrice,
+			roots,
+			*refundTo,
+			receptions,
+			prepare.Cmds{},
+		})
+	}
+
+	for i := range bparams {
+		pretx, gtx, err := self.genTx(account, &bparams[i], "genBatchTx")
+		if err != nil {
+			e = err
+			return
+		}
+		gtx.Hash = gtx.Tx.ToHash()
+		for _, in := range pretx.Ins {
+			gtx.Roots = append(gtx.Roots, in.Out.Root)
+		}
+		txs = append(txs, BatchTx{
+			Tx:         gtx,
+			Receptions: groups[i],
+			Ins:        len(pretx.Ins),
+			Outs:       len(pretx.Outs),
+			Fee: FeeBreakdown{
+				Currency: "SERO",
+				Gas:      pretx.Gas,
+				GasPrice: new(big.Int).Set(gasPrice),
+				Value:    new(big.Int).Set(fee.Value.ToIntRef()),
+			},
+		})
+	}
+	log.Info("Exchange genBatchTx success", "receptions", len(param.Receptions), "txs", len(txs))
+	return
+}
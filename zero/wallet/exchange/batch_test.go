@@ -0,0 +1,96 @@
+package exchange
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func TestSplitReceptions(t *testing.T) {
+	defer func(n int) { maxTxOuts = n }(maxTxOuts)
+	maxTxOuts = 5
+
+	receptions := []BatchReception{}
+	for _, currency := range []string{"SERO", "sero", "SERO", "AAA", "AAA", "SERO", "BBB"} {
+		receptions = append(receptions, BatchReception{Currency: currency, Value: big.NewInt(1)})
+	}
+
+	groups := splitReceptions(receptions)
+	want := [][]int{{0, 1, 2}, {3, 4, 5}, {6}}
+	if len(groups) != len(want) {
+		t.Fatalf("group count mismatch: have %v, want %v", groups, want)
+	}
+	for i := range want {
+		if len(groups[i]) != len(want[i]) {
+			t.Fatalf("group %d mismatch: have %v, want %v", i, groups[i], want[i])
+		}
+		for j := range want[i] {
+			if groups[i][j] != want[i][j] {
+				t.Errorf("group %d mismatch: have %v, want %v", i, groups[i], want[i])
+			}
+		}
+	}
+}
+
+// putSpendableUtxo indexes an unspent utxo of value SERO for pk.
+func putSpendableUtxo(ex *Exchange, pk c_type.Uint512, n byte, value uint64) Utxo {
+	utxo := Utxo{
+		Root:  c_type.Uint256{n},
+		Nil:   c_type.Uint256{n, 1},
+		Num:   1,
+		Asset: assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.NewU256(value)}},
+	}
+	data, _ := rlp.EncodeToBytes(&utxo)
+	ex.db.Put(rootKey(utxo.Root), data)
+	pkKey := utxoPkKey(pk, utxo.Asset.Tkn.Currency[:], &utxo.Root)
+	ex.db.Put(pkKey, []byte{0})
+	ex.db.Put(nilKey(utxo.Nil), pkKey)
+	ex.db.Put(nilKey(utxo.Root), pkKey)
+	return utxo
+}
+
+func TestGenBatchTxRollback(t *testing.T) {
+	defer func(n int) { maxTxOuts = n }(maxTxOuts)
+	maxTxOuts = 3
+
+	ex, remove := newHistoryExchange(t)
+	defer remove()
+	pk := c_type.Uint512{1}
+	ex.accounts.Store(pk, &Account{pk: &pk})
+
+	free := putSpendableUtxo(ex, pk, 1, 100)
+	taken := putSpendableUtxo(ex, pk, 2, 100)
+	ex.reserve(taken.Root, "other")
+
+	// The first group is funded by the free utxo, the second one finds none
+	param := &BatchTxParam{
+		From:     pk,
+		Gas:      1,
+		GasPrice: big.NewInt(1),
+		Receptions: []BatchReception{
+			{Currency: "SERO", Value: big.NewInt(50)},
+			{Currency: "SERO", Value: big.NewInt(50)},
+		},
+	}
+	if groups := splitReceptions(param.Receptions); len(groups) != 2 {
+		t.Fatalf("group count mismatch: have %d, want 2", len(groups))
+	}
+	txs, err := ex.GenBatchTx(param)
+	if err == nil || txs != nil {
+		t.Fatalf("batch of the unfunded group generated: %v %v", txs, err)
+	}
+
+	// The reservation of the first group is rolled back, the one of another
+	// path is kept
+	if _, ok := ex.usedFlag.Load(free.Root); ok {
+		t.Errorf("reservation of the failed batch not rolled back")
+	}
+	list := ex.ListReservations(nil)
+	if len(list) != 1 || list[0].Root != taken.Root || list[0].Owner != "other" {
+		t.Fatalf("reservations mismatch: %v", list)
+	}
+}
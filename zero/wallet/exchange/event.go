@@ -0,0 +1,46 @@
+package exchange
+
+import (
+	"math/big"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/event"
+)
+
+const (
+	UtxoReceived = "received"
+	UtxoSpent    = "spent"
+)
+
+// UtxoEvent is posted when indexing adds a UTXO to, or spends one from, a
+// watched account. A received UTXO whose block is rolled back by a reorg is
+// posted again with Removed set.
+type UtxoEvent struct {
+	Kind     string
+	Pk       c_type.Uint512
+	Pkr      c_type.PKr
+	Currency string
+	Amount   *big.Int
+	Root     c_type.Uint256
+	Num      uint64
+	Removed  bool
+}
+
+func newUtxoEvent(kind string, pk c_type.Uint512, utxo *Utxo, num uint64) UtxoEvent {
+	return UtxoEvent{
+		Kind:     kind,
+		Pk:       pk,
+		Pkr:      utxo.Pkr,
+		Currency: common.BytesToString(utxo.Asset.Tkn.Currency[:]),
+		Amount:   new(big.Int).Set(utxo.Asset.Tkn.Value.ToIntRef()),
+		Root:     utxo.Root,
+		Num:      num,
+	}
+}
+
+// SubscribeUtxoEvent registers a subscription for the UTXO changes produced by
+// each indexing pass.
+func (self *Exchange) SubscribeUtxoEvent(ch chan<- []UtxoEvent) event.Subscription {
+	return self.feed.Subscribe(ch)
+}
@@ -0,0 +1,114 @@
+package exchange
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// eventChain serves the canonical headers and has no spending transactions.
+type eventChain struct {
+	reorgChain
+}
+
+func (self *eventChain) GetBlockByNumber(num uint64) *types.Block {
+	return nil
+}
+
+func newEventHeader(num uint64, extra string) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(num), Time: new(big.Int).SetUint64(num * 10), Extra: []byte(extra)}
+}
+
+func expectUtxoEvent(t *testing.T, ch chan []UtxoEvent, kind string, root c_type.Uint256, num uint64, removed bool) {
+	select {
+	case events := <-ch:
+		if len(events) != 1 {
+			t.Fatalf("events mismatch: have %d, want 1", len(events))
+		}
+		ev := events[0]
+		if ev.Kind != kind || ev.Root != root || ev.Num != num || ev.Removed != removed {
+			t.Fatalf("event mismatch: have %s/%d/%v, want %s/%d/%v", ev.Kind, ev.Num, ev.Removed, kind, num, removed)
+		}
+		if ev.Currency != "SERO" || ev.Amount.Int64() != 100 {
+			t.Fatalf("event asset mismatch: %s %v", ev.Currency, ev.Amount)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("%s event not posted", kind)
+	}
+}
+
+func TestUtxoEvents(t *testing.T) {
+	chain := &eventChain{reorgChain{headers: map[uint64]*types.Header{}}}
+	var blocks []txtool.Block
+	for num := uint64(1); num <= 5; num++ {
+		header := newEventHeader(num, "a")
+		chain.headers[num] = header
+		blocks = append(blocks, txtool.Block{Num: hexutil.Uint64(num), Hash: *header.Hash().HashToUint256()})
+	}
+	bc := txtool.Ref_inst.Bc
+	defer func() { txtool.Ref_inst.Bc = bc }()
+	txtool.Ref_inst.Bc = chain
+
+	ex, remove := newHistoryExchange(t)
+	defer remove()
+	ch := make(chan []UtxoEvent, 4)
+	sub := ex.SubscribeUtxoEvent(ch)
+	defer sub.Unsubscribe()
+
+	pk := c_type.Uint512{1}
+	pks := []c_type.Uint512{pk}
+	ex.numbers.Store(pk, uint64(1))
+	utxo := Utxo{
+		Root:   c_type.Uint256{1},
+		Nil:    c_type.Uint256{1, 1},
+		TxHash: c_type.Uint256{1, 2},
+		Num:    2,
+		Asset:  assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.NewU256(100)}},
+	}
+
+	// A pass receiving the utxo posts it once written
+	utxos := map[PkKey][]Utxo{{key: pk, Num: 2}: {utxo}}
+	infos := map[uint64]*BlockInfo{2: {Num: 2, Hash: blocks[1].Hash, Outs: []Utxo{utxo}}}
+	if count := ex.indexUtxos(1, pks, blocks[:3], utxos, infos, nil); count != 3 {
+		t.Fatalf("indexed blocks mismatch: have %d, want 3", count)
+	}
+	expectUtxoEvent(t, ch, UtxoReceived, utxo.Root, 2, false)
+
+	// A later pass spending its nil posts the spend
+	infos = map[uint64]*BlockInfo{5: {Num: 5, Hash: blocks[4].Hash, Ins: []c_type.Uint256{utxo.Root}}}
+	nils := map[c_type.Uint256]uint64{utxo.Nil: 5}
+	if count := ex.indexUtxos(4, pks, blocks[3:], nil, infos, nils); count != 2 {
+		t.Fatalf("indexed blocks mismatch: have %d, want 2", count)
+	}
+	expectUtxoEvent(t, ch, UtxoSpent, utxo.Root, 5, false)
+
+	// A stale pass is discarded without events
+	if count := ex.indexUtxos(1, pks, blocks[:3], utxos, nil, nil); count != 0 {
+		t.Fatalf("stale pass indexed %d blocks", count)
+	}
+
+	// Replacing the receiving block removes the utxo
+	chain.headers[2] = newEventHeader(2, "b")
+	ex.unwindReorg()
+	expectUtxoEvent(t, ch, UtxoReceived, utxo.Root, 2, true)
+	if has, _ := ex.db.Has(rootKey(utxo.Root)); has {
+		t.Errorf("removed utxo still indexed")
+	}
+	if value, _ := ex.numbers.Load(pk); value.(uint64) != 1 {
+		t.Errorf("account number mismatch: have %d, want 1", value)
+	}
+
+	// Nothing is left to post
+	select {
+	case events := <-ch:
+		t.Fatalf("unexpected events: %v", events)
+	default:
+	}
+}
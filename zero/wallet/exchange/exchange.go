@@ -101,21 +101,28 @@ type FetchJob struct {
 }
 
 type Exchange struct {
-	db             *serodb.LDBDatabase
+	db             serodb.KeyValueStore
 	txPool         *core.TxPool
 	accountManager *accounts.Manager
 
 	accounts    sync.Map
 	pkrAccounts sync.Map
 
-	usedFlag sync.Map
-	numbers  sync.Map
+	usedFlag  sync.Map
+	numbers   sync.Map
+	backfills sync.Map
+	workers   chan struct{}
+	indexLock sync.Mutex
+	undoSeq   uint64
 
 	feed    event.Feed
 	updater event.Subscription        // Wallet update subscriptions for all backends
 	update  chan accounts.WalletEvent // Subscription sink for backend wallet changes
 	quit    chan chan error
 	lock    sync.RWMutex
+
+	// selectLock is held from the selection of utxos until they are reserved
+	selectLock sync.Mutex
 }
 
 var current_exchange *Exchange
@@ -134,14 +141,16 @@ func NewExchange(dbpath string, txPool *core.TxPool, accountManager *accounts.Ma
 		accountManager: accountManager,
 		update:         update,
 		updater:        updater,
+		workers:        make(chan struct{}, backfillWorkers),
 	}
 	current_exchange = exchange
 
-	db, err := serodb.NewLDBDatabase(dbpath, 1024, 1024)
+	db, err := serodb.NewDatabase(dbpath, 1024, 1024)
 	if err != nil {
 		panic(err)
 	}
 	exchange.db = db
+	exchange.loadUndoSeq()
 
 	exchange.numbers = sync.Map{}
 	exchange.accounts = sync.Map{}
@@ -153,6 +162,7 @@ func NewExchange(dbpath string, txPool *core.TxPool, accountManager *accounts.Ma
 	exchange.usedFlag = sync.Map{}
 
 	AddJob("0/10 * * * * ?", exchange.fetchBlockInfo)
+	AddJob("0/30 * * * * ?", exchange.releaseExpired)
 
 	if autoMerge {
 		AddJob("0 0/5 * * * ?", exchange.merge)
@@ -586,9 +596,19 @@ func (self *Exchange) GenTxWithSign(param prepare.PreTxParam) (pretx *txtool.GTx
 		return
 	}
 	var roots prepare.Utxos
-	if roots, e = prepare.SelectUtxos(&param, self); e != nil {
+	var leased []c_type.Uint256
+	if leased, e = self.selectAndReserve("genTxWithSign", func() ([]c_type.Uint256, error) {
+		var err error
+		roots, err = prepare.SelectUtxos(&param, self)
+		return roots.Roots(), err
+	}); e != nil {
 		return
 	}
+	defer func() {
+		if e != nil {
+			self.releaseRoots(leased)
+		}
+	}()
 
 	var account *Account
 	if value, ok := self.accounts.Load(param.From); ok {
@@ -614,7 +634,7 @@ func (self *Exchange) GenTxWithSign(param prepare.PreTxParam) (pretx *txtool.GTx
 		param.Cmds,
 	}
 
-	if pretx, tx, e = self.genTx(account, &bparam); e != nil {
+	if pretx, tx, e = self.genTx(account, &bparam, "genTxWithSign"); e != nil {
 		log.Error("Exchange genTx", "error", e)
 		return
 	}
@@ -655,8 +675,8 @@ func (self *Exchange) ClearTxParam(txParam *txtool.GTxParam) (count int) {
 	return
 }
 
-func (self *Exchange) genTx(account *Account, param *prepare.BeforeTxParam) (txParam *txtool.GTxParam, tx *txtool.GTx, e error) {
-	if txParam, e = self.buildTxParam(param); e != nil {
+func (self *Exchange) genTx(account *Account, param *prepare.BeforeTxParam, owner string) (txParam *txtool.GTxParam, tx *txtool.GTx, e error) {
+	if txParam, e = self.buildTxParam(param, owner); e != nil {
 		return
 	}
 
@@ -674,6 +694,12 @@ func (self *Exchange) genTx(account *Account, param *prepare.BeforeTxParam) (txP
 		return
 	} else {
 		tx = &gtx
+		hash := gtx.Tx.ToHash()
+		roots := []c_type.Uint256{}
+		for _, in := range txParam.Ins {
+			roots = append(roots, in.Out.Root)
+		}
+		self.Lease(roots, "", 0, &hash)
 		return
 	}
 }
@@ -743,8 +769,8 @@ func (self *Exchange) getUtxo(root c_type.Uint256) (utxo Utxo, e error) {
 		utxo.Ignore = true
 	}
 
-	if value, ok := self.usedFlag.Load(utxo.Root); ok {
-		utxo.flag = value.(int)
+	if _, ok := self.usedFlag.Load(utxo.Root); ok {
+		utxo.flag = 1
 	}
 	return
 }
@@ -808,6 +834,51 @@ func (self *Exchange) findUtxos(pk *c_type.Uint512, currency string, amount *big
 	return
 }
 
+func (self *Exchange) spendableUtxos(pk *c_type.Uint512, currency string) (utxos UtxoList) {
+	currency = strings.ToUpper(currency)
+	prefix := append(pkPrefix, append(pk[:], common.LeftPadBytes([]byte(currency), 32)...)...)
+	iterator := self.db.NewIteratorWithPrefix(prefix)
+
+	for iterator.Next() {
+		key := iterator.Key()
+		var root c_type.Uint256
+		copy(root[:], key[98:130])
+
+		if utxo, err := self.getUtxo(root); err == nil {
+			if utxo.Ignore || utxo.Asset.Tkn == nil {
+				continue
+			}
+			if _, ok := self.usedFlag.Load(utxo.Root); !ok {
+				utxos = append(utxos, utxo)
+			}
+		}
+	}
+	iterator.Release()
+	return
+}
+
+// selectUtxos finds the utxos paying amount with the named coin selector and
+// reports the strategy that produced the result.
+func (self *Exchange) selectUtxos(pk *c_type.Uint512, currency string, amount *big.Int, name string) (utxos []Utxo, remain *big.Int, used string) {
+	selector, err := GetCoinSelector(name)
+	if err != nil || selector.Name() == DefaultSelector {
+		// the default order is the one of the database, no need to load
+		// every candidate
+		utxos, remain = self.findUtxos(pk, currency, amount)
+		return utxos, remain, DefaultSelector
+	}
+
+	candidates := self.spendableUtxos(pk, currency)
+	utxos, remain = selector.Select(candidates, amount)
+	used = selector.Name()
+	if remain.Sign() > 0 && used == BranchAndBoundSelector {
+		// no exact match, pay with change instead
+		utxos, remain = coinSelectors[LargestFirstSelector].Select(candidates, amount)
+		used = LargestFirstSelector
+	}
+	return
+}
+
 func DecOuts(outs []txtool.Out, skr *c_type.PKr) (douts []txtool.DOut) {
 	tk := c_type.Tk{}
 	copy(tk[:], skr[:])
@@ -839,24 +910,30 @@ func (c uint64Slice) Less(i, j int) bool {
 
 var fetchCount = uint64(5000)
 
+// getBlocksInfo loads the confirmed blocks to index.
+var getBlocksInfo = flight.SRI_Inst.GetBlocksInfo
+
 func (self *Exchange) fetchBlockInfo() {
 	if txtool.Ref_inst.Bc == nil || !txtool.Ref_inst.Bc.IsValid() {
 		return
 	}
+	self.unwindReorg()
+
+	if !self.migrateHistory() {
+		return
+	}
+	self.scheduleBackfills()
 	for {
 		indexs := map[uint64][]c_type.Uint512{}
 		orders := uint64Slice{}
-		self.numbers.Range(func(key, value interface{}) bool {
-			pk := key.(c_type.Uint512)
-			num := value.(uint64)
+		for pk, num := range self.headNumbers() {
 			if list, ok := indexs[num]; ok {
 				indexs[num] = append(list, pk)
 			} else {
 				indexs[num] = []c_type.Uint512{pk}
 				orders = append(orders, num)
 			}
-			return true
-		})
+		}
 		if orders.Len() == 0 {
 			return
 		}
@@ -887,7 +964,7 @@ func (self *Exchange) fetchBlockInfo() {
 
 func (self *Exchange) fetchAndIndexUtxo(start, countBlock uint64, pks []c_type.Uint512) (count int) {
 
-	blocks, err := flight.SRI_Inst.GetBlocksInfo(start, countBlock)
+	blocks, err := getBlocksInfo(start, countBlock)
 	if err != nil {
 		log.Info("Exchange GetBlocksInfo", "error", err)
 		return
@@ -899,7 +976,8 @@ func (self *Exchange) fetchAndIndexUtxo(start, countBlock uint64, pks []c_type.U
 
 	utxosMap := map[PkKey][]Utxo{}
 	nilsMap := map[c_type.Uint256]Utxo{}
-	nils := []c_type.Uint256{}
+	// nil => number of the block spending it
+	nils := map[c_type.Uint256]uint64{}
 	blockMap := map[uint64]*BlockInfo{}
 	for _, block := range blocks {
 		num := uint64(block.Num)
@@ -962,7 +1040,7 @@ func (self *Exchange) fetchAndIndexUtxo(start, countBlock uint64, pks []c_type.U
 						continue
 					}
 				}
-				nils = append(nils, Nil)
+				nils[Nil] = num
 				roots = append(roots, utxo.Root)
 			}
 			if len(roots) > 0 {
@@ -975,13 +1053,42 @@ func (self *Exchange) fetchAndIndexUtxo(start, countBlock uint64, pks []c_type.U
 		}
 	}
 
-	batch := self.db.NewBatch()
+	return self.indexUtxos(start, pks, blocks, utxosMap, blockMap, nils)
+}
+
+// indexUtxos writes the decrypted UTXOs and spent nils of one pass over blocks
+// and posts the events they produce. Blocks are decrypted concurrently, but
+// written one pass at a time. The events are sent after the lock is released
+// so a slow subscriber does not stall the other indexing paths.
+func (self *Exchange) indexUtxos(start uint64, pks []c_type.Uint512, blocks []txtool.Block, utxosMap map[PkKey][]Utxo, blockMap map[uint64]*BlockInfo, nils map[c_type.Uint256]uint64) (count int) {
+	var sent []UtxoEvent
+	defer func() {
+		if len(sent) > 0 {
+			self.feed.Send(sent)
+		}
+	}()
+	self.indexLock.Lock()
+	defer self.indexLock.Unlock()
+	for _, pk := range pks {
+		if value, ok := self.numbers.Load(pk); !ok || value.(uint64) != start {
+			log.Info("Exchange discard stale index pass", "start", start)
+			return
+		}
+	}
+
+	batch := self.newIndexBatch(pks, blocks)
 
 	self.indexPkgs(pks, batch, blocks)
 
 	var roots []c_type.Uint256
+	var events []UtxoEvent
+	var err error
 	if len(utxosMap) > 0 || len(nils) > 0 {
-		if roots, err = self.indexBlocks(batch, utxosMap, blockMap, nils); err != nil {
+		if err = self.indexHistory(batch, utxosMap, nils); err != nil {
+			log.Error("indexHistory ", "error", err)
+			return
+		}
+		if roots, events, err = self.indexBlocks(batch, utxosMap, blockMap, nils); err != nil {
 			log.Error("indexBlocks ", "error", err)
 			return
 		}
@@ -1000,6 +1107,7 @@ func (self *Exchange) fetchAndIndexUtxo(start, countBlock uint64, pks []c_type.U
 		for _, pk := range pks {
 			self.numbers.Store(pk, num)
 		}
+		sent = events
 	}
 
 	for _, root := range roots {
@@ -1009,8 +1117,10 @@ func (self *Exchange) fetchAndIndexUtxo(start, countBlock uint64, pks []c_type.U
 	return
 }
 
-func (self *Exchange) indexBlocks(batch serodb.Batch, utxosMap map[PkKey][]Utxo, blockMap map[uint64]*BlockInfo, nils []c_type.Uint256) (delRoots []c_type.Uint256, err error) {
+func (self *Exchange) indexBlocks(batch serodb.Batch, utxosMap map[PkKey][]Utxo, blockMap map[uint64]*BlockInfo, nils map[c_type.Uint256]uint64) (delRoots []c_type.Uint256, events []UtxoEvent, err error) {
 	ops := map[string]string{}
+	// nil/root => event of the utxo received in this pass
+	received := map[c_type.Uint256]UtxoEvent{}
 
 	for num, blockInfo := range blockMap {
 		data, e := rlp.EncodeToBytes(&blockInfo)
@@ -1062,6 +1172,13 @@ func (self *Exchange) indexBlocks(batch serodb.Batch, utxosMap map[PkKey][]Utxo,
 
 			roots = append(roots, utxo.Root)
 
+			if utxo.Asset.Tkn != nil {
+				event := newUtxoEvent(UtxoReceived, key.key, &utxo, utxo.Num)
+				received[utxo.Nil] = event
+				received[utxo.Root] = event
+				events = append(events, event)
+			}
+
 			if list, ok := txMap[utxo.TxHash]; ok {
 				txMap[utxo.TxHash] = append(list, utxo)
 			} else {
@@ -1098,12 +1215,12 @@ func (self *Exchange) indexBlocks(batch serodb.Batch, utxosMap map[PkKey][]Utxo,
 		records = append(records, list...)
 		data, err = rlp.EncodeToBytes(&records)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		batch.Put(key, data)
 	}
 
-	for _, Nil := range nils {
+	for Nil, num := range nils {
 
 		var pk c_type.Uint512
 		key := nilKey(Nil)
@@ -1124,6 +1241,12 @@ func (self *Exchange) indexBlocks(batch serodb.Batch, utxosMap map[PkKey][]Utxo,
 			delRoots = append(delRoots, root)
 
 			copy(pk[:], value[2:66])
+
+			if event, ok := received[Nil]; ok {
+				event.Kind = UtxoSpent
+				event.Num = num
+				events = append(events, event)
+			}
 		} else {
 			value, _ := self.db.Get(key)
 			if value != nil {
@@ -1142,6 +1265,10 @@ func (self *Exchange) indexBlocks(batch serodb.Batch, utxosMap map[PkKey][]Utxo,
 				delRoots = append(delRoots, root)
 
 				copy(pk[:], value[2:66])
+
+				if utxo, e := self.getUtxo(root); e == nil && utxo.Asset.Tkn != nil {
+					events = append(events, newUtxoEvent(UtxoSpent, pk, &utxo, num))
+				}
 			}
 		}
 
@@ -1272,9 +1399,23 @@ func (self *Exchange) GenMergeTx(mp *MergeParam) (txParam *txtool.GTxParam, e er
 		mp.To = account.wallet.Accounts()[0].GetDefaultPkr(1).NewRef()
 	}
 	var mu MergeUtxos
-	if mu, e = self.getMergeUtxos(account.pk, mp.Currency, int(mp.Zcount), int(mp.Left), int(mp.Icount)); e != nil {
+	var leased []c_type.Uint256
+	if leased, e = self.selectAndReserve("genMergeTx", func() (roots []c_type.Uint256, err error) {
+		if mu, err = self.getMergeUtxos(account.pk, mp.Currency, int(mp.Zcount), int(mp.Left), int(mp.Icount)); err != nil {
+			return
+		}
+		for _, utxo := range mu.list {
+			roots = append(roots, utxo.Root)
+		}
+		return
+	}); e != nil {
 		return
 	}
+	defer func() {
+		if e != nil {
+			self.releaseRoots(leased)
+		}
+	}()
 
 	bytes := common.LeftPadBytes([]byte(mp.Currency), 32)
 	var Currency c_type.Uint256
@@ -1324,7 +1465,7 @@ func (self *Exchange) GenMergeTx(mp *MergeParam) (txParam *txtool.GTxParam, e er
 		prepare.Cmds{},
 	}
 
-	txParam, e = self.buildTxParam(&bparam)
+	txParam, e = self.buildTxParam(&bparam, "genMergeTx")
 	if e != nil {
 		return
 	}
@@ -1345,9 +1486,23 @@ func (self *Exchange) Merge(pk *c_type.Uint512, currency string, force bool) (co
 	}
 
 	var mu MergeUtxos
-	if mu, e = self.getMergeUtxos(account.pk, currency, 100, 50, 0); e != nil {
+	var leased []c_type.Uint256
+	if leased, e = self.selectAndReserve("merge", func() (roots []c_type.Uint256, err error) {
+		if mu, err = self.getMergeUtxos(account.pk, currency, 100, 50, 0); err != nil {
+			return
+		}
+		for _, utxo := range mu.list {
+			roots = append(roots, utxo.Root)
+		}
+		return
+	}); e != nil {
 		return
 	}
+	defer func() {
+		if e != nil {
+			self.releaseRoots(leased)
+		}
+	}()
 
 	if mu.zcount >= 100 || mu.ocount >= 2400 || time.Now().After(account.nextMergeTime) || force {
 
@@ -1400,7 +1555,7 @@ func (self *Exchange) Merge(pk *c_type.Uint512, currency string, force bool) (co
 			prepare.Cmds{},
 		}
 
-		pretx, gtx, err := self.genTx(account, &bparam)
+		pretx, gtx, err := self.genTx(account, &bparam, "merge")
 		if err != nil {
 			account.nextMergeTime = time.Now().Add(time.Hour * 6)
 			e = err
@@ -12,29 +12,27 @@ import (
 )
 
 func (self *Exchange) GenTx(param prepare.PreTxParam) (txParam *txtool.GTxParam, e error) {
+	self.selectLock.Lock()
+	defer self.selectLock.Unlock()
 	txParam, e = prepare.GenTxParam(&param, self, &prepare.DefaultTxParamState{})
 	if e == nil && txParam != nil {
-		for _, in := range txParam.Ins {
-			self.usedFlag.Store(in.Out.Root, 1)
-		}
+		self.reserveTxParam(txParam, "genTx")
 	}
 	return
 }
 
-func (self *Exchange) buildTxParam(param *prepare.BeforeTxParam) (txParam *txtool.GTxParam, e error) {
+func (self *Exchange) buildTxParam(param *prepare.BeforeTxParam, owner string) (txParam *txtool.GTxParam, e error) {
 
 	txParam, e = prepare.BuildTxParam(&prepare.DefaultTxParamState{}, param)
 
 	if e == nil && txParam != nil {
-		for _, in := range txParam.Ins {
-			self.usedFlag.Store(in.Out.Root, 1)
-		}
+		self.reserveTxParam(txParam, owner)
 	}
 	return
 }
 
-func (self *Exchange) FindRoots(pk *c_type.Uint512, currency string, amount *big.Int) (roots prepare.Utxos, remain big.Int) {
-	utxos, r := self.findUtxos(pk, currency, amount)
+func (self *Exchange) FindRoots(pk *c_type.Uint512, currency string, amount *big.Int, selector string) (roots prepare.Utxos, remain big.Int, used string) {
+	utxos, r, used := self.selectUtxos(pk, currency, amount, selector)
 	for _, utxo := range utxos {
 		roots = append(roots, prepare.Utxo{utxo.Root, utxo.Asset})
 	}
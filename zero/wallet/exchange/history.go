@@ -0,0 +1,514 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/flight"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+var histPrefix = []byte("HIST")
+
+// historyVersion is the schema of the history index. The blocks indexed by an
+// older one are migrated once from the indexed utxos and the nils of the chain.
+const historyVersion = 1
+
+var (
+	histVersionKey = []byte("HISTVERSION")
+	histMigrateKey = []byte("HISTMIGRATE")
+)
+
+func histPkPrefix(pk c_type.Uint512) []byte {
+	return append(histPrefix, pk[:]...)
+}
+
+// "HIST" + PK + blockNumber + txHash => HistoryRecord
+func histKey(pk c_type.Uint512, num uint64, txHash c_type.Uint256) []byte {
+	key := append(histPkPrefix(pk), utils.EncodeNumber(num)...)
+	return append(key, txHash[:]...)
+}
+
+// HistoryRecord is what one transaction did to one account: the utxos it
+// spent, the utxos it paid to the account and the fee when the account paid it.
+type HistoryRecord struct {
+	TxHash    c_type.Uint256
+	Num       uint64
+	Timestamp uint64
+	Ins       []Utxo
+	Outs      []Utxo
+	Fee       assets.Token
+}
+
+// spendingTxs maps every nil and root spent by txs to its transaction.
+func spendingTxs(list types.Transactions) map[c_type.Uint256]*types.Transaction {
+	txs := map[c_type.Uint256]*types.Transaction{}
+	for _, tx := range list {
+		stxt := tx.Stxt()
+		if tx0 := stxt.Tx0(); tx0 != nil {
+			for _, in := range tx0.Desc_O.Ins {
+				txs[in.Root] = tx
+			}
+			for _, in := range tx0.Desc_Z.Ins {
+				txs[in.Nil] = tx
+				txs[in.Trace] = tx
+			}
+		}
+		for _, in := range stxt.Tx1.Ins_C {
+			txs[in.Nil] = tx
+		}
+		for _, in := range stxt.Tx1.Ins_P {
+			txs[in.Root] = tx
+			txs[in.Nil] = tx
+		}
+		for _, in := range stxt.Tx1.Ins_P0 {
+			txs[in.Root] = tx
+			txs[in.Nil] = tx
+			txs[in.Trace] = tx
+		}
+	}
+	return txs
+}
+
+// indexHistory groups the utxos received and spent by the indexed accounts
+// per transaction, so account history can later be rebuilt from this database.
+func (self *Exchange) indexHistory(batch serodb.Batch, utxosMap map[PkKey][]Utxo, nils map[c_type.Uint256]uint64) (err error) {
+	bc := txtool.Ref_inst.Bc
+	records := map[string]*HistoryRecord{}
+	timestamps := map[uint64]uint64{}
+	timestamp := func(num uint64) uint64 {
+		if t, ok := timestamps[num]; ok {
+			return t
+		}
+		if header := bc.GetHeaderByNumber(num); header != nil {
+			timestamps[num] = header.Time.Uint64()
+		}
+		return timestamps[num]
+	}
+	record := func(pk c_type.Uint512, num uint64, txHash c_type.Uint256) *HistoryRecord {
+		key := string(histKey(pk, num, txHash))
+		if r, ok := records[key]; ok {
+			return r
+		}
+		r := &HistoryRecord{TxHash: txHash, Num: num, Timestamp: timestamp(num)}
+		records[key] = r
+		return r
+	}
+
+	received := map[c_type.Uint256]PkKey{}
+	utxos := map[c_type.Uint256]Utxo{}
+	for key, list := range utxosMap {
+		for _, utxo := range list {
+			r := record(key.key, utxo.Num, utxo.TxHash)
+			r.Outs = append(r.Outs, utxo)
+			received[utxo.Nil] = key
+			received[utxo.Root] = key
+			utxos[utxo.Nil] = utxo
+			utxos[utxo.Root] = utxo
+		}
+	}
+
+	blocks := map[uint64]map[c_type.Uint256]*types.Transaction{}
+	for Nil, num := range nils {
+		var pk c_type.Uint512
+		var utxo Utxo
+		if key, ok := received[Nil]; ok {
+			pk = key.key
+			utxo = utxos[Nil]
+		} else {
+			if pk, utxo, ok, err = self.spentUtxo(Nil); err != nil {
+				return
+			} else if !ok {
+				continue
+			}
+		}
+
+		txs, ok := blocks[num]
+		if !ok {
+			if block := bc.GetBlockByNumber(num); block != nil {
+				txs = spendingTxs(block.Transactions())
+			}
+			blocks[num] = txs
+		}
+		tx, ok := txs[Nil]
+		if !ok {
+			log.Error("Exchange indexHistory can not find spending tx", "blockNumber", num, "nil", common.Bytes2Hex(Nil[:]))
+			continue
+		}
+		r := record(pk, num, *tx.Hash().HashToUint256())
+		r.Ins = append(r.Ins, utxo)
+		r.Fee = tx.Stxt().Fee
+	}
+
+	for key, r := range records {
+		data, e := rlp.EncodeToBytes(r)
+		if e != nil {
+			return e
+		}
+		if err = batch.Put([]byte(key), data); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// spentUtxo returns the indexed utxo of a nil or a root and its account. The
+// utxos still unspent are found by their "NIL" entry, the spent ones by the
+// account whose received utxos of the block hold them.
+func (self *Exchange) spentUtxo(Nil c_type.Uint256) (pk c_type.Uint512, utxo Utxo, ok bool, err error) {
+	if value, _ := self.db.Get(nilKey(Nil)); value != nil {
+		var root c_type.Uint256
+		copy(root[:], value[98:130])
+		copy(pk[:], value[2:66])
+		utxo, err = self.getUtxo(root)
+		return pk, utxo, err == nil, err
+	}
+
+	root := Nil
+	if value, _ := self.db.Get(nilToRootKey(Nil)); len(value) == len(root) {
+		copy(root[:], value)
+	}
+	if utxo, err = self.getUtxo(root); err != nil || utxo.Root != root {
+		return
+	}
+	iterator := self.db.NewIteratorWithPrefix(append(append([]byte{}, utxoPrefix...), utils.EncodeNumber(utxo.Num)...))
+	defer iterator.Release()
+	for iterator.Next() {
+		var roots []c_type.Uint256
+		if e := rlp.DecodeBytes(iterator.Value(), &roots); e != nil {
+			continue
+		}
+		for _, r := range roots {
+			if r == root {
+				copy(pk[:], iterator.Key()[len(utxoPrefix)+8:])
+				return pk, utxo, true, nil
+			}
+		}
+	}
+	return
+}
+
+// historyMigration is the progress of the history migration, the blocks
+// [Next, End) are left to migrate.
+type historyMigration struct {
+	Next uint64
+	End  uint64
+}
+
+// loadHistoryMigration returns the progress of the history migration, it starts
+// it at the first indexed utxo and ends it at the last indexed block.
+func (self *Exchange) loadHistoryMigration() (migration historyMigration, done bool, err error) {
+	if version, _ := self.db.Get(histVersionKey); len(version) == 8 && utils.DecodeNumber(version) >= historyVersion {
+		return migration, true, nil
+	}
+	if data, _ := self.db.Get(histMigrateKey); data != nil {
+		err = rlp.DecodeBytes(data, &migration)
+		return
+	}
+
+	iterator := self.db.NewIteratorWithPrefix(utxoPrefix)
+	if iterator.Next() {
+		migration.Next = utils.DecodeNumber(iterator.Key()[len(utxoPrefix) : len(utxoPrefix)+8])
+	}
+	iterator.Release()
+	self.numbers.Range(func(key, value interface{}) bool {
+		if num := value.(uint64); num > migration.End {
+			migration.End = num
+		}
+		return true
+	})
+	if migration.Next >= migration.End {
+		return migration, true, self.db.Put(histVersionKey, utils.EncodeNumber(historyVersion))
+	}
+	data, err := rlp.EncodeToBytes(&migration)
+	if err != nil {
+		return
+	}
+	log.Info("Exchange history migration started", "from", migration.Next, "to", migration.End)
+	return migration, false, self.db.Put(histMigrateKey, data)
+}
+
+// migrateHistoryBlocks writes the history of blocks, the next blocks of the
+// migration, together with the progress of the migration.
+func (self *Exchange) migrateHistoryBlocks(migration historyMigration, blocks []txtool.Block) (historyMigration, error) {
+	last := uint64(blocks[len(blocks)-1].Num)
+	if last >= migration.End {
+		last = migration.End - 1
+	}
+
+	utxosMap := map[PkKey][]Utxo{}
+	iterator := self.db.NewIteratorWithPrefix(utxoPrefix)
+	for ok := iterator.Seek(utxoKey(migration.Next, c_type.Uint512{})); ok; ok = iterator.Next() {
+		key := iterator.Key()
+		num := utils.DecodeNumber(key[len(utxoPrefix) : len(utxoPrefix)+8])
+		if num > last {
+			break
+		}
+		var roots []c_type.Uint256
+		if err := rlp.DecodeBytes(iterator.Value(), &roots); err != nil {
+			iterator.Release()
+			return migration, err
+		}
+		pkKey := PkKey{Num: num}
+		copy(pkKey.key[:], key[len(utxoPrefix)+8:])
+		for _, root := range roots {
+			if utxo, err := self.getUtxo(root); err == nil && utxo.Root == root {
+				utxosMap[pkKey] = append(utxosMap[pkKey], utxo)
+			}
+		}
+	}
+	iterator.Release()
+
+	nils := map[c_type.Uint256]uint64{}
+	for _, block := range blocks {
+		if num := uint64(block.Num); num >= migration.Next && num <= last {
+			for _, Nil := range block.Nils {
+				nils[Nil] = num
+			}
+		}
+	}
+
+	batch := self.db.NewBatch()
+	if err := self.indexHistory(batch, utxosMap, nils); err != nil {
+		return migration, err
+	}
+	migration.Next = last + 1
+	if migration.Next >= migration.End {
+		batch.Delete(histMigrateKey)
+		batch.Put(histVersionKey, utils.EncodeNumber(historyVersion))
+	} else {
+		data, err := rlp.EncodeToBytes(&migration)
+		if err != nil {
+			return migration, err
+		}
+		batch.Put(histMigrateKey, data)
+	}
+	return migration, batch.Write()
+}
+
+// migrateHistory backfills the history of the blocks indexed before the history
+// index, it returns false until the migration is done so the indexing waits.
+func (self *Exchange) migrateHistory() bool {
+	migration, done, err := self.loadHistoryMigration()
+	if err != nil {
+		log.Error("Exchange history migration", "err", err)
+		return false
+	}
+	for !done {
+		count := migration.End - migration.Next
+		if count > fetchCount {
+			count = fetchCount
+		}
+		blocks, err := flight.SRI_Inst.GetBlocksInfo(migration.Next, count)
+		if err != nil || len(blocks) == 0 {
+			log.Info("Exchange history migration GetBlocksInfo", "error", err)
+			return false
+		}
+		self.indexLock.Lock()
+		migration, err = self.migrateHistoryBlocks(migration, blocks)
+		self.indexLock.Unlock()
+		if err != nil {
+			log.Error("Exchange history migration", "blockNumber", migration.Next, "err", err)
+			return false
+		}
+		log.Info("Exchange history migrated", "blockNumber", migration.Next-1, "to", migration.End)
+		done = migration.Next >= migration.End
+	}
+	return true
+}
+
+// HistoryRow is one line of an account statement: the effect of a single
+// transaction on one currency of the account.
+type HistoryRow struct {
+	TxHash    c_type.Uint256
+	Num       uint64
+	Timestamp uint64
+	Currency  string
+	Inflow    *big.Int
+	Outflow   *big.Int
+	Fee       *big.Int
+	Balance   *big.Int
+	Tickets   []string
+}
+
+func (row HistoryRow) MarshalJSON() ([]byte, error) {
+	tickets := row.Tickets
+	if tickets == nil {
+		tickets = []string{}
+	}
+	return json.Marshal(map[string]interface{}{
+		"txHash":    hexutil.Encode(row.TxHash[:]),
+		"block":     row.Num,
+		"timestamp": row.Timestamp,
+		"currency":  row.Currency,
+		"inflow":    row.Inflow.String(),
+		"outflow":   row.Outflow.String(),
+		"fee":       row.Fee.String(),
+		"balance":   row.Balance.String(),
+		"tickets":   tickets,
+	})
+}
+
+func addAmount(amounts map[string]*big.Int, currency string, value *big.Int) {
+	if amount, ok := amounts[currency]; ok {
+		amount.Add(amount, value)
+	} else {
+		amounts[currency] = new(big.Int).Set(value)
+	}
+}
+
+func ticketChange(sign string, tkt *assets.Ticket) string {
+	return sign + common.BytesToString(tkt.Category[:]) + ":" + hexutil.Encode(tkt.Value[:])
+}
+
+// ExportHistory returns the statement rows of pk for the blocks [begin, end),
+// with balances running from the first block the account was indexed at.
+func ExportHistory(db serodb.KeyValueStore, pk c_type.Uint512, begin, end uint64) (rows []HistoryRow, err error) {
+	balances := map[string]*big.Int{}
+
+	iterator := db.NewIteratorWithPrefix(histPkPrefix(pk))
+	defer iterator.Release()
+	for iterator.Next() {
+		var r HistoryRecord
+		if err = rlp.Decode(bytes.NewReader(iterator.Value()), &r); err != nil {
+			log.Error("Exchange Invalid history RLP", "key", common.Bytes2Hex(iterator.Key()), "err", err)
+			return
+		}
+		if r.Num >= end {
+			break
+		}
+
+		inflows := map[string]*big.Int{}
+		outflows := map[string]*big.Int{}
+		tickets := map[string][]string{}
+		for _, utxo := range r.Outs {
+			if utxo.Asset.Tkn != nil {
+				addAmount(inflows, common.BytesToString(utxo.Asset.Tkn.Currency[:]), utxo.Asset.Tkn.Value.ToIntRef())
+			}
+			if tkt := utxo.Asset.Tkt; tkt != nil {
+				category := common.BytesToString(tkt.Category[:])
+				tickets[category] = append(tickets[category], ticketChange("+", tkt))
+			}
+		}
+		for _, utxo := range r.Ins {
+			if utxo.Asset.Tkn != nil {
+				addAmount(outflows, common.BytesToString(utxo.Asset.Tkn.Currency[:]), utxo.Asset.Tkn.Value.ToIntRef())
+			}
+			if tkt := utxo.Asset.Tkt; tkt != nil {
+				category := common.BytesToString(tkt.Category[:])
+				tickets[category] = append(tickets[category], ticketChange("-", tkt))
+			}
+		}
+
+		currencies := []string{}
+		seen := map[string]bool{}
+		for _, amounts := range []map[string]*big.Int{inflows, outflows} {
+			for currency := range amounts {
+				if !seen[currency] {
+					seen[currency] = true
+					currencies = append(currencies, currency)
+				}
+			}
+		}
+		for category := range tickets {
+			if !seen[category] {
+				seen[category] = true
+				currencies = append(currencies, category)
+			}
+		}
+		sort.Strings(currencies)
+
+		for _, currency := range currencies {
+			row := HistoryRow{
+				TxHash:    r.TxHash,
+				Num:       r.Num,
+				Timestamp: r.Timestamp,
+				Currency:  currency,
+				Inflow:    new(big.Int),
+				Outflow:   new(big.Int),
+				Fee:       new(big.Int),
+				Tickets:   tickets[currency],
+			}
+			if v, ok := inflows[currency]; ok {
+				row.Inflow.Set(v)
+			}
+			if v, ok := outflows[currency]; ok {
+				row.Outflow.Set(v)
+			}
+			if len(r.Ins) > 0 && common.BytesToString(r.Fee.Currency[:]) == currency {
+				row.Fee.Set(r.Fee.Value.ToIntRef())
+			}
+			balance, ok := balances[currency]
+			if !ok {
+				balance = new(big.Int)
+				balances[currency] = balance
+			}
+			balance.Add(balance, row.Inflow)
+			balance.Sub(balance, row.Outflow)
+			row.Balance = new(big.Int).Set(balance)
+
+			if r.Num >= begin {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return
+}
+
+func (self *Exchange) ExportHistory(pk c_type.Uint512, begin, end uint64) ([]HistoryRow, error) {
+	return ExportHistory(self.db, pk, begin, end)
+}
+
+var historyHeader = []string{"txHash", "block", "timestamp", "currency", "inflow", "outflow", "fee", "balance", "tickets"}
+
+// WriteHistory writes rows as "csv" or "json".
+func WriteHistory(w io.Writer, rows []HistoryRow, format string) error {
+	switch format {
+	case "json":
+		if rows == nil {
+			rows = []HistoryRow{}
+		}
+		return json.NewEncoder(w).Encode(rows)
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write(historyHeader); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			record := []string{
+				hexutil.Encode(row.TxHash[:]),
+				strconv.FormatUint(row.Num, 10),
+				strconv.FormatUint(row.Timestamp, 10),
+				row.Currency,
+				row.Inflow.String(),
+				row.Outflow.String(),
+				row.Fee.String(),
+				row.Balance.String(),
+				strings.Join(row.Tickets, " "),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unknown export format %v", format)
+	}
+}
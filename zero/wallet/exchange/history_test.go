@@ -0,0 +1,138 @@
+package exchange
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txs/stx/stx_v1"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// historyChain serves the blocks of the spending transactions.
+type historyChain struct {
+	txtool.BlockChain
+	blocks map[uint64]*types.Block
+}
+
+func (self *historyChain) GetBlockByNumber(num uint64) *types.Block {
+	return self.blocks[num]
+}
+
+func (self *historyChain) GetHeaderByNumber(num uint64) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(num), Time: new(big.Int).SetUint64(num * 10)}
+}
+
+func newHistoryExchange(t *testing.T) (*Exchange, func()) {
+	dir, err := ioutil.TempDir("", "exchange")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := serodb.NewDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Exchange{db: db}, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestHistoryMigration(t *testing.T) {
+	ex, remove := newHistoryExchange(t)
+	defer remove()
+
+	pk := c_type.Uint512{1}
+	sero := utils.CurrencyToUint256("SERO")
+	newUtxo := func(n byte, num uint64, value uint64) Utxo {
+		return Utxo{
+			Root:   c_type.Uint256{n},
+			Nil:    c_type.Uint256{n, 1},
+			TxHash: c_type.Uint256{n, 2},
+			Num:    num,
+			Asset:  assets.Asset{Tkn: &assets.Token{Currency: sero, Value: utils.NewU256(value)}},
+		}
+	}
+	// The utxos indexed before the history index
+	received := []Utxo{newUtxo(1, 2, 100), newUtxo(2, 3, 50)}
+	for _, utxo := range received {
+		data, _ := rlp.EncodeToBytes(&utxo)
+		ex.db.Put(rootKey(utxo.Root), data)
+		ex.db.Put(nilToRootKey(utxo.Nil), utxo.Root[:])
+		roots, _ := rlp.EncodeToBytes([]c_type.Uint256{utxo.Root})
+		ex.db.Put(utxoKey(utxo.Num, pk), roots)
+	}
+	ex.numbers.Store(pk, uint64(6))
+
+	spend := types.NewTxWithGTx(25000, big.NewInt(1), &stx.T{
+		Fee: assets.Token{Currency: sero, Value: utils.NewU256(5)},
+		Tx1: stx_v1.Tx{Ins_C: []stx_v1.In_C{{Nil: received[0].Nil}}},
+	})
+	bc := txtool.Ref_inst.Bc
+	defer func() { txtool.Ref_inst.Bc = bc }()
+	txtool.Ref_inst.Bc = &historyChain{blocks: map[uint64]*types.Block{
+		5: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(5)}).WithBody(types.Transactions{spend}),
+	}}
+
+	migration, done, err := ex.loadHistoryMigration()
+	if err != nil || done || migration != (historyMigration{2, 6}) {
+		t.Fatalf("migration mismatch: %v %v %v", migration, done, err)
+	}
+	if migration, err = ex.migrateHistoryBlocks(migration, []txtool.Block{{Num: 2}, {Num: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The migration resumes after the migrated blocks
+	if migration, done, err = ex.loadHistoryMigration(); err != nil || done || migration != (historyMigration{4, 6}) {
+		t.Fatalf("resumed migration mismatch: %v %v %v", migration, done, err)
+	}
+	if rows, _ := ExportHistory(ex.db, pk, 0, 10); len(rows) != 2 || rows[1].Balance.Int64() != 150 {
+		t.Fatalf("rows after the first pass mismatch: %v", rows)
+	}
+	if migration, err = ex.migrateHistoryBlocks(migration, []txtool.Block{{Num: 4}, {Num: 5, Nils: []c_type.Uint256{received[0].Nil}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, done, err = ex.loadHistoryMigration(); err != nil || !done {
+		t.Fatalf("migration not done: %v", err)
+	}
+	if data, _ := ex.db.Get(histMigrateKey); data != nil {
+		t.Fatalf("migration progress left")
+	}
+
+	rows, err := ExportHistory(ex.db, pk, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("rows mismatch: %v", rows)
+	}
+	row := rows[2]
+	if common.BytesToHash(row.TxHash[:]) != spend.Hash() || row.Num != 5 || row.Timestamp != 50 {
+		t.Errorf("spending row mismatch: %v", row)
+	}
+	if row.Outflow.Int64() != 100 || row.Fee.Int64() != 5 || row.Balance.Int64() != 50 {
+		t.Errorf("spending amounts mismatch: out %v fee %v balance %v", row.Outflow, row.Fee, row.Balance)
+	}
+}
+
+func TestHistoryMigrationEmpty(t *testing.T) {
+	ex, remove := newHistoryExchange(t)
+	defer remove()
+
+	ex.numbers.Store(c_type.Uint512{1}, uint64(100))
+	if _, done, err := ex.loadHistoryMigration(); err != nil || !done {
+		t.Fatalf("migration of an empty index not done: %v", err)
+	}
+	if version, _ := ex.db.Get(histVersionKey); len(version) != 8 || utils.DecodeNumber(version) != historyVersion {
+		t.Fatalf("history version not written")
+	}
+}
@@ -0,0 +1,184 @@
+package exchange
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+// DefaultLease is how long a utxo stays reserved for a transaction that has
+// not shown up in the pool or the chain.
+var DefaultLease = 10 * time.Minute
+
+// Reservation marks a utxo as used by a generated transaction.
+type Reservation struct {
+	Root    c_type.Uint256
+	Owner   string
+	TxHash  *c_type.Uint256
+	Created time.Time
+	Expiry  time.Time
+}
+
+func (self *Exchange) reserve(root c_type.Uint256, owner string) {
+	now := time.Now()
+	self.usedFlag.Store(root, &Reservation{
+		Root:    root,
+		Owner:   owner,
+		Created: now,
+		Expiry:  now.Add(DefaultLease),
+	})
+}
+
+func (self *Exchange) reserveTxParam(txParam *txtool.GTxParam, owner string) {
+	for _, in := range txParam.Ins {
+		self.reserve(in.Out.Root, owner)
+	}
+}
+
+// selectAndReserve runs selection under the select lock and reserves the
+// roots it returns before releasing the lock, so the generating paths never
+// pick the same utxo.
+func (self *Exchange) selectAndReserve(owner string, selection func() ([]c_type.Uint256, error)) (roots []c_type.Uint256, e error) {
+	self.selectLock.Lock()
+	defer self.selectLock.Unlock()
+	if roots, e = selection(); e != nil {
+		return nil, e
+	}
+	for _, root := range roots {
+		self.reserve(root, owner)
+	}
+	return
+}
+
+func (self *Exchange) releaseRoots(roots []c_type.Uint256) {
+	for _, root := range roots {
+		self.ClearUsedFlagForRoot(root)
+	}
+}
+
+// Lease relabels the reservations of roots and extends them by ttl. A non nil
+// txHash binds them to the transaction that spends them.
+func (self *Exchange) Lease(roots []c_type.Uint256, owner string, ttl time.Duration, txHash *c_type.Uint256) (count int) {
+	for _, root := range roots {
+		if value, ok := self.usedFlag.Load(root); ok {
+			r := *value.(*Reservation)
+			if owner != "" {
+				r.Owner = owner
+			}
+			if ttl > 0 {
+				r.Expiry = time.Now().Add(ttl)
+			}
+			if txHash != nil {
+				hash := *txHash
+				r.TxHash = &hash
+			}
+			self.usedFlag.Store(root, &r)
+			count++
+		}
+	}
+	return
+}
+
+// ListReservations returns the reservations, of pk when given, by expiry.
+func (self *Exchange) ListReservations(pk *c_type.Uint512) (list []Reservation) {
+	self.usedFlag.Range(func(key, value interface{}) bool {
+		r := value.(*Reservation)
+		if pk != nil {
+			// "NIL" + root => "PK" + PK + currency + root
+			value, err := self.db.Get(nilKey(r.Root))
+			if err != nil || len(value) < 66 {
+				return true
+			}
+			var owner c_type.Uint512
+			copy(owner[:], value[2:66])
+			if owner != *pk {
+				return true
+			}
+		}
+		list = append(list, *r)
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Expiry.Before(list[j].Expiry)
+	})
+	return
+}
+
+func addSpent(spent map[c_type.Uint256]bool, txs types.Transactions) {
+	for key := range spendingTxs(txs) {
+		spent[key] = true
+	}
+}
+
+// poolReader is the part of the transaction pool the leases are checked with.
+type poolReader interface {
+	Content() (types.Transactions, types.Transactions, types.Transactions)
+	Get(hash common.Hash) *types.Transaction
+}
+
+// releaseExpired drops the expired reservations whose transaction is neither
+// in the pool nor in the chain.
+func (self *Exchange) releaseExpired() {
+	var pool poolReader
+	if self.txPool != nil {
+		pool = self.txPool
+	}
+	self.releaseExpiredAt(time.Now(), pool)
+}
+
+func (self *Exchange) releaseExpiredAt(now time.Time, pool poolReader) {
+	if txtool.Ref_inst.Bc == nil {
+		return
+	}
+	expired := []*Reservation{}
+	self.usedFlag.Range(func(key, value interface{}) bool {
+		if r := value.(*Reservation); now.After(r.Expiry) {
+			expired = append(expired, r)
+		}
+		return true
+	})
+	if len(expired) == 0 {
+		return
+	}
+
+	spent := map[c_type.Uint256]bool{}
+	if pool != nil {
+		_, _, all := pool.Content()
+		addSpent(spent, all)
+	}
+	bc := txtool.Ref_inst.Bc
+	head := bc.GetCurrenHeader().Number.Uint64()
+	for i := uint64(0); i <= seroparam.DefaultConfirmedBlock() && i <= head; i++ {
+		if block := bc.GetBlockByNumber(head - i); block != nil {
+			addSpent(spent, block.Transactions())
+		}
+	}
+
+	for _, r := range expired {
+		if spent[r.Root] {
+			continue
+		}
+		if utxo, err := self.getUtxo(r.Root); err == nil && spent[utxo.Nil] {
+			continue
+		}
+		if r.TxHash != nil {
+			if pool != nil && pool.Get(common.BytesToHash(r.TxHash[:])) != nil {
+				continue
+			}
+			if tx, _, _, _ := rawdb.ReadTransaction(bc.GetDB(), common.BytesToHash(r.TxHash[:])); tx != nil {
+				continue
+			}
+		}
+		if value, ok := self.usedFlag.Load(r.Root); ok && value.(*Reservation) == r {
+			self.usedFlag.Delete(r.Root)
+			log.Info("Exchange release expired reservation", "root", common.Bytes2Hex(r.Root[:]), "owner", r.Owner)
+		}
+	}
+}
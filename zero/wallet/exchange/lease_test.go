@@ -0,0 +1,205 @@
+package exchange
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txs/stx/stx_v1"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// leaseChain serves the recent blocks and the transaction lookups.
+type leaseChain struct {
+	txtool.BlockChain
+	db     serodb.Database
+	blocks map[uint64]*types.Block
+}
+
+func (self *leaseChain) GetCurrenHeader() *types.Header {
+	return &types.Header{Number: big.NewInt(3)}
+}
+
+func (self *leaseChain) GetBlockByNumber(num uint64) *types.Block {
+	return self.blocks[num]
+}
+
+func (self *leaseChain) GetDB() serodb.Database {
+	return self.db
+}
+
+// leasePool holds the pending transactions.
+type leasePool struct {
+	txs types.Transactions
+}
+
+func (self *leasePool) Content() (types.Transactions, types.Transactions, types.Transactions) {
+	return self.txs, nil, self.txs
+}
+
+func (self *leasePool) Get(hash common.Hash) *types.Transaction {
+	for _, tx := range self.txs {
+		if tx.Hash() == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
+func newSpendingTx(nils ...c_type.Uint256) *types.Transaction {
+	ins := []stx_v1.In_C{}
+	for _, Nil := range nils {
+		ins = append(ins, stx_v1.In_C{Nil: Nil})
+	}
+	return types.NewTxWithGTx(25000, big.NewInt(1), &stx.T{
+		Fee: assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.NewU256(1)},
+		Tx1: stx_v1.Tx{Ins_C: ins},
+	})
+}
+
+func txHashOf(tx *types.Transaction) *c_type.Uint256 {
+	return tx.Hash().HashToUint256()
+}
+
+func TestLease(t *testing.T) {
+	ex, remove := newHistoryExchange(t)
+	defer remove()
+
+	root := c_type.Uint256{1}
+	ex.reserve(root, "genTx")
+	created, _ := ex.usedFlag.Load(root)
+	if r := created.(*Reservation); r.Owner != "genTx" || r.TxHash != nil || r.Expiry.Sub(r.Created) != DefaultLease {
+		t.Fatalf("reservation mismatch: %v", r)
+	}
+
+	// Only the reserved roots are leased
+	hash := c_type.Uint256{9}
+	if count := ex.Lease([]c_type.Uint256{root, {2}}, "payout", time.Hour, &hash); count != 1 {
+		t.Fatalf("leased count mismatch: have %d, want 1", count)
+	}
+	value, _ := ex.usedFlag.Load(root)
+	r := value.(*Reservation)
+	if r.Owner != "payout" || r.TxHash == nil || *r.TxHash != hash {
+		t.Fatalf("lease mismatch: %v", r)
+	}
+	if left := time.Until(r.Expiry); left < 59*time.Minute || left > time.Hour {
+		t.Fatalf("lease expiry mismatch: %v left", left)
+	}
+	if r.Created != created.(*Reservation).Created {
+		t.Errorf("lease changed the creation time")
+	}
+	if _, ok := ex.usedFlag.Load(c_type.Uint256{2}); ok {
+		t.Errorf("lease reserved an unknown root")
+	}
+
+	// An empty owner and no ttl keep the lease
+	if count := ex.Lease([]c_type.Uint256{root}, "", 0, nil); count != 1 {
+		t.Fatalf("leased count mismatch: have %d, want 1", count)
+	}
+	value, _ = ex.usedFlag.Load(root)
+	if kept := value.(*Reservation); kept.Owner != "payout" || kept.Expiry != r.Expiry || *kept.TxHash != hash {
+		t.Fatalf("lease not kept: %v", kept)
+	}
+}
+
+func TestReleaseExpired(t *testing.T) {
+	ex, remove := newHistoryExchange(t)
+	defer remove()
+	pk := c_type.Uint512{1}
+
+	inPool := putSpendableUtxo(ex, pk, 1, 100)
+	inBlock := putSpendableUtxo(ex, pk, 2, 100)
+	free := putSpendableUtxo(ex, pk, 3, 100)
+	poolTx := newSpendingTx(inPool.Nil)
+	blockTx := newSpendingTx(inBlock.Nil)
+	pendingTx := newSpendingTx(c_type.Uint256{4, 1})
+	minedTx := newSpendingTx(c_type.Uint256{5, 1})
+
+	// The mined transaction is in the chain but below the recent blocks
+	chaindb := serodb.NewMemDatabase()
+	mined := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(100)}).WithBody(types.Transactions{minedTx})
+	rawdb.WriteBlock(chaindb, mined)
+	rawdb.WriteTxLookupEntries(chaindb, mined)
+	bc := txtool.Ref_inst.Bc
+	defer func() { txtool.Ref_inst.Bc = bc }()
+	txtool.Ref_inst.Bc = &leaseChain{db: chaindb, blocks: map[uint64]*types.Block{
+		2: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2)}).WithBody(types.Transactions{blockTx}),
+	}}
+	pool := &leasePool{txs: types.Transactions{poolTx, pendingTx}}
+
+	ex.reserve(inPool.Root, "genTx")
+	ex.reserve(inBlock.Root, "genTx")
+	ex.reserve(free.Root, "genTx")
+	for _, r := range []struct {
+		root c_type.Uint256
+		tx   *types.Transaction
+	}{{c_type.Uint256{4}, pendingTx}, {c_type.Uint256{5}, minedTx}, {c_type.Uint256{6}, newSpendingTx(c_type.Uint256{6, 1})}} {
+		ex.reserve(r.root, "genTx")
+		ex.Lease([]c_type.Uint256{r.root}, "", 0, txHashOf(r.tx))
+	}
+
+	// Nothing is released before the deadline
+	ex.releaseExpiredAt(time.Now(), pool)
+	if list := ex.ListReservations(nil); len(list) != 6 {
+		t.Fatalf("reservations released before the deadline: %d left", len(list))
+	}
+
+	// Past the deadline only the leases of no pending or mined transaction go
+	ex.releaseExpiredAt(time.Now().Add(DefaultLease+time.Second), pool)
+	kept := map[c_type.Uint256]bool{}
+	for _, r := range ex.ListReservations(nil) {
+		kept[r.Root] = true
+	}
+	for _, root := range []c_type.Uint256{inPool.Root, inBlock.Root, {4}, {5}} {
+		if !kept[root] {
+			t.Errorf("reservation of %x released", root[:1])
+		}
+	}
+	for _, root := range []c_type.Uint256{free.Root, {6}} {
+		if kept[root] {
+			t.Errorf("reservation of %x not released", root[:1])
+		}
+	}
+}
+
+func TestListReservations(t *testing.T) {
+	ex, remove := newHistoryExchange(t)
+	defer remove()
+	pk1, pk2 := c_type.Uint512{1}, c_type.Uint512{2}
+
+	first := putSpendableUtxo(ex, pk1, 1, 100)
+	second := putSpendableUtxo(ex, pk2, 2, 100)
+	third := putSpendableUtxo(ex, pk1, 3, 100)
+	ex.reserve(first.Root, "genTx")
+	ex.reserve(second.Root, "genBatchTx")
+	ex.reserve(third.Root, "merge")
+	ex.Lease([]c_type.Uint256{first.Root}, "", time.Hour, nil)
+
+	// All reservations are listed by expiry
+	list := ex.ListReservations(nil)
+	if len(list) != 3 || list[2].Root != first.Root {
+		t.Fatalf("reservations mismatch: %v", list)
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i].Expiry.Before(list[i-1].Expiry) {
+			t.Fatalf("reservations not ordered by expiry: %v", list)
+		}
+	}
+
+	// The reservations of an account only
+	list = ex.ListReservations(&pk1)
+	if len(list) != 2 || list[0].Root != third.Root || list[0].Owner != "merge" || list[1].Root != first.Root {
+		t.Fatalf("account reservations mismatch: %v", list)
+	}
+	if list = ex.ListReservations(&c_type.Uint512{3}); len(list) != 0 {
+		t.Fatalf("reservations of an unknown account: %v", list)
+	}
+}
@@ -0,0 +1,284 @@
+package exchange
+
+import (
+	"bytes"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// reorgDepth is how far below the chain head the exchange keeps the
+// information needed to unwind indexed blocks.
+var reorgDepth = uint64(512)
+
+var undoPrefix = []byte("UNDO")
+
+// "UNDO" + seq => undoRecord
+func undoKey(seq uint64) []byte {
+	return append(undoPrefix, utils.EncodeNumber(seq)...)
+}
+
+// undoRecord holds the block hashes of one indexing pass together with the
+// previous value of every key the pass wrote, so the pass can be reverted.
+type undoRecord struct {
+	Start  uint64
+	Hashes []c_type.Uint256
+	Pks    []c_type.Uint512
+	Keys   [][]byte
+	Values [][]byte
+	Exists []bool
+}
+
+func (self *undoRecord) End() uint64 {
+	return self.Start + uint64(len(self.Hashes)) - 1
+}
+
+// undoBatch captures the pre-image of each key before it is first modified.
+type undoBatch struct {
+	serodb.Batch
+	db     serodb.Getter
+	seen   map[string]bool
+	seq    uint64
+	record *undoRecord
+}
+
+func (self *undoBatch) remember(key []byte) {
+	if self.seen[string(key)] {
+		return
+	}
+	self.seen[string(key)] = true
+	value, err := self.db.Get(key)
+	self.record.Keys = append(self.record.Keys, common.CopyBytes(key))
+	self.record.Values = append(self.record.Values, value)
+	self.record.Exists = append(self.record.Exists, err == nil)
+}
+
+func (self *undoBatch) Put(key []byte, value []byte) error {
+	self.remember(key)
+	return self.Batch.Put(key, value)
+}
+
+func (self *undoBatch) Delete(key []byte) error {
+	self.remember(key)
+	return self.Batch.Delete(key)
+}
+
+func (self *undoBatch) Write() error {
+	data, err := rlp.EncodeToBytes(self.record)
+	if err != nil {
+		return err
+	}
+	if err = self.Batch.Put(undoKey(self.seq), data); err != nil {
+		return err
+	}
+	return self.Batch.Write()
+}
+
+// newIndexBatch returns the batch used to index blocks for pks. Passes that end
+// within reorgDepth of the chain head are journaled so they can be rolled back.
+func (self *Exchange) newIndexBatch(pks []c_type.Uint512, blocks []txtool.Block) serodb.Batch {
+	batch := self.db.NewBatch()
+	if len(blocks) == 0 || txtool.Ref_inst.Bc == nil {
+		return batch
+	}
+	head := txtool.Ref_inst.Bc.GetCurrenHeader().Number.Uint64()
+	if uint64(blocks[len(blocks)-1].Num)+reorgDepth < head {
+		return batch
+	}
+
+	record := &undoRecord{Start: uint64(blocks[0].Num), Pks: pks}
+	for _, block := range blocks {
+		record.Hashes = append(record.Hashes, block.Hash)
+	}
+	self.undoSeq++
+	return &undoBatch{
+		Batch:  batch,
+		db:     self.db,
+		seen:   map[string]bool{},
+		seq:    self.undoSeq,
+		record: record,
+	}
+}
+
+type undoEntry struct {
+	seq    uint64
+	record undoRecord
+}
+
+// undoRecords returns the journaled passes ordered by sequence number.
+func (self *Exchange) undoRecords() (entries []undoEntry) {
+	iterator := self.db.NewIteratorWithPrefix(undoPrefix)
+	for iterator.Next() {
+		key := iterator.Key()
+		var record undoRecord
+		if err := rlp.Decode(bytes.NewReader(iterator.Value()), &record); err != nil {
+			log.Error("Exchange Invalid undo RLP", "key", common.Bytes2Hex(key), "err", err)
+			continue
+		}
+		entries = append(entries, undoEntry{utils.DecodeNumber(key[len(undoPrefix):]), record})
+	}
+	iterator.Release()
+	return
+}
+
+func (self *Exchange) loadUndoSeq() {
+	iterator := self.db.NewIteratorWithPrefix(undoPrefix)
+	if iterator.Last() {
+		self.undoSeq = utils.DecodeNumber(iterator.Key()[len(undoPrefix):])
+	}
+	iterator.Release()
+}
+
+// findReorg returns the lowest indexed height whose recorded hash is no
+// longer part of the canonical chain, a height above the head of a shorter
+// canonical chain included.
+func (self *Exchange) findReorg() (num uint64, ok bool) {
+	bc := txtool.Ref_inst.Bc
+	for _, entry := range self.undoRecords() {
+		for i, hash := range entry.record.Hashes {
+			height := entry.record.Start + uint64(i)
+			if ok && height >= num {
+				break
+			}
+			header := bc.GetHeaderByNumber(height)
+			if header == nil || *header.Hash().HashToUint256() != hash {
+				num, ok = height, true
+				break
+			}
+		}
+	}
+	return
+}
+
+// removedEvents returns the received UTXOs that reverting record drops from
+// the index, read from the roots the pass stored for each account and block.
+func (self *Exchange) removedEvents(record *undoRecord) (events []UtxoEvent) {
+	size := len(utxoPrefix) + 8 + len(c_type.Uint512{})
+	for i, key := range record.Keys {
+		if record.Exists[i] || len(key) != size || !bytes.HasPrefix(key, utxoPrefix) {
+			continue
+		}
+		data, err := self.db.Get(key)
+		if err != nil {
+			continue
+		}
+		var roots []c_type.Uint256
+		if err := rlp.Decode(bytes.NewReader(data), &roots); err != nil {
+			log.Error("Exchange Invalid roots RLP", "key", common.Bytes2Hex(key), "err", err)
+			continue
+		}
+		var pk c_type.Uint512
+		copy(pk[:], key[len(utxoPrefix)+8:])
+		for _, root := range roots {
+			utxo, err := self.getUtxo(root)
+			if err != nil || utxo.Asset.Tkn == nil {
+				continue
+			}
+			event := newUtxoEvent(UtxoReceived, pk, &utxo, utxo.Num)
+			event.Removed = true
+			events = append(events, event)
+		}
+	}
+	return
+}
+
+// rollback reverts every indexing pass that touched blocks at or above num,
+// together with all passes written after it, and rewinds the accounts so the
+// blocks are indexed again from the new canonical branch. It returns the
+// removal events of the received UTXOs the reverted passes had indexed.
+func (self *Exchange) rollback(num uint64) (events []UtxoEvent, err error) {
+	entries := self.undoRecords()
+
+	first := -1
+	for i, entry := range entries {
+		if entry.record.End() >= num {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return
+	}
+
+	batch := self.db.NewBatch()
+	numbers := map[c_type.Uint512]uint64{}
+	for i := len(entries) - 1; i >= first; i-- {
+		record := &entries[i].record
+		events = append(events, self.removedEvents(record)...)
+		for j, key := range record.Keys {
+			if record.Exists[j] {
+				err = batch.Put(key, record.Values[j])
+			} else {
+				err = batch.Delete(key)
+			}
+			if err != nil {
+				return
+			}
+		}
+		if err = batch.Delete(undoKey(entries[i].seq)); err != nil {
+			return
+		}
+		for _, pk := range record.Pks {
+			numbers[pk] = record.Start
+		}
+	}
+	if err = batch.Write(); err != nil {
+		return nil, err
+	}
+
+	for pk, start := range numbers {
+		if _, ok := self.numbers.Load(pk); ok {
+			self.numbers.Store(pk, start)
+		}
+		if account := self.getAccountByPk(pk); account != nil {
+			account.isChanged = true
+		}
+	}
+	log.Warn("Exchange rollback", "from", num, "passes", len(entries)-first)
+	return
+}
+
+// pruneUndo drops journals of passes that are now too deep to be reorganized.
+func (self *Exchange) pruneUndo() {
+	head := txtool.Ref_inst.Bc.GetCurrenHeader().Number.Uint64()
+	batch := self.db.NewBatch()
+	for _, entry := range self.undoRecords() {
+		if entry.record.End()+reorgDepth < head {
+			batch.Delete(undoKey(entry.seq))
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Exchange pruneUndo", "error", err)
+	}
+}
+
+// checkReorg unwinds the index when blocks it has seen left the canonical chain
+// and returns the events to post for the UTXOs that were removed.
+func (self *Exchange) checkReorg() (events []UtxoEvent) {
+	if num, ok := self.findReorg(); ok {
+		log.Warn("Exchange detected chain reorg", "blockNumber", num)
+		var err error
+		if events, err = self.rollback(num); err != nil {
+			log.Error("Exchange rollback", "error", err)
+			events = nil
+		}
+	}
+	self.pruneUndo()
+	return
+}
+
+// unwindReorg checks for a reorg under the index lock and posts the removal
+// events once the lock is released.
+func (self *Exchange) unwindReorg() {
+	self.indexLock.Lock()
+	events := self.checkReorg()
+	self.indexLock.Unlock()
+	if len(events) > 0 {
+		self.feed.Send(events)
+	}
+}
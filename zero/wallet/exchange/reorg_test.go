@@ -0,0 +1,164 @@
+package exchange
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func toBlocks(chain []*types.Block) (blocks []txtool.Block) {
+	for _, b := range chain {
+		blocks = append(blocks, txtool.Block{Num: hexutil.Uint64(b.NumberU64()), Hash: *b.Hash().HashToUint256()})
+	}
+	return
+}
+
+func TestExchangeRollbackOnReorg(t *testing.T) {
+	cpt.ZeroInit_NoCircuit()
+	for _, engine := range serodb.Engines() {
+		t.Run(engine, func(t *testing.T) {
+			testExchangeRollbackOnReorg(t, engine)
+		})
+	}
+}
+
+func testExchangeRollbackOnReorg(t *testing.T, engine string) {
+	var (
+		chaindb = serodb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(chaindb)
+	)
+	blockchain, _ := core.NewBlockChain(chaindb, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil)
+	defer blockchain.Stop()
+	txtool.Ref_inst.SetBC(&core.State1BlockChain{blockchain})
+
+	chainA, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), chaindb, 10, nil)
+	if _, err := blockchain.InsertChain(chainA); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "exchange")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := serodb.OpenEngine(engine, dir, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ex := &Exchange{db: db}
+	pk := c_type.Uint512{1}
+	db.Put(numKey(pk), utils.EncodeNumber(1))
+	ex.numbers.Store(pk, uint64(1))
+
+	batch := ex.newIndexBatch([]c_type.Uint512{pk}, toBlocks(chainA))
+	batch.Put(utxoKey(8, pk), []byte{1})
+	batch.Put(numKey(pk), utils.EncodeNumber(11))
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	ex.numbers.Store(pk, uint64(11))
+
+	if num, ok := ex.findReorg(); ok {
+		t.Fatalf("unexpected reorg at %d", num)
+	}
+
+	chainB, _ := core.GenerateChain(params.TestChainConfig, chainA[4], ethash.NewFaker(), chaindb, 8, func(i int, b *core.BlockGen) {
+		b.SetExtra([]byte("fork"))
+	})
+	if _, err := blockchain.InsertChain(chainB); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+
+	num, ok := ex.findReorg()
+	if !ok || num != 6 {
+		t.Fatalf("reorg point mismatch: have %d/%v, want 6/true", num, ok)
+	}
+	ex.checkReorg()
+
+	if has, _ := db.Has(utxoKey(8, pk)); has {
+		t.Errorf("utxo from orphaned block still indexed")
+	}
+	if value, _ := db.Get(numKey(pk)); utils.DecodeNumber(value) != 1 {
+		t.Errorf("indexed number mismatch: have %d, want 1", utils.DecodeNumber(value))
+	}
+	if value, _ := ex.numbers.Load(pk); value.(uint64) != 1 {
+		t.Errorf("account number mismatch: have %d, want 1", value)
+	}
+	if entries := ex.undoRecords(); len(entries) != 0 {
+		t.Errorf("undo journal not cleared: %d entries", len(entries))
+	}
+}
+
+// reorgChain serves the canonical headers of the reorg tests.
+type reorgChain struct {
+	txtool.BlockChain
+	headers map[uint64]*types.Header
+}
+
+func (self *reorgChain) GetHeaderByNumber(num uint64) *types.Header {
+	return self.headers[num]
+}
+
+func (self *reorgChain) GetCurrenHeader() *types.Header {
+	var head *types.Header
+	for _, header := range self.headers {
+		if head == nil || header.Number.Cmp(head.Number) > 0 {
+			head = header
+		}
+	}
+	return head
+}
+
+func TestExchangeReorgToShorterChain(t *testing.T) {
+	chain := &reorgChain{headers: map[uint64]*types.Header{}}
+	var blocks []txtool.Block
+	for num := uint64(1); num <= 5; num++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(num), Extra: []byte("a")}
+		chain.headers[num] = header
+		blocks = append(blocks, txtool.Block{Num: hexutil.Uint64(num), Hash: *header.Hash().HashToUint256()})
+	}
+	bc := txtool.Ref_inst.Bc
+	defer func() { txtool.Ref_inst.Bc = bc }()
+	txtool.Ref_inst.Bc = chain
+
+	ex, remove := newHistoryExchange(t)
+	defer remove()
+	pk := c_type.Uint512{1}
+	batch := ex.newIndexBatch([]c_type.Uint512{pk}, blocks)
+	batch.Put(numKey(pk), utils.EncodeNumber(6))
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if num, ok := ex.findReorg(); ok {
+		t.Fatalf("unexpected reorg at %d", num)
+	}
+
+	// The canonical chain no more reaches the indexed blocks 4 and 5
+	delete(chain.headers, 5)
+	delete(chain.headers, 4)
+	if num, ok := ex.findReorg(); !ok || num != 4 {
+		t.Fatalf("reorg point mismatch: have %d/%v, want 4/true", num, ok)
+	}
+
+	// A replaced block below the head of the shorter chain comes first
+	chain.headers[3] = &types.Header{Number: big.NewInt(3), Extra: []byte("b")}
+	if num, ok := ex.findReorg(); !ok || num != 3 {
+		t.Fatalf("reorg point mismatch: have %d/%v, want 3/true", num, ok)
+	}
+}
@@ -0,0 +1,179 @@
+package exchange
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/sero-cash/go-sero/zero/txtool/prepare"
+)
+
+const (
+	DefaultSelector        = "default"
+	LargestFirstSelector   = "largestFirst"
+	SmallestFirstSelector  = "smallestFirst"
+	BranchAndBoundSelector = "branchAndBound"
+	FewestInputsSelector   = "fewestInputs"
+)
+
+// maxOIns is the number of o inputs a single transaction may carry.
+var maxOIns = prepare.MaxOIns
+
+// bnbMaxTries bounds the branch-and-bound search.
+var bnbMaxTries = 100000
+
+// CoinSelector picks the utxos that pay amount out of the spendable candidates.
+type CoinSelector interface {
+	Name() string
+	Select(candidates UtxoList, amount *big.Int) (selected UtxoList, remain *big.Int)
+}
+
+var coinSelectors = map[string]CoinSelector{
+	DefaultSelector:        &defaultSelector{},
+	LargestFirstSelector:   &largestFirst{},
+	SmallestFirstSelector:  &smallestFirst{},
+	BranchAndBoundSelector: &branchAndBound{},
+	FewestInputsSelector:   &fewestInputs{},
+}
+
+func GetCoinSelector(name string) (CoinSelector, error) {
+	if selector, ok := coinSelectors[name]; ok {
+		return selector, nil
+	}
+	return nil, fmt.Errorf("unknown coin selector %v", name)
+}
+
+func utxoValue(utxo *Utxo) *big.Int {
+	return utxo.Asset.Tkn.Value.ToIntRef()
+}
+
+func sortByValue(list UtxoList, desc bool) UtxoList {
+	sorted := append(UtxoList{}, list...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		cmp := utxoValue(&sorted[i]).Cmp(utxoValue(&sorted[j]))
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	return sorted
+}
+
+func takeGreedy(list UtxoList, amount *big.Int) (selected UtxoList, remain *big.Int) {
+	remain = new(big.Int).Set(amount)
+	for _, utxo := range list {
+		if remain.Sign() <= 0 {
+			break
+		}
+		selected = append(selected, utxo)
+		remain.Sub(remain, utxoValue(&utxo))
+	}
+	return
+}
+
+// defaultSelector takes the utxos in the order of the database until amount is
+// paid.
+type defaultSelector struct{}
+
+func (self *defaultSelector) Name() string {
+	return DefaultSelector
+}
+
+func (self *defaultSelector) Select(candidates UtxoList, amount *big.Int) (UtxoList, *big.Int) {
+	return takeGreedy(candidates, amount)
+}
+
+type largestFirst struct{}
+
+func (self *largestFirst) Name() string {
+	return LargestFirstSelector
+}
+
+func (self *largestFirst) Select(candidates UtxoList, amount *big.Int) (UtxoList, *big.Int) {
+	return takeGreedy(sortByValue(candidates, true), amount)
+}
+
+type smallestFirst struct{}
+
+func (self *smallestFirst) Name() string {
+	return SmallestFirstSelector
+}
+
+func (self *smallestFirst) Select(candidates UtxoList, amount *big.Int) (UtxoList, *big.Int) {
+	return takeGreedy(sortByValue(candidates, false), amount)
+}
+
+// branchAndBound searches for a set of utxos that pays amount exactly so no
+// change output is left behind. It returns nothing when there is no such set
+// within bnbMaxTries.
+type branchAndBound struct{}
+
+func (self *branchAndBound) Name() string {
+	return BranchAndBoundSelector
+}
+
+func (self *branchAndBound) Select(candidates UtxoList, amount *big.Int) (UtxoList, *big.Int) {
+	sorted := sortByValue(candidates, true)
+
+	// rest[i] is the total value of sorted[i:]
+	rest := make([]*big.Int, len(sorted)+1)
+	rest[len(sorted)] = new(big.Int)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		rest[i] = new(big.Int).Add(rest[i+1], utxoValue(&sorted[i]))
+	}
+
+	tries := 0
+	picked := []int{}
+	var search func(index int, remain *big.Int) bool
+	search = func(index int, remain *big.Int) bool {
+		if remain.Sign() == 0 {
+			return true
+		}
+		tries++
+		if tries > bnbMaxTries || index >= len(sorted) || remain.Sign() < 0 || rest[index].Cmp(remain) < 0 {
+			return false
+		}
+		picked = append(picked, index)
+		if search(index+1, new(big.Int).Sub(remain, utxoValue(&sorted[index]))) {
+			return true
+		}
+		picked = picked[:len(picked)-1]
+		return search(index+1, remain)
+	}
+
+	if amount.Sign() <= 0 || !search(0, amount) {
+		return nil, new(big.Int).Set(amount)
+	}
+	selected := UtxoList{}
+	for _, i := range picked {
+		selected = append(selected, sorted[i])
+	}
+	return selected, new(big.Int)
+}
+
+// fewestInputs pays amount with as few utxos as possible while keeping the
+// number of o inputs within maxOIns.
+type fewestInputs struct{}
+
+func (self *fewestInputs) Name() string {
+	return FewestInputsSelector
+}
+
+func (self *fewestInputs) Select(candidates UtxoList, amount *big.Int) (selected UtxoList, remain *big.Int) {
+	remain = new(big.Int).Set(amount)
+	ocount := 0
+	for _, utxo := range sortByValue(candidates, true) {
+		if remain.Sign() <= 0 {
+			break
+		}
+		if !utxo.IsZ {
+			if ocount >= maxOIns {
+				continue
+			}
+			ocount++
+		}
+		selected = append(selected, utxo)
+		remain.Sub(remain, utxoValue(&utxo))
+	}
+	return
+}
@@ -0,0 +1,87 @@
+package exchange
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func testUtxos(values ...uint64) (list UtxoList) {
+	for i, v := range values {
+		list = append(list, Utxo{
+			Root:  c_type.Uint256{byte(i + 1)},
+			Asset: assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.NewU256(v)}},
+		})
+	}
+	return
+}
+
+func sumValues(list UtxoList) uint64 {
+	sum := uint64(0)
+	for _, utxo := range list {
+		sum += utxoValue(&utxo).Uint64()
+	}
+	return sum
+}
+
+func TestCoinSelectors(t *testing.T) {
+	candidates := testUtxos(1, 50, 7, 30, 12, 100)
+
+	tests := []struct {
+		selector string
+		amount   int64
+		count    int
+		sum      uint64
+	}{
+		{LargestFirstSelector, 120, 2, 150},
+		{SmallestFirstSelector, 15, 3, 20},
+		{BranchAndBoundSelector, 37, 2, 37},
+		{BranchAndBoundSelector, 1000, 0, 0},
+		{FewestInputsSelector, 140, 2, 150},
+		{DefaultSelector, 55, 3, 58},
+	}
+	for i, test := range tests {
+		selector, err := GetCoinSelector(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		selected, remain := selector.Select(candidates, big.NewInt(test.amount))
+		if len(selected) != test.count || sumValues(selected) != test.sum {
+			t.Errorf("test %d (%s): selected %d utxos worth %d, want %d worth %d", i, test.selector, len(selected), sumValues(selected), test.count, test.sum)
+		}
+		want := test.amount - int64(test.sum)
+		if test.count == 0 {
+			want = test.amount
+		}
+		if remain.Int64() != want {
+			t.Errorf("test %d (%s): remain %v, want %d", i, test.selector, remain, want)
+		}
+	}
+
+	if _, err := GetCoinSelector("unknown"); err == nil {
+		t.Errorf("expected error for unknown selector")
+	}
+}
+
+func TestFewestInputsOInsLimit(t *testing.T) {
+	defer func(n int) { maxOIns = n }(maxOIns)
+	maxOIns = 1
+
+	candidates := testUtxos(100, 90, 5, 5)
+	candidates[2].IsZ = true
+	candidates[3].IsZ = true
+
+	selector, _ := GetCoinSelector(FewestInputsSelector)
+	selected, remain := selector.Select(candidates, big.NewInt(108))
+	if len(selected) != 3 || remain.Sign() > 0 {
+		t.Fatalf("selected %d utxos, remain %v", len(selected), remain)
+	}
+	for _, utxo := range selected {
+		if utxo.Root == candidates[1].Root {
+			t.Errorf("second o utxo selected above the o inputs limit")
+		}
+	}
+}
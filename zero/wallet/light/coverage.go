@@ -0,0 +1,225 @@
+package light
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+// Range is the blocks [Start, End].
+type Range struct {
+	Start uint64
+	End   uint64
+}
+
+// Ranges is a sorted list of disjoint ranges.
+type Ranges []Range
+
+// add returns the ranges merged with r.
+func (self Ranges) add(r Range) (ranges Ranges) {
+	all := append(append(Ranges{}, self...), r)
+	sort.Slice(all, func(i, j int) bool { return all[i].Start < all[j].Start })
+	for _, r := range all {
+		if n := len(ranges); n > 0 && r.Start <= ranges[n-1].End+1 {
+			if r.End > ranges[n-1].End {
+				ranges[n-1].End = r.End
+			}
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	return
+}
+
+// missing returns the parts of r not in the ranges.
+func (self Ranges) missing(r Range) (ranges Ranges) {
+	start := r.Start
+	for _, c := range self {
+		if c.End < start {
+			continue
+		}
+		if c.Start > r.End {
+			break
+		}
+		if c.Start > start {
+			ranges = append(ranges, Range{start, c.Start - 1})
+		}
+		start = c.End + 1
+		if start > r.End || start == 0 {
+			return
+		}
+	}
+	return append(ranges, Range{start, r.End})
+}
+
+// Coverage tells the blocks the light node has indexed, the outs of the blocks
+// outside of Covered are not served.
+type Coverage struct {
+	Start   uint64
+	Current uint64
+	Covered Ranges
+	Pending Ranges
+}
+
+var (
+	coveredKey  = []byte("LIGHT_COVERED")
+	backfillKey = []byte("LIGHT_BACKFILL")
+)
+
+// rangesStore keeps the covered and the pending backfill ranges of the light
+// database, written along with the indexed blocks.
+type rangesStore struct {
+	lock    sync.Mutex
+	covered Ranges
+	pending Ranges
+}
+
+func readRanges(db serodb.Database, key []byte) (ranges Ranges) {
+	data, err := db.Get(key)
+	if err != nil {
+		return nil
+	}
+	if err := rlp.DecodeBytes(data, &ranges); err != nil {
+		log.Error("Light invalid ranges", "key", string(key), "err", err)
+		return nil
+	}
+	return
+}
+
+func putRanges(batch serodb.Batch, key []byte, ranges Ranges) {
+	data, err := rlp.EncodeToBytes(ranges)
+	if err != nil {
+		log.Error("Light encode ranges", "key", string(key), "err", err)
+		return
+	}
+	batch.Put(key, data)
+}
+
+// loadRanges reads the ranges, a database written before they were tracked
+// is covered from the start it was created with to its sync number.
+func (self *LightNode) loadRanges() {
+	self.ranges.covered = readRanges(self.db, coveredKey)
+	self.ranges.pending = readRanges(self.db, backfillKey)
+	if self.ranges.covered != nil {
+		return
+	}
+	value, err := self.db.Get(numKey())
+	if err != nil {
+		return
+	}
+	if last, start := bytesToUint64(value), zconfig.Default_light_start_num(); last > start {
+		self.ranges.covered = Ranges{{start + 1, last}}
+	}
+}
+
+// writeIndexed writes the batch of the indexed blocks r with the updated ranges.
+func (self *LightNode) writeIndexed(batch serodb.Batch, r Range) error {
+	self.ranges.lock.Lock()
+	defer self.ranges.lock.Unlock()
+
+	covered := self.ranges.covered.add(r)
+	putRanges(batch, coveredKey, covered)
+	var pending Ranges
+	for _, p := range self.ranges.pending {
+		pending = append(pending, covered.missing(p)...)
+	}
+	putRanges(batch, backfillKey, pending)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	self.ranges.covered = covered
+	self.ranges.pending = pending
+	return nil
+}
+
+// Backfill schedules the indexing of the blocks [start, end] the light node has
+// not covered yet and returns the pending ranges.
+func (self *LightNode) Backfill(start, end uint64) (Ranges, error) {
+	if start > end {
+		return nil, errors.New("light backfill start is after end")
+	}
+	if current := self.getLastNumber(); end > current {
+		end = current
+	}
+	if start == 0 {
+		start = 1
+	}
+
+	self.ranges.lock.Lock()
+	defer self.ranges.lock.Unlock()
+	pending := self.ranges.pending
+	if start <= end {
+		for _, r := range self.ranges.covered.missing(Range{start, end}) {
+			pending = pending.add(r)
+		}
+	}
+	batch := self.db.NewBatch()
+	putRanges(batch, backfillKey, pending)
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	self.ranges.pending = pending
+	log.Info("Light backfill scheduled", "start", start, "end", end, "pending", len(pending))
+	return append(Ranges{}, pending...), nil
+}
+
+func (self *LightNode) nextBackfill() (r Range, ok bool) {
+	self.ranges.lock.Lock()
+	defer self.ranges.lock.Unlock()
+	if len(self.ranges.pending) == 0 {
+		return
+	}
+	r = self.ranges.pending[0]
+	if r.End-r.Start+1 > fetchCount {
+		r.End = r.Start + fetchCount - 1
+	}
+	return r, true
+}
+
+// backfill indexes the next blocks of the pending ranges.
+func (self *LightNode) backfill() {
+	if !self.chainReady() {
+		return
+	}
+	r, ok := self.nextBackfill()
+	if !ok {
+		return
+	}
+	blocks, err := self.sri.GetBlocksInfo(r.Start, r.End-r.Start+1)
+	if err != nil {
+		log.Error("light backfill GetBlocksInfo", "start", r.Start, "err", err)
+		return
+	}
+	if uint64(len(blocks)) != r.End-r.Start+1 {
+		log.Error("light backfill blocks missing", "start", r.Start, "end", r.End, "blocks", len(blocks))
+		return
+	}
+	batch := self.db.NewBatch()
+	if err := self.indexBlocks(batch, blocks, nil); err != nil {
+		log.Error("light backfill index", "start", r.Start, "err", err)
+		return
+	}
+	if err := self.writeIndexed(batch, r); err != nil {
+		log.Error("light backfill write", "start", r.Start, "err", err)
+		return
+	}
+	log.Info("Light backfilled", "start", r.Start, "end", r.End)
+}
+
+// Coverage returns the blocks indexed by the light node.
+func (self *LightNode) Coverage() Coverage {
+	current := self.getLastNumber()
+	self.ranges.lock.Lock()
+	defer self.ranges.lock.Unlock()
+	return Coverage{
+		Start:   zconfig.Light_start_num(),
+		Current: current,
+		Covered: append(Ranges{}, self.ranges.covered...),
+		Pending: append(Ranges{}, self.ranges.pending...),
+	}
+}
@@ -0,0 +1,30 @@
+package light
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRanges(t *testing.T) {
+	var ranges Ranges
+	ranges = ranges.add(Range{10, 20})
+	ranges = ranges.add(Range{30, 40})
+	ranges = ranges.add(Range{21, 25})
+	if want := (Ranges{{10, 25}, {30, 40}}); !reflect.DeepEqual(ranges, want) {
+		t.Errorf("add mismatch: have %v, want %v", ranges, want)
+	}
+	if ranges = ranges.add(Range{26, 29}); !reflect.DeepEqual(ranges, Ranges{{10, 40}}) {
+		t.Errorf("add did not merge: %v", ranges)
+	}
+
+	ranges = Ranges{{10, 20}, {30, 40}}
+	if missing := ranges.missing(Range{1, 50}); !reflect.DeepEqual(missing, Ranges{{1, 9}, {21, 29}, {41, 50}}) {
+		t.Errorf("missing mismatch: %v", missing)
+	}
+	if missing := ranges.missing(Range{12, 18}); len(missing) != 0 {
+		t.Errorf("covered range missing: %v", missing)
+	}
+	if missing := ranges.missing(Range{15, 35}); !reflect.DeepEqual(missing, Ranges{{21, 29}}) {
+		t.Errorf("missing mismatch: %v", missing)
+	}
+}
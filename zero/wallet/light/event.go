@@ -0,0 +1,65 @@
+package light
+
+import (
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/log"
+)
+
+// outsQueueSize is how many indexed passes may wait for the subscribers.
+const outsQueueSize = 64
+
+// OutsEvent is posted when the light node indexes new blocks, with their outs
+// by PKr and the nils, roots and traces they spend.
+type OutsEvent struct {
+	Outs map[c_type.PKr][]BlockOut
+	Nils map[c_type.Uint256]NilValue
+}
+
+func newOutsEvent() *OutsEvent {
+	return &OutsEvent{
+		Outs: make(map[c_type.PKr][]BlockOut),
+		Nils: make(map[c_type.Uint256]NilValue),
+	}
+}
+
+func (self *OutsEvent) addOuts(pkr c_type.PKr, num uint64, data []BlockData) {
+	if self != nil {
+		self.Outs[pkr] = append(self.Outs[pkr], BlockOut{Num: num, Data: data})
+	}
+}
+
+func (self *OutsEvent) addNil(key c_type.Uint256, value NilValue) {
+	if self != nil {
+		value.Nil = key
+		self.Nils[key] = value
+	}
+}
+
+func (self *OutsEvent) empty() bool {
+	return len(self.Outs) == 0 && len(self.Nils) == 0
+}
+
+// SubscribeOutsEvent registers a subscription for the blocks indexed by the
+// light node.
+func (self *LightNode) SubscribeOutsEvent(ch chan<- OutsEvent) event.Subscription {
+	return self.outsFeed.Subscribe(ch)
+}
+
+// queueOuts hands ev to the sending loop without waiting for the subscribers,
+// so a stalled subscriber never holds up the indexing. The event is dropped
+// when the queue is full.
+func (self *LightNode) queueOuts(ev OutsEvent) {
+	select {
+	case self.outsQueue <- ev:
+	default:
+		log.Warn("light outs event dropped", "outs", len(ev.Outs), "nils", len(ev.Nils))
+	}
+}
+
+// postOuts sends the queued events to the subscribers.
+func (self *LightNode) postOuts() {
+	for ev := range self.outsQueue {
+		self.outsFeed.Send(ev)
+	}
+}
@@ -0,0 +1,56 @@
+package light
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+)
+
+func TestQueueOuts(t *testing.T) {
+	node := &LightNode{outsQueue: make(chan OutsEvent, 2)}
+	go node.postOuts()
+	defer close(node.outsQueue)
+
+	// A subscriber that never reads stalls the sending loop only
+	stalled := make(chan OutsEvent)
+	sub := node.SubscribeOutsEvent(stalled)
+	defer sub.Unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			ev := newOutsEvent()
+			ev.addNil(c_type.Uint256{byte(i)}, NilValue{Num: uint64(i)})
+			node.queueOuts(*ev)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queueing blocked on a stalled subscriber")
+	}
+
+	// The queued events are delivered in order once the subscriber reads
+	// again, the ones past the queue were dropped
+	var nums []uint64
+	for drained := false; !drained; {
+		select {
+		case ev := <-stalled:
+			for _, value := range ev.Nils {
+				nums = append(nums, value.Num)
+			}
+		case <-time.After(100 * time.Millisecond):
+			drained = true
+		}
+	}
+	if len(nums) < 2 || len(nums) > 3 {
+		t.Fatalf("delivered events mismatch: %v", nums)
+	}
+	for i, num := range nums {
+		if (i == 0 && num != 0) || (i > 0 && num <= nums[i-1]) {
+			t.Fatalf("delivered events out of order: %v", nums)
+		}
+	}
+}
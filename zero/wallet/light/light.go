@@ -13,15 +13,17 @@ import (
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/core"
 	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/log"
 	"github.com/sero-cash/go-sero/rlp"
 	"github.com/sero-cash/go-sero/serodb"
 	"github.com/sero-cash/go-sero/zero/txtool"
 	"github.com/sero-cash/go-sero/zero/txtool/flight"
+	"github.com/sero-cash/go-sero/zero/zconfig"
 )
 
 type LightNode struct {
-	db   *serodb.LDBDatabase
+	db   serodb.KeyValueStore
 	bcDB serodb.Database
 	//immatureTx *ImmatureTx
 
@@ -30,6 +32,9 @@ type LightNode struct {
 	sri flight.SRI
 
 	lastNumber uint64
+	ranges     rangesStore
+	outsFeed   event.Feed
+	outsQueue  chan OutsEvent
 }
 
 var (
@@ -46,7 +51,7 @@ var (
 
 func NewLightNode(dbPath string, txPool *core.TxPool, bcDB serodb.Database) (lightNode *LightNode) {
 
-	db, err := serodb.NewLDBDatabase(dbPath, 1024, 1024)
+	db, err := serodb.NewDatabase(dbPath, 1024, 1024)
 	if err != nil {
 		panic(err)
 	}
@@ -57,10 +62,20 @@ func NewLightNode(dbPath string, txPool *core.TxPool, bcDB serodb.Database) (lig
 		db:     db,
 		bcDB:   bcDB,
 		//immatureTx: immatureTx,
+		outsQueue: make(chan OutsEvent, outsQueueSize),
 	}
 	Current_light = lightNode
+	go lightNode.postOuts()
+
+	lightNode.loadRanges()
+	if covered := lightNode.ranges.covered; len(covered) > 0 && zconfig.Light_start_num()+1 < covered[0].Start {
+		if _, err := lightNode.Backfill(zconfig.Light_start_num()+1, covered[0].Start-1); err != nil {
+			log.Error("light schedule start backfill", "err", err)
+		}
+	}
 
 	AddJob("0/10 * * * * ?", lightNode.fetchBlockInfo)
+	AddJob("5/10 * * * * ?", lightNode.backfill)
 
 	log.Info("Init NewLightNode success")
 	return
@@ -71,11 +86,7 @@ var fetchCount = uint64(5000)
 func (self *LightNode) getLastNumber() (num uint64) {
 
 	if self.lastNumber == 0 {
-		// light wallet start at block 1200000
-		var initBlockNum = uint64(1280000)
-		if seroparam.Is_Dev() {
-			initBlockNum = uint64(0)
-		}
+		initBlockNum := zconfig.Light_start_num()
 		value, err := self.db.Get(numKey())
 		if err != nil {
 			self.db.Put(numKey(), uint64ToBytes(initBlockNum))
@@ -95,11 +106,15 @@ func numKey() []byte {
 	return []byte("LIGHT_SYNC_NUM")
 }
 
+func (self *LightNode) chainReady() bool {
+	return txtool.Ref_inst.Bc != nil && txtool.Ref_inst.Bc.IsValid()
+}
+
 func (self *LightNode) fetchBlockInfo() {
 
 	//self.immatureTx.fetchBlockInfo()
 
-	if txtool.Ref_inst.Bc == nil || !txtool.Ref_inst.Bc.IsValid() {
+	if !self.chainReady() {
 		return
 	}
 
@@ -112,8 +127,34 @@ func (self *LightNode) fetchBlockInfo() {
 	if len(blocks) == 0 {
 		return
 	}
-	var count uint64 = 0
 	batch := self.db.NewBatch()
+	ev := newOutsEvent()
+	if err := self.indexBlocks(batch, blocks, ev); err != nil {
+		log.Error("light index blocks", "start", start+1, "err", err)
+		return
+	}
+	count := uint64(len(blocks))
+
+	lastNumber := self.lastNumber
+	if count < fetchCount {
+		lastNumber = start + count
+	} else {
+		lastNumber = start + fetchCount
+	}
+	batch.Put(numKey(), uint64ToBytes(lastNumber))
+	err = self.writeIndexed(batch, Range{start + 1, lastNumber})
+	if err == nil {
+		self.lastNumber = lastNumber
+		if !ev.empty() {
+			self.queueOuts(*ev)
+		}
+	}
+	return
+}
+
+// indexBlocks puts the outs and the nils of the blocks in the batch, and in ev
+// when it is not nil.
+func (self *LightNode) indexBlocks(batch serodb.Batch, blocks []txtool.Block, ev *OutsEvent) error {
 	for _, block := range blocks {
 		// PKR -> Outs
 		outs := block.Outs
@@ -187,9 +228,10 @@ func (self *LightNode) fetchBlockInfo() {
 		for pkr, v := range pkrMap {
 			data, err := rlp.EncodeToBytes(v)
 			if err != nil {
-				return
+				return err
 			}
 			batch.Put(pkrKey(pkr, uint64(block.Num)), data)
+			ev.addOuts(pkr, blockNum, v)
 		}
 		for _, tx := range body.Transactions {
 			hash := tx.Hash()
@@ -217,35 +259,39 @@ func (self *LightNode) fetchBlockInfo() {
 				TxHash: txHash,
 				TxInfo: txInfo,
 			}
-			if nilValue, err := rlp.EncodeToBytes(nilValue); err != nil {
-				return
+			if data, err := rlp.EncodeToBytes(nilValue); err != nil {
+				return err
 			} else {
+				putNil := func(key c_type.Uint256) {
+					batch.Put(nilKey(key), data)
+					ev.addNil(key, nilValue)
+				}
 				if tx.Stxt().Tx0() != nil {
 					for _, in := range tx.Stxt().Tx0().Desc_O.Ins {
-						batch.Put(nilKey(in.Nil), nilValue)
-						batch.Put(nilKey(in.Root), nilValue)
+						putNil(in.Nil)
+						putNil(in.Root)
 					}
 					for _, in := range tx.Stxt().Tx0().Desc_Z.Ins {
-						batch.Put(nilKey(in.Trace), nilValue)
-						batch.Put(nilKey(in.Nil), nilValue)
+						putNil(in.Trace)
+						putNil(in.Nil)
 					}
 				}
 				if tx.Stxt().Tx1.Ins_C != nil {
 					for _, in := range tx.Stxt().Tx1.Ins_C {
-						batch.Put(nilKey(in.Nil), nilValue)
+						putNil(in.Nil)
 					}
 				}
 				if tx.Stxt().Tx1.Ins_P != nil {
 					for _, in := range tx.Stxt().Tx1.Ins_P {
-						batch.Put(nilKey(in.Nil), nilValue)
-						batch.Put(nilKey(in.Root), nilValue)
+						putNil(in.Nil)
+						putNil(in.Root)
 					}
 				}
 				if tx.Stxt().Tx1.Ins_P0 != nil {
 					for _, in := range tx.Stxt().Tx1.Ins_P0 {
-						batch.Put(nilKey(in.Nil), nilValue)
-						batch.Put(nilKey(in.Root), nilValue)
-						batch.Put(nilKey(in.Trace), nilValue)
+						putNil(in.Nil)
+						putNil(in.Root)
+						putNil(in.Trace)
 					}
 				}
 			}
@@ -256,24 +302,8 @@ func (self *LightNode) fetchBlockInfo() {
 		//		batch.Put(nilKey(Nil, uint64(block.Num)), uint64ToBytes(1))
 		//	}
 		// }
-		count++
 	}
-	if count == 0 {
-		return
-	}
-
-	lastNumber := self.lastNumber
-	if count < fetchCount {
-		lastNumber = start + count
-	} else {
-		lastNumber = start + fetchCount
-	}
-	batch.Put(numKey(), uint64ToBytes(lastNumber))
-	err = batch.Write()
-	if err == nil {
-		self.lastNumber = lastNumber
-	}
-	return
+	return nil
 }
 
 type NilValue struct {
@@ -0,0 +1,209 @@
+package stakeservice
+
+import (
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/stake"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+var (
+	shareLedgerPrefix = []byte("SLEDGER")
+	poolLedgerPrefix  = []byte("PLEDGER")
+)
+
+// "SLEDGER" + shareId + blockNumber => LedgerEntry
+func shareLedgerKey(id common.Hash, num uint64) []byte {
+	key := append(append([]byte{}, shareLedgerPrefix...), id[:]...)
+	return append(key, utils.EncodeNumber(num)...)
+}
+
+// "PLEDGER" + poolId + blockNumber => LedgerEntry
+func poolLedgerKey(id common.Hash, num uint64) []byte {
+	key := append(append([]byte{}, poolLedgerPrefix...), id[:]...)
+	return append(key, utils.EncodeNumber(num)...)
+}
+
+// LedgerEntry is what happened to a share or a pool in one block.
+//
+// Selected counts the shares drawn to vote, Voted the votes that were rewarded
+// (for a pool only the votes it cast itself), SoloVoted the votes the shares of
+// a pool cast themselves, Missed the drawn shares whose vote window passed and
+// Expired the shares never drawn before they expired.
+// Reward is the vote reward, Income what became payable and Paid what was paid
+// out by the periodic payment.
+type LedgerEntry struct {
+	Num       uint64
+	Selected  uint32
+	Voted     uint32
+	SoloVoted uint32
+	Missed    uint32
+	Expired   uint32
+	Reward    *big.Int
+	Income    *big.Int
+	Paid      *big.Int
+}
+
+func newLedgerEntry(num uint64) *LedgerEntry {
+	return &LedgerEntry{Num: num, Reward: new(big.Int), Income: new(big.Int), Paid: new(big.Int)}
+}
+
+func (self *LedgerEntry) empty() bool {
+	return self.Selected == 0 && self.Voted == 0 && self.SoloVoted == 0 && self.Missed == 0 && self.Expired == 0 &&
+		self.Reward.Sign() == 0 && self.Income.Sign() == 0 && self.Paid.Sign() == 0
+}
+
+func (self *LedgerEntry) add(entry *LedgerEntry) {
+	self.Selected += entry.Selected
+	self.Voted += entry.Voted
+	self.SoloVoted += entry.SoloVoted
+	self.Missed += entry.Missed
+	self.Expired += entry.Expired
+	self.Reward.Add(self.Reward, entry.Reward)
+	self.Income.Add(self.Income, entry.Income)
+	self.Paid.Add(self.Paid, entry.Paid)
+}
+
+func bigOrZero(x *big.Int) *big.Int {
+	if x == nil {
+		return new(big.Int)
+	}
+	return x
+}
+
+// shareLedgerEntry derives the entry of share at num from its previous state
+// old, which is nil when the share was bought in this block.
+func shareLedgerEntry(old, share *stake.Share, num uint64) *LedgerEntry {
+	entry := newLedgerEntry(num)
+	if old == nil {
+		return entry
+	}
+	if old.Num > share.Num {
+		entry.Selected = old.Num - share.Num
+	}
+	if willVote := old.WillVoteNum + entry.Selected; willVote > share.WillVoteNum {
+		entry.Voted = willVote - share.WillVoteNum
+	}
+	if old.Status != stake.STATUS_FINISHED && share.Status == stake.STATUS_FINISHED {
+		entry.Missed = share.WillVoteNum
+	}
+	if old.Status == stake.STATUS_VALID && share.Status == stake.STATUS_OUTOFDATE {
+		entry.Expired = share.Num
+	}
+	entry.Reward.Sub(bigOrZero(share.Profit), bigOrZero(old.Profit))
+
+	returned := new(big.Int).Mul(bigOrZero(share.Value), big.NewInt(int64(entry.Voted+entry.Missed+entry.Expired)))
+	entry.Income.Add(entry.Reward, returned)
+	if share.LastPayTime == num && old.LastPayTime != num {
+		entry.Paid.Add(bigOrZero(old.Income), entry.Income)
+		entry.Paid.Sub(entry.Paid, bigOrZero(share.Income))
+	}
+	return entry
+}
+
+// poolLedgerEntry derives the entry of pool at num from its previous state.
+// voted and missed are the votes the shares of the pool were rewarded for and
+// missed in this block.
+func poolLedgerEntry(old, pool *stake.StakePool, num uint64, voted, missed uint32) *LedgerEntry {
+	entry := newLedgerEntry(num)
+	if old == nil {
+		return entry
+	}
+	if pool.ChoicedShareNum > old.ChoicedShareNum {
+		entry.Selected = pool.ChoicedShareNum - old.ChoicedShareNum
+	}
+	if missedVote := old.MissedVoteNum + entry.Selected; missedVote > pool.MissedVoteNum {
+		entry.Voted = missedVote - pool.MissedVoteNum
+	}
+	if voted > entry.Voted {
+		entry.SoloVoted = voted - entry.Voted
+	}
+	entry.Missed = missed
+	if pool.ExpireNum > old.ExpireNum {
+		entry.Expired = pool.ExpireNum - old.ExpireNum
+	}
+	entry.Reward.Sub(bigOrZero(pool.Profit), bigOrZero(old.Profit))
+
+	entry.Income.Set(entry.Reward)
+	if returned := new(big.Int).Sub(bigOrZero(old.Amount), bigOrZero(pool.Amount)); returned.Sign() > 0 {
+		entry.Income.Add(entry.Income, returned)
+	}
+	if pool.LastPayTime == num && old.LastPayTime != num {
+		entry.Paid.Add(bigOrZero(old.Income), entry.Income)
+		entry.Paid.Sub(entry.Paid, bigOrZero(pool.Income))
+	}
+	return entry
+}
+
+func putLedgerEntry(batch serodb.Batch, key []byte, entry *LedgerEntry) {
+	if entry.empty() {
+		return
+	}
+	if data, err := rlp.EncodeToBytes(entry); err != nil {
+		panic(err)
+	} else {
+		if err := batch.Put(key, data); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (self *StakeService) getPool(id common.Hash, cache map[common.Hash]*stake.StakePool) *stake.StakePool {
+	if val, ok := cache[id]; ok {
+		return val
+	}
+	hash, err := self.db.Get(poolKey(id[:]))
+	if err != nil {
+		return nil
+	}
+	ret := stake.StakePoolDB.GetObject(self.bc.GetDB(), hash, &stake.StakePool{})
+	if ret == nil {
+		return nil
+	}
+	return ret.(*stake.StakePool)
+}
+
+// LedgerPage is one page of a ledger together with the totals of the whole
+// queried range.
+type LedgerPage struct {
+	Entries []LedgerEntry
+	Total   LedgerEntry
+	Count   int
+}
+
+func (self *StakeService) ledger(prefix []byte, start, end uint64, offset, limit int) (page LedgerPage) {
+	page.Total = *newLedgerEntry(0)
+	iterator := self.db.NewIteratorWithPrefix(prefix)
+	defer iterator.Release()
+	for ok := iterator.Seek(append(append([]byte{}, prefix...), utils.EncodeNumber(start)...)); ok; ok = iterator.Next() {
+		entry := LedgerEntry{}
+		if err := rlp.DecodeBytes(iterator.Value(), &entry); err != nil {
+			log.Error("StakeService invalid ledger RLP", "key", common.Bytes2Hex(iterator.Key()), "err", err)
+			continue
+		}
+		if entry.Num >= end {
+			break
+		}
+		if page.Count >= offset && (limit <= 0 || len(page.Entries) < limit) {
+			page.Entries = append(page.Entries, entry)
+		}
+		page.Count++
+		page.Total.add(&entry)
+	}
+	return
+}
+
+// ShareLedger returns the ledger of share id for the blocks [start, end),
+// skipping offset entries and returning at most limit, all when limit is 0.
+func (self *StakeService) ShareLedger(id common.Hash, start, end uint64, offset, limit int) LedgerPage {
+	return self.ledger(append(append([]byte{}, shareLedgerPrefix...), id[:]...), start, end, offset, limit)
+}
+
+// PoolLedger returns the ledger of pool id for the blocks [start, end).
+func (self *StakeService) PoolLedger(id common.Hash, start, end uint64, offset, limit int) LedgerPage {
+	return self.ledger(append(append([]byte{}, poolLedgerPrefix...), id[:]...), start, end, offset, limit)
+}
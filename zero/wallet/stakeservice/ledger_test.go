@@ -0,0 +1,67 @@
+package stakeservice
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-sero/zero/stake"
+)
+
+func TestShareLedgerEntry(t *testing.T) {
+	old := &stake.Share{
+		Value:       big.NewInt(100),
+		Num:         10,
+		WillVoteNum: 1,
+		Status:      stake.STATUS_VALID,
+		Income:      big.NewInt(0),
+		Profit:      big.NewInt(50),
+	}
+	// two shares drawn, two votes rewarded with 30 and the income paid out
+	share := &stake.Share{
+		Value:       big.NewInt(100),
+		Num:         8,
+		WillVoteNum: 1,
+		Status:      stake.STATUS_VALID,
+		Income:      big.NewInt(0),
+		Profit:      big.NewInt(80),
+		LastPayTime: 20,
+	}
+	entry := shareLedgerEntry(old, share, 20)
+	if entry.Selected != 2 || entry.Voted != 2 || entry.Missed != 0 || entry.Expired != 0 {
+		t.Errorf("counts mismatch: %+v", entry)
+	}
+	if entry.Reward.Int64() != 30 || entry.Income.Int64() != 230 || entry.Paid.Int64() != 230 {
+		t.Errorf("amounts mismatch: reward %v income %v paid %v", entry.Reward, entry.Income, entry.Paid)
+	}
+
+	// the last pending vote is missed
+	missed := *share
+	missed.Status = stake.STATUS_FINISHED
+	missed.Income = big.NewInt(100)
+	entry = shareLedgerEntry(share, &missed, 21)
+	if entry.Missed != 1 || entry.Income.Int64() != 100 || entry.Paid.Sign() != 0 {
+		t.Errorf("missed mismatch: %+v", entry)
+	}
+
+	if entry := shareLedgerEntry(nil, share, 20); !entry.empty() {
+		t.Errorf("new share must have an empty entry: %+v", entry)
+	}
+}
+
+func TestPoolLedgerEntry(t *testing.T) {
+	old := &stake.StakePool{ChoicedShareNum: 10, MissedVoteNum: 2, Income: big.NewInt(0), Profit: big.NewInt(0)}
+	// three shares drawn, the pool voted two of them and one share voted solo
+	pool := &stake.StakePool{ChoicedShareNum: 13, MissedVoteNum: 3, Income: big.NewInt(20), Profit: big.NewInt(20)}
+	entry := poolLedgerEntry(old, pool, 30, 3, 0)
+	if entry.Selected != 3 || entry.Voted != 2 || entry.SoloVoted != 1 {
+		t.Errorf("counts mismatch: %+v", entry)
+	}
+	if entry.Reward.Int64() != 20 || entry.Income.Int64() != 20 || entry.Paid.Sign() != 0 {
+		t.Errorf("amounts mismatch: reward %v income %v paid %v", entry.Reward, entry.Income, entry.Paid)
+	}
+
+	window := PoolWindow{LedgerEntry: *entry}
+	if window.Participation() != 1 || window.SoloRatio() != float64(1)/3 {
+		t.Errorf("ratios mismatch: %v %v", window.Participation(), window.SoloRatio())
+	}
+}
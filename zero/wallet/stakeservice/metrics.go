@@ -0,0 +1,75 @@
+package stakeservice
+
+import (
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/metrics"
+	"github.com/sero-cash/go-sero/zero/stake"
+)
+
+type poolReport struct {
+	pool  *stake.StakePool
+	entry *LedgerEntry
+}
+
+// PoolWindow aggregates the ledger of a pool over the blocks [Start, End).
+type PoolWindow struct {
+	Start uint64
+	End   uint64
+	LedgerEntry
+}
+
+// Participation is the share of the selected votes that were cast, by the
+// pool or by the shares themselves.
+func (self *PoolWindow) Participation() float64 {
+	if self.Selected == 0 {
+		return 0
+	}
+	return float64(self.Voted+self.SoloVoted) / float64(self.Selected)
+}
+
+// SoloRatio is the share of the cast votes the pool left to its shares.
+func (self *PoolWindow) SoloRatio() float64 {
+	if self.Voted+self.SoloVoted == 0 {
+		return 0
+	}
+	return float64(self.SoloVoted) / float64(self.Voted+self.SoloVoted)
+}
+
+// PoolMetrics splits the blocks [start, end) into windows of window blocks and
+// returns the ledger totals of pool id in each of them.
+func (self *StakeService) PoolMetrics(id common.Hash, start, end, window uint64) (windows []PoolWindow) {
+	if window == 0 {
+		window = stake.GetStatisticsMissWindow()
+	}
+	for from := start; from < end; from += window {
+		to := from + window
+		if to > end {
+			to = end
+		}
+		page := self.PoolLedger(id, from, to, 0, 0)
+		page.Total.Num = from
+		windows = append(windows, PoolWindow{Start: from, End: to, LedgerEntry: page.Total})
+	}
+	return
+}
+
+func poolMetricName(pool *stake.StakePool, name string) string {
+	return "stake/pool/" + hexutil.Encode(pool.Id()[:8]) + "/" + name
+}
+
+// reportPoolMetrics publishes the state of a pool run by a local account.
+func reportPoolMetrics(pool *stake.StakePool, entry *LedgerEntry) {
+	metrics.GetOrRegisterGauge(poolMetricName(pool, "shares"), nil).Update(int64(pool.CurrentShareNum))
+	metrics.GetOrRegisterGauge(poolMetricName(pool, "wishvotes"), nil).Update(int64(pool.WishVoteNum))
+	metrics.GetOrRegisterCounter(poolMetricName(pool, "selected"), nil).Inc(int64(entry.Selected))
+	metrics.GetOrRegisterCounter(poolMetricName(pool, "voted"), nil).Inc(int64(entry.Voted))
+	metrics.GetOrRegisterCounter(poolMetricName(pool, "solovoted"), nil).Inc(int64(entry.SoloVoted))
+	metrics.GetOrRegisterCounter(poolMetricName(pool, "missed"), nil).Inc(int64(entry.Missed))
+	metrics.GetOrRegisterCounter(poolMetricName(pool, "expired"), nil).Inc(int64(entry.Expired))
+	// fee income in units of 1e-9 SERO to fit the counter
+	fee := new(big.Int).Div(entry.Reward, big.NewInt(1000000000))
+	metrics.GetOrRegisterCounter(poolMetricName(pool, "feeincome"), nil).Inc(fee.Int64())
+}
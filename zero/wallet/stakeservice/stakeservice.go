@@ -32,7 +32,7 @@ type Account struct {
 type StakeService struct {
 	bc             *core.BlockChain
 	accountManager *accounts.Manager
-	db             *serodb.LDBDatabase
+	db             serodb.KeyValueStore
 
 	accounts sync.Map
 	numbers  sync.Map
@@ -62,7 +62,7 @@ func NewStakeService(dbpath string, bc *core.BlockChain, accountManager *account
 	}
 	current_StakeService = stakeService
 
-	db, err := serodb.NewLDBDatabase(dbpath, 1024, 1024)
+	db, err := serodb.NewDatabase(dbpath, 1024, 1024)
 	if err != nil {
 		panic(err)
 	}
@@ -259,11 +259,23 @@ func (self *StakeService) stakeIndex() {
 	batch := self.db.NewBatch()
 	blocNumber := start
 	sharesCache := map[common.Hash]*stake.Share{}
+	poolsCache := map[common.Hash]*stake.StakePool{}
+	reports := []poolReport{}
 	pkrStakeInfoCache := map[c_type.PKr]*SharesInfo{}
 	pkStakeInfoCache := map[c_type.Uint512]*SharesInfo{}
 	for blocNumber+seroparam.DefaultConfirmedBlock() <= header.Number.Uint64() {
 		shares, pools := self.GetBlockRecords(blocNumber)
+		poolsVoted := map[common.Hash]uint32{}
+		poolsMissed := map[common.Hash]uint32{}
 		for _, share := range shares {
+			id := common.BytesToHash(share.Id())
+			entry := shareLedgerEntry(self.getShare(id, sharesCache), share, blocNumber)
+			putLedgerEntry(batch, shareLedgerKey(id, blocNumber), entry)
+			if share.PoolId != nil {
+				poolsVoted[*share.PoolId] += entry.Voted
+				poolsMissed[*share.PoolId] += entry.Missed
+			}
+
 			// batch.Put(sharekey(share.Id()), share.State())
 			// batch.Put(pkrShareKey(share.PKr, share.Id()), share.State())
 
@@ -276,6 +288,13 @@ func (self *StakeService) stakeIndex() {
 		}
 
 		for _, pool := range pools {
+			id := common.BytesToHash(pool.Id())
+			entry := poolLedgerEntry(self.getPool(id, poolsCache), pool, blocNumber, poolsVoted[id], poolsMissed[id])
+			putLedgerEntry(batch, poolLedgerKey(id, blocNumber), entry)
+			if _, ok := self.ownPkr(pool.PKr); ok {
+				reports = append(reports, poolReport{pool, entry})
+			}
+			poolsCache[id] = pool
 			batch.Put(poolKey(pool.Id()), pool.State())
 		}
 		sharesCount += len(shares)
@@ -333,6 +352,9 @@ func (self *StakeService) stakeIndex() {
 			self.numbers.Store(pk, blocNumber)
 			return true
 		})
+		for _, report := range reports {
+			reportPoolMetrics(report.pool, report.entry)
+		}
 		log.Info("StakeIndex", "blockNumber", blocNumber, "sharesCount", sharesCount, "poolsCount", poolsCount)
 	}
 }
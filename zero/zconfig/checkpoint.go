@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/sero-cash/go-czero-import/c_type"
 	"github.com/sero-cash/go-czero-import/seroparam"
@@ -564,22 +565,49 @@ type checkPoint struct {
 type checkPoints struct {
 	maxNum uint64
 	points map[uint64][]byte
+
+	lock   sync.RWMutex
+	signed map[uint64]*SignedCheckpoint
 }
 
+// MaxNum is the last block the check points cover, the signed check points
+// extend the compiled ones as long as no point is missing.
 func (self *checkPoints) MaxNum() (ret uint64) {
-	if seroparam.Is_Dev() {
-		return 0
-	} else {
-		return self.maxNum
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.maxNumLocked()
+}
+
+func (self *checkPoints) maxNumLocked() (ret uint64) {
+	if !seroparam.Is_Dev() {
+		ret = self.maxNum
+	}
+	for self.signed[ret+CheckPointInterval] != nil {
+		ret += CheckPointInterval
+	}
+	return
+}
+
+func (self *checkPoints) root(num uint64) (rt []byte, ok bool) {
+	if !seroparam.Is_Dev() {
+		if rt, ok = self.points[num]; ok {
+			return
+		}
+	}
+	if cp := self.signed[num]; cp != nil {
+		return cp.Root[:], true
 	}
+	return nil, false
 }
 
 func (self *checkPoints) Check(num uint64, root []byte) (e error) {
-	if num > self.maxNum {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if num > self.maxNumLocked() {
 		panic(fmt.Errorf("check points error: the num > maxNum %d-%s", num, hex.EncodeToString(root)))
 	}
-	if (num > 0) && (num%10000 == 0) {
-		if rt, ok := self.points[num]; !ok {
+	if (num > 0) && (num%CheckPointInterval == 0) {
+		if rt, ok := self.root(num); !ok {
 			return fmt.Errorf("check points error: can not find the point %d-%s", num, hex.EncodeToString(root))
 		} else {
 			if bytes.Compare(rt, root) != 0 {
@@ -593,8 +621,10 @@ func (self *checkPoints) Check(num uint64, root []byte) (e error) {
 	}
 }
 
-func newCheckPoints() (ret checkPoints) {
+func newCheckPoints() (ret *checkPoints) {
+	ret = &checkPoints{}
 	ret.points = make(map[uint64][]byte)
+	ret.signed = make(map[uint64]*SignedCheckpoint)
 	var cps []*checkPoint
 	json.Unmarshal([]byte(checkpoints_json), &cps)
 
@@ -3,10 +3,110 @@ package zconfig
 import (
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/crypto"
 )
 
 func TestCheckPoint(t *testing.T) {
 	fmt.Println("checkpoint: ", hex.EncodeToString(CheckPoints.points[10000]))
 	fmt.Println("maxNum: ", CheckPoints.MaxNum())
 }
+
+func TestSignedCheckpoint(t *testing.T) {
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	signers := []string{
+		hexutil.Encode(crypto.CompressPubkey(&key1.PublicKey)),
+		hexutil.Encode(crypto.CompressPubkey(&key2.PublicKey)),
+	}
+	if err := Init_CheckpointSigners(signers, 2); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { checkpointSigners, checkpointThreshold = nil, 0 }()
+
+	cps := newCheckPoints()
+	num := cps.maxNum + CheckPointInterval
+	cp := &SignedCheckpoint{Num: num, Root: common.Hash{1}}
+	cp.Sign(key1)
+	cp.Sign(key1)
+	cp.Sign(other)
+	if err := cps.Add(cp); err == nil {
+		t.Errorf("added with one trusted signature")
+	}
+	cp.Sign(key2)
+	if err := cps.Add(cp); err != nil {
+		t.Fatal(err)
+	}
+	if max := cps.MaxNum(); max != num {
+		t.Errorf("max num %d, want %d", max, num)
+	}
+	if err := cps.Check(num, common.Hash{1}.Bytes()); err != nil {
+		t.Error(err)
+	}
+	if err := cps.Check(num, common.Hash{2}.Bytes()); err == nil {
+		t.Errorf("mismatched root passed")
+	}
+
+	gap := &SignedCheckpoint{Num: num + 2*CheckPointInterval, Root: common.Hash{3}}
+	gap.Sign(key1)
+	gap.Sign(key2)
+	if err := cps.Add(gap); err != nil {
+		t.Fatal(err)
+	}
+	if max := cps.MaxNum(); max != num {
+		t.Errorf("max num %d over a missing point, want %d", max, num)
+	}
+	conflict := &SignedCheckpoint{Num: num, Root: common.Hash{4}}
+	conflict.Sign(key1)
+	conflict.Sign(key2)
+	if err := cps.Add(conflict); err == nil {
+		t.Errorf("conflicting point added")
+	}
+}
+
+func TestSaveCheckpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() { checkpointFile = "" }()
+
+	if err := SaveCheckpoints(); err == nil {
+		t.Errorf("saved without a check point file")
+	}
+	file := filepath.Join(dir, "checkpoints.json")
+	Init_CheckpointFile(file)
+	if err := SaveCheckpoints(); err != nil {
+		t.Fatal(err)
+	}
+	cps, err := ReadCheckpoints(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := CheckPoints.Signed(); len(cps) != len(want) {
+		t.Fatalf("saved check points mismatch: have %d, want %d", len(cps), len(want))
+	}
+
+	// The saved file replaces the previous one
+	cp := SignedCheckpoint{Num: CheckPointInterval, Root: common.Hash{1}, Sigs: []hexutil.Bytes{{1, 2}}}
+	if err := WriteCheckpoints(file, []SignedCheckpoint{cp}); err != nil {
+		t.Fatal(err)
+	}
+	if cps, err = ReadCheckpoints(file); err != nil {
+		t.Fatal(err)
+	}
+	if len(cps) != 1 || cps[0].Num != cp.Num || cps[0].Root != cp.Root || len(cps[0].Sigs) != 1 {
+		t.Fatalf("written check points mismatch: %v", cps)
+	}
+	if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind")
+	}
+}
@@ -1,7 +1,32 @@
 package zconfig
 
-import "path/filepath"
+import (
+	"path/filepath"
+
+	"github.com/sero-cash/go-czero-import/seroparam"
+)
 
 func Light_dir() string {
 	return filepath.Join(dir, "light")
 }
+
+var light_start_num *uint64
+
+// Init_LightStartNum sets the block the light node indexes the blocks after.
+func Init_LightStartNum(num uint64) {
+	light_start_num = &num
+}
+
+func Default_light_start_num() uint64 {
+	if seroparam.Is_Dev() {
+		return 0
+	}
+	return 1280000
+}
+
+func Light_start_num() uint64 {
+	if light_start_num != nil {
+		return *light_start_num
+	}
+	return Default_light_start_num()
+}
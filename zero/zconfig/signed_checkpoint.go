@@ -0,0 +1,190 @@
+package zconfig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/crypto"
+)
+
+// CheckPointInterval is the number of blocks between the check points.
+const CheckPointInterval = 10000
+
+// SignedCheckpoint is a check point loaded at runtime, trusted when enough of
+// the configured check point signers signed it.
+type SignedCheckpoint struct {
+	Num  uint64          `json:"num"`
+	Root common.Hash     `json:"root"`
+	Sigs []hexutil.Bytes `json:"sigs"`
+}
+
+// SigHash is the hash the signers sign.
+func (self *SignedCheckpoint) SigHash() common.Hash {
+	var num [8]byte
+	binary.BigEndian.PutUint64(num[:], self.Num)
+	return crypto.Keccak256Hash([]byte("sero checkpoint"), num[:], self.Root[:])
+}
+
+// Signers returns the compressed public keys of the distinct signers.
+func (self *SignedCheckpoint) Signers() (signers []string) {
+	hash := self.SigHash()
+	seen := make(map[string]bool)
+	for _, sig := range self.Sigs {
+		pub, err := crypto.SigToPub(hash[:], sig)
+		if err != nil {
+			continue
+		}
+		signer := hexutil.Encode(crypto.CompressPubkey(pub))
+		if !seen[signer] {
+			seen[signer] = true
+			signers = append(signers, signer)
+		}
+	}
+	return
+}
+
+// Sign adds the signature of key, signing twice with a key is a no-op.
+func (self *SignedCheckpoint) Sign(key *ecdsa.PrivateKey) error {
+	signer := hexutil.Encode(crypto.CompressPubkey(&key.PublicKey))
+	for _, s := range self.Signers() {
+		if s == signer {
+			return nil
+		}
+	}
+	hash := self.SigHash()
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return err
+	}
+	self.Sigs = append(self.Sigs, sig)
+	return nil
+}
+
+var checkpointSigners map[string]bool
+var checkpointThreshold int
+var checkpointFile string
+
+// Init_CheckpointSigners sets the compressed public keys trusted to sign check
+// points and the number of them a check point needs.
+func Init_CheckpointSigners(signers []string, threshold int) error {
+	if threshold < 1 || threshold > len(signers) {
+		return fmt.Errorf("check point threshold %d of %d signers", threshold, len(signers))
+	}
+	trusted := make(map[string]bool)
+	for _, signer := range signers {
+		data, err := hexutil.Decode(signer)
+		if err != nil {
+			return fmt.Errorf("check point signer %s: %v", signer, err)
+		}
+		pub, err := crypto.DecompressPubkey(data)
+		if err != nil {
+			return fmt.Errorf("check point signer %s: %v", signer, err)
+		}
+		trusted[hexutil.Encode(crypto.CompressPubkey(pub))] = true
+	}
+	checkpointSigners = trusted
+	checkpointThreshold = threshold
+	return nil
+}
+
+// Add trusts the signed check point.
+func (self *checkPoints) Add(cp *SignedCheckpoint) error {
+	if len(checkpointSigners) == 0 {
+		return errors.New("check points error: no check point signers")
+	}
+	if cp.Num == 0 || cp.Num%CheckPointInterval != 0 {
+		return fmt.Errorf("check points error: %d is not a check point", cp.Num)
+	}
+	count := 0
+	for _, signer := range cp.Signers() {
+		if checkpointSigners[signer] {
+			count++
+		}
+	}
+	if count < checkpointThreshold {
+		return fmt.Errorf("check points error: the point %d has %d trusted signatures, %d needed", cp.Num, count, checkpointThreshold)
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if rt, ok := self.root(cp.Num); ok && !bytes.Equal(rt, cp.Root[:]) {
+		return fmt.Errorf("check points error: the point %d-%s conflicts with %s", cp.Num, cp.Root.Hex(), hexutil.Encode(rt))
+	}
+	added := *cp
+	added.Sigs = append([]hexutil.Bytes{}, cp.Sigs...)
+	self.signed[cp.Num] = &added
+	return nil
+}
+
+// Signed returns the signed check points by number.
+func (self *checkPoints) Signed() (cps []SignedCheckpoint) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	for _, cp := range self.signed {
+		cps = append(cps, *cp)
+	}
+	sort.Slice(cps, func(i, j int) bool { return cps[i].Num < cps[j].Num })
+	return
+}
+
+// ReadCheckpoints reads the json list of signed check points of the file.
+func ReadCheckpoints(file string) (cps []SignedCheckpoint, err error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &cps)
+	return
+}
+
+// LoadCheckpoints adds the signed check points of the file.
+func LoadCheckpoints(file string) (int, error) {
+	cps, err := ReadCheckpoints(file)
+	if err != nil {
+		return 0, err
+	}
+	for i := range cps {
+		if err := CheckPoints.Add(&cps[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(cps), nil
+}
+
+// WriteCheckpoints replaces the file with the json list of the signed check
+// points, the file is renamed into place once it is written.
+func WriteCheckpoints(file string, cps []SignedCheckpoint) error {
+	data, err := json.MarshalIndent(cps, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// Init_CheckpointFile sets the file the signed check points added at runtime
+// are saved to.
+func Init_CheckpointFile(file string) {
+	checkpointFile = file
+}
+
+// SaveCheckpoints writes the trusted signed check points to the check point
+// file so they are loaded again after a restart.
+func SaveCheckpoints() error {
+	if checkpointFile == "" {
+		return errors.New("check points error: no check point file")
+	}
+	return WriteCheckpoints(checkpointFile, CheckPoints.Signed())
+}